@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/epinio/epinio/acceptance/helpers/catalog"
+	"github.com/epinio/epinio/helpers"
 	v1 "github.com/epinio/epinio/internal/api/v1"
 	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/gorilla/websocket"
@@ -89,7 +90,7 @@ var _ = Describe("Apps API Application Endpoints", func() {
 	}
 
 	updateAppInstances := func(org string, app string, instances int32) (int, []byte) {
-		data, err := json.Marshal(models.UpdateAppRequest{Instances: instances})
+		data, err := json.Marshal(models.UpdateAppRequest{Instances: &instances})
 		ExpectWithOffset(1, err).ToNot(HaveOccurred())
 
 		response, err := env.Curl("PATCH",
@@ -244,6 +245,46 @@ var _ = Describe("Apps API Application Endpoints", func() {
 			})
 		})
 
+		Describe("GET api/v1/orgs/:org/applications/:app/instances", func() {
+			It("lists the instances of the application", func() {
+				app := catalog.NewAppName()
+				env.MakeApp(app, 1, true)
+				defer env.DeleteApp(app)
+
+				var instances models.AppInstanceList
+				Eventually(func() bool {
+					response, err := env.Curl("GET", fmt.Sprintf("%s/api/v1/orgs/%s/applications/%s/instances",
+						serverURL, org, app), strings.NewReader(""))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(response).ToNot(BeNil())
+					defer response.Body.Close()
+					bodyBytes, err := ioutil.ReadAll(response.Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+					err = json.Unmarshal(bodyBytes, &instances)
+					Expect(err).ToNot(HaveOccurred())
+					return len(instances) == 1 && instances[0].Ready
+				}, "1m").Should(BeTrue())
+
+				Expect(instances[0].Phase).To(Equal("Running"))
+				Expect(instances[0].Restarts).To(Equal(int32(0)))
+				Expect(instances[0].Worker).To(BeEmpty())
+				Expect(instances[0].Node).ToNot(BeEmpty())
+			})
+
+			It("returns a 404 when the app does not exist", func() {
+				response, err := env.Curl("GET", fmt.Sprintf("%s/api/v1/orgs/%s/applications/bogus/instances", serverURL, org), strings.NewReader(""))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response).ToNot(BeNil())
+
+				defer response.Body.Close()
+				bodyBytes, err := ioutil.ReadAll(response.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusNotFound), string(bodyBytes))
+			})
+		})
+
 		Describe("DELETE api/v1/orgs/:org/applications/:app", func() {
 			It("removes the application, unbinds bound services", func() {
 				app1 := catalog.NewAppName()
@@ -362,6 +403,24 @@ var _ = Describe("Apps API Application Endpoints", func() {
 
 	})
 
+	// uploadApp uploads the sample app, and returns the git reference to it
+	uploadApp := func(org, appName string) *models.GitRef {
+		uploadURL := serverURL + "/" + v1.Routes.Path("AppUpload", org, appName)
+		uploadPath := "../../../fixtures/sample-app.tar"
+		uploadRequest, err := uploadRequest(uploadURL, uploadPath)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		resp, err := env.Client().Do(uploadRequest)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		respObj := &models.UploadResponse{}
+		err = json.Unmarshal(bodyBytes, &respObj)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+
+		return respObj.Git
+	}
+
 	Context("Staging", func() {
 		var (
 			url     string
@@ -380,29 +439,12 @@ var _ = Describe("Apps API Application Endpoints", func() {
 			Expect(err).ToNot(HaveOccurred())
 
 			// First upload to allow staging to succeed
-			uploadURL := serverURL + "/" + v1.Routes.Path("AppUpload", org, appName)
-			uploadPath := "../../../fixtures/sample-app.tar"
-			uploadRequest, err := uploadRequest(uploadURL, uploadPath)
-			Expect(err).ToNot(HaveOccurred())
-			resp, err := env.Client().Do(uploadRequest)
-			Expect(err).ToNot(HaveOccurred())
-			bodyBytes, err := ioutil.ReadAll(resp.Body)
-			Expect(err).ToNot(HaveOccurred())
-			respObj := &models.UploadResponse{}
-			err = json.Unmarshal(bodyBytes, &respObj)
-			Expect(err).ToNot(HaveOccurred())
-
 			request = models.StageRequest{
 				App: models.AppRef{
 					Name: appName,
 					Org:  org,
 				},
-				Instances: &one,
-				Git: &models.GitRef{
-					Revision: respObj.Git.Revision,
-					URL:      respObj.Git.URL,
-				},
-				Route: appName + ".omg.howdoi.website",
+				Git: uploadApp(org, appName),
 			}
 
 			url = serverURL + "/" + v1.Routes.Path("AppStage", org, appName)
@@ -434,6 +476,89 @@ var _ = Describe("Apps API Application Endpoints", func() {
 				bodyBytes, err := ioutil.ReadAll(response.Body)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+				stage := &models.StageResponse{}
+				err = json.Unmarshal(bodyBytes, stage)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(stage.Stage.ID).ToNot(BeEmpty())
+				Expect(stage.Image.ID).To(ContainSubstring(appName))
+			})
+
+			It("reports the progress of the staging", func() {
+				response, err := env.Curl("POST", url, strings.NewReader(body))
+				Expect(err).ToNot(HaveOccurred())
+				defer response.Body.Close()
+
+				bodyBytes, err := ioutil.ReadAll(response.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+				stage := &models.StageResponse{}
+				err = json.Unmarshal(bodyBytes, stage)
+				Expect(err).ToNot(HaveOccurred())
+
+				statusURL := serverURL + "/" + v1.Routes.Path("StagingShow", org, stage.Stage.ID)
+				status := models.StagingStatus{}
+				Eventually(func() string {
+					response, err := env.Curl("GET", statusURL, strings.NewReader(""))
+					Expect(err).ToNot(HaveOccurred())
+					defer response.Body.Close()
+
+					bodyBytes, err := ioutil.ReadAll(response.Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+					Expect(json.Unmarshal(bodyBytes, &status)).To(Succeed())
+					return status.State
+				}, "5m", "5s").Should(Equal(models.StagingSucceeded))
+
+				Expect(status.ID).To(Equal(stage.Stage.ID))
+				Expect(status.CompletionTime).ToNot(BeNil())
+				Expect(status.Tasks).To(HaveLen(3))
+				for i, name := range []string{"clone", "stage", "clean"} {
+					Expect(status.Tasks[i].Name).To(Equal(name))
+					Expect(status.Tasks[i].State).To(Equal(models.StagingSucceeded))
+				}
+
+				By("hiding it from other organizations")
+				response, err = env.Curl("GET", serverURL+"/"+v1.Routes.Path("StagingShow", "other-org", stage.Stage.ID), strings.NewReader(""))
+				Expect(err).ToNot(HaveOccurred())
+				defer response.Body.Close()
+				Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+			})
+
+			It("cancels the staging", func() {
+				response, err := env.Curl("POST", url, strings.NewReader(body))
+				Expect(err).ToNot(HaveOccurred())
+				defer response.Body.Close()
+
+				bodyBytes, err := ioutil.ReadAll(response.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+				stage := &models.StageResponse{}
+				err = json.Unmarshal(bodyBytes, stage)
+				Expect(err).ToNot(HaveOccurred())
+
+				cancelURL := serverURL + "/" + v1.Routes.Path("StagingCancel", org, stage.Stage.ID)
+				response, err = env.Curl("DELETE", cancelURL, strings.NewReader(""))
+				Expect(err).ToNot(HaveOccurred())
+				defer response.Body.Close()
+
+				bodyBytes, err = ioutil.ReadAll(response.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+				Eventually(func() string {
+					out, err := helpers.Kubectl(fmt.Sprintf("get pipelinerun --namespace tekton-staging %s -o jsonpath={.status.conditions[0].reason}", stage.Stage.ID))
+					Expect(err).ToNot(HaveOccurred(), out)
+					return out
+				}, "1m").Should(Equal("PipelineRunCancelled"))
+
+				By("refusing to cancel it again")
+				response, err = env.Curl("DELETE", cancelURL, strings.NewReader(""))
+				Expect(err).ToNot(HaveOccurred())
+				defer response.Body.Close()
+				Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
 			})
 		})
 
@@ -446,18 +571,40 @@ var _ = Describe("Apps API Application Endpoints", func() {
 				env.DeleteApp(appName)
 			})
 
-			It("creates an app with the specified number of instances", func() {
+			It("deploys the app with the specified number of instances, without deploy request", func() {
 				response, err := env.Curl("POST", url, strings.NewReader(body))
 				Expect(err).ToNot(HaveOccurred())
 				Expect(response).ToNot(BeNil())
 				defer response.Body.Close()
 
+				bodyBytes, err := ioutil.ReadAll(response.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
 				Eventually(func() string {
 					return appStatus(org, appName)
 				}, "5m").Should(Equal("2/2"))
 			})
 		})
 
+		When("staging with a builder image which is not offered", func() {
+			BeforeEach(func() {
+				request.BuilderImage = "splatform/not-a-builder"
+			})
+
+			It("returns BadRequest", func() {
+				resp, err := env.Curl("POST", url, strings.NewReader(body))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp).ToNot(BeNil())
+				defer resp.Body.Close()
+
+				bodyBytes, err := ioutil.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest), string(bodyBytes))
+				Expect(string(bodyBytes)).To(ContainSubstring("builder image 'splatform/not-a-builder' is not offered"))
+			})
+		})
+
 		When("staging with invalid instances", func() {
 			When("instances is not a integer", func() {
 				BeforeEach(func() {
@@ -548,6 +695,219 @@ var _ = Describe("Apps API Application Endpoints", func() {
 		})
 	})
 
+	Context("Deploying", func() {
+		var (
+			url     string
+			body    string
+			appName string
+			request models.DeployRequest
+		)
+
+		BeforeEach(func() {
+			org = catalog.NewOrgName()
+			env.SetupAndTargetOrg(org)
+			appName = catalog.NewAppName()
+
+			By("creating application resource first")
+			_, err := createApplication(appName, org)
+			Expect(err).ToNot(HaveOccurred())
+
+			By("staging the application")
+			stageRequest, err := json.Marshal(models.StageRequest{
+				App: models.NewAppRef(appName, org),
+				Git: uploadApp(org, appName),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			response, err := env.Curl("POST",
+				serverURL+"/"+v1.Routes.Path("AppStage", org, appName),
+				strings.NewReader(string(stageRequest)))
+			Expect(err).ToNot(HaveOccurred())
+			defer response.Body.Close()
+			bodyBytes, err := ioutil.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+			stage := &models.StageResponse{}
+			err = json.Unmarshal(bodyBytes, stage)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() string {
+				out, err := helpers.Kubectl(fmt.Sprintf(
+					"get pipelinerun --namespace tekton-staging %s -o=jsonpath='{.status.conditions[0].status}'",
+					stage.Stage.ID))
+				Expect(err).ToNot(HaveOccurred(), out)
+				return out
+			}, "5m").Should(Equal("True"))
+
+			request = models.DeployRequest{
+				App:            models.NewAppRef(appName, org),
+				Stage:          stage.Stage,
+				Image:          stage.Image,
+				DeploySettings: models.DeploySettings{Instances: &one},
+			}
+
+			url = serverURL + "/" + v1.Routes.Path("AppDeploy", org, appName)
+		})
+
+		JustBeforeEach(func() {
+			bodyBytes, err := json.Marshal(request)
+			Expect(err).ToNot(HaveOccurred())
+			body = string(bodyBytes)
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		When("deploying a staged app", func() {
+			It("returns the route of the app", func() {
+				response, err := env.Curl("POST", url, strings.NewReader(body))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response).ToNot(BeNil())
+				defer response.Body.Close()
+
+				bodyBytes, err := ioutil.ReadAll(response.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+				deploy := &models.DeployResponse{}
+				err = json.Unmarshal(bodyBytes, deploy)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploy.Routes).To(HaveLen(1))
+				Expect(deploy.Routes[0]).To(HavePrefix(appName + "."))
+
+				Eventually(func() string {
+					return appStatus(org, appName)
+				}, "5m").Should(Equal("1/1"))
+			})
+		})
+
+		When("deploying an image not built by the staging run", func() {
+			BeforeEach(func() {
+				request.Image = models.NewImage("splatform/sample-app")
+			})
+
+			It("returns BadRequest", func() {
+				response, err := env.Curl("POST", url, strings.NewReader(body))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response).ToNot(BeNil())
+				defer response.Body.Close()
+
+				bodyBytes, err := ioutil.ReadAll(response.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusBadRequest), string(bodyBytes))
+				Expect(string(bodyBytes)).To(ContainSubstring("was not built by staging run"))
+			})
+		})
+
+		When("deploying with more instances", func() {
+			BeforeEach(func() {
+				request.Instances = &two
+			})
+
+			It("creates an app with the specified number of instances", func() {
+				response, err := env.Curl("POST", url, strings.NewReader(body))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response).ToNot(BeNil())
+				defer response.Body.Close()
+
+				Eventually(func() string {
+					return appStatus(org, appName)
+				}, "5m").Should(Equal("2/2"))
+			})
+		})
+
+		When("deploying with invalid instances", func() {
+			When("instances is not a integer", func() {
+				BeforeEach(func() {
+					n := int32(314)
+					request.Instances = &n // Hack: see below too
+				})
+
+				It("returns BadRequest", func() {
+					// Hack to make the Instances value non-integer
+					body = strings.Replace(body, "314", "3.14", 1)
+
+					resp, err := env.Curl("POST", url, strings.NewReader(body))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp).ToNot(BeNil())
+					defer resp.Body.Close()
+
+					bodyBytes, err := ioutil.ReadAll(resp.Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp.StatusCode).To(Equal(http.StatusBadRequest), string(bodyBytes))
+
+					r := &v1.ErrorResponse{}
+					err = json.Unmarshal(bodyBytes, &r)
+					Expect(err).ToNot(HaveOccurred())
+
+					responseErr := r.Errors[0]
+					Expect(responseErr.Status).To(Equal(400))
+					Expect(responseErr.Title).To(Equal("Failed to construct an Application from the request"))
+					Expect(responseErr.Details).To(MatchRegexp(
+						"cannot unmarshal number 3.14 into Go struct field DeployRequest.instances of type int",
+					))
+				})
+			})
+
+			When("instances is a negative integer", func() {
+				BeforeEach(func() {
+					n := int32(-3)
+					request.Instances = &n
+				})
+
+				It("returns BadRequest", func() {
+					resp, err := env.Curl("POST", url, strings.NewReader(body))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp).ToNot(BeNil())
+					defer resp.Body.Close()
+
+					bodyBytes, err := ioutil.ReadAll(resp.Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp.StatusCode).To(Equal(http.StatusBadRequest), string(bodyBytes))
+
+					r := &v1.ErrorResponse{}
+					err = json.Unmarshal(bodyBytes, &r)
+					Expect(err).ToNot(HaveOccurred())
+
+					responseErr := r.Errors[0]
+					Expect(responseErr.Status).To(Equal(400))
+					Expect(responseErr.Title).To(Equal("instances param should be integer equal or greater than zero"))
+				})
+			})
+
+			When("instances is not a number", func() {
+				BeforeEach(func() {
+					n := int32(314)
+					request.Instances = &n // Hack: see below too
+				})
+
+				It("returns BadRequest", func() {
+					// Hack to make the Instances value non-number
+					body = strings.Replace(body, "314", "thisisnotanumber", 1)
+
+					resp, err := env.Curl("POST", url, strings.NewReader(body))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp).ToNot(BeNil())
+					defer resp.Body.Close()
+
+					bodyBytes, err := ioutil.ReadAll(resp.Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp.StatusCode).To(Equal(http.StatusBadRequest), string(bodyBytes))
+
+					r := &v1.ErrorResponse{}
+					err = json.Unmarshal(bodyBytes, &r)
+					Expect(err).ToNot(HaveOccurred())
+
+					responseErr := r.Errors[0]
+					Expect(responseErr.Status).To(Equal(400))
+					Expect(responseErr.Title).To(Equal("Failed to construct an Application from the request"))
+				})
+			})
+		})
+	})
+
 	Context("Logs", func() {
 		Describe("GET api/v1/orgs/:orgs/applications/:app/logs", func() {
 			logLength := 0
@@ -559,7 +919,7 @@ var _ = Describe("Apps API Application Endpoints", func() {
 			BeforeEach(func() {
 				app = catalog.NewAppName()
 				out := env.MakeApp(app, 1, true)
-				routeRegexp := regexp.MustCompile(`Route: (https:\/\/.*\.omg\.howdoi\.website)`)
+				routeRegexp := regexp.MustCompile(`Routes: (https:\/\/[^,\s]*\.omg\.howdoi\.website)`)
 				route = routeRegexp.FindStringSubmatch(out)[1]
 				Expect(route).ToNot(BeEmpty())
 			})
@@ -0,0 +1,43 @@
+package v1_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/epinio/epinio/acceptance/helpers/catalog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics Endpoint", func() {
+	var org string
+
+	BeforeEach(func() {
+		org = catalog.NewOrgName()
+		env.SetupAndTargetOrg(org)
+	})
+
+	It("serves the metrics of the API and of the resources", func() {
+		response, err := env.Curl("GET", fmt.Sprintf("%s/api/v1/orgs", serverURL), strings.NewReader(""))
+		Expect(err).ToNot(HaveOccurred())
+		response.Body.Close()
+
+		response, err = env.Curl("GET", fmt.Sprintf("%s/metrics", serverURL), strings.NewReader(""))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response).ToNot(BeNil())
+		defer response.Body.Close()
+		bodyBytes, err := ioutil.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+		metrics := string(bodyBytes)
+		Expect(metrics).To(MatchRegexp(`epinio_api_requests_total\{code="200",method="get",route="Orgs"\} \d+`))
+		Expect(metrics).To(MatchRegexp(`epinio_api_request_duration_seconds_bucket\{code="200",method="get",route="Orgs",le="[^"]+"\} \d+`))
+		Expect(metrics).To(MatchRegexp(`epinio_organizations \d+`))
+		Expect(metrics).To(ContainSubstring(fmt.Sprintf(`epinio_applications{org="%s"} 0`, org)))
+		Expect(metrics).To(ContainSubstring(fmt.Sprintf(`epinio_stagings_in_progress{org="%s"} 0`, org)))
+	})
+})
@@ -2,7 +2,9 @@ package acceptance_test
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
@@ -14,6 +16,7 @@ import (
 	"github.com/epinio/epinio/acceptance/helpers/catalog"
 	"github.com/epinio/epinio/acceptance/helpers/proc"
 	"github.com/epinio/epinio/helpers"
+	"github.com/epinio/epinio/helpers/kubernetes/tailer"
 	v1 "github.com/epinio/epinio/internal/api/v1"
 
 	. "github.com/onsi/ginkgo"
@@ -170,6 +173,224 @@ var _ = Describe("Apps", func() {
 		})
 	})
 
+	When("pushing an app with a manifest", func() {
+		var appDir string
+
+		BeforeEach(func() {
+			var err error
+			appDir, err = ioutil.TempDir("", "epinio-manifest-app")
+			Expect(err).ToNot(HaveOccurred())
+
+			out, err := proc.Run(fmt.Sprintf("cp -r ../assets/sample-app/. %s", appDir), "", false)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			err = ioutil.WriteFile(path.Join(appDir, "epinio.yml"), []byte(fmt.Sprintf(`name: %s
+instances: 2
+environment:
+  MANIFEST_MODE: production
+`, appName)), 0644)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+			Expect(os.RemoveAll(appDir)).To(Succeed())
+		})
+
+		It("uses the settings of the manifest", func() {
+			out, err := env.Epinio("apps push", appDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("App is online"))
+
+			out, err = env.Epinio("app env list "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`MANIFEST_MODE\s*\|\s*production`))
+
+			Eventually(func() string {
+				out, err := env.Epinio("app show "+appName, "")
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}, "1m").Should(MatchRegexp(`Status\s*\|\s*2\/2\s*\|`))
+		})
+
+		It("lets options override the manifest", func() {
+			out, err := env.Epinio("apps push -i 1 -e MANIFEST_MODE=debug", appDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("app env list "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`MANIFEST_MODE\s*\|\s*debug`))
+
+			Eventually(func() string {
+				out, err := env.Epinio("app show "+appName, "")
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}, "1m").Should(MatchRegexp(`Status\s*\|\s*1\/1\s*\|`))
+		})
+
+		It("exports the manifest of the live app", func() {
+			out, err := env.Epinio("apps push", appDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			exported := path.Join(appDir, "exported.yml")
+			out, err = env.Epinio(fmt.Sprintf("app export-manifest %s %s", appName, exported), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			content, err := ioutil.ReadFile(exported)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("name: " + appName))
+			Expect(string(content)).To(ContainSubstring("instances: 2"))
+			Expect(string(content)).To(ContainSubstring("MANIFEST_MODE: production"))
+		})
+	})
+
+	When("pushing an app twice", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("reuses the build cache until cleared", func() {
+			env.MakeGolangApp(appName, 1, true)
+
+			out, err := helpers.Kubectl(fmt.Sprintf("get pvc --namespace tekton-staging -l app.kubernetes.io/name=%s,app.kubernetes.io/part-of=%s,app.kubernetes.io/component=cache", appName, org))
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring(appName))
+
+			out = env.MakeGolangApp(appName, 1, true)
+			Expect(out).To(ContainSubstring("Restoring data for"))
+
+			out, err = env.Epinio("app cache clear "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Build cache cleared."))
+
+			Eventually(func() string {
+				out, _ := helpers.Kubectl(fmt.Sprintf("get pvc --namespace tekton-staging -l app.kubernetes.io/name=%s,app.kubernetes.io/part-of=%s,app.kubernetes.io/component=cache", appName, org))
+				return out
+			}, "1m").Should(ContainSubstring("No resources found"))
+		})
+	})
+
+	When("pushing an app with health checks", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("probes the instances, and keeps the checks across pushes", func() {
+			appDir := "../assets/sample-app"
+			out, err := env.Epinio(fmt.Sprintf("apps push %s --readiness http:/ --liveness tcp", appName), appDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("App is online"))
+
+			out, err = helpers.Kubectl(fmt.Sprintf("get deployment --namespace %s %s -o=jsonpath='{.spec.template.spec.containers[0].readinessProbe.httpGet.path}'", org, appName))
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(Equal("/"))
+
+			out, err = env.Epinio(fmt.Sprintf("apps push %s", appName), appDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Liveness\s*\|\s*tcp`))
+			Expect(out).To(MatchRegexp(`Readiness\s*\|\s*http /`))
+		})
+	})
+
+	When("pushing an app with a bad health check", func() {
+		It("rejects the check", func() {
+			out, err := env.Epinio(fmt.Sprintf("apps push %s --liveness ping", appName), "../assets/sample-app")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("bad liveness check 'ping'"))
+		})
+	})
+
+	When("adding routes to an app", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("manages the routes of the app", func() {
+			out := env.MakeApp(appName, 1, true)
+			m := regexp.MustCompile(`Routes: https://` + appName + `\.([^,\s]*)`).FindStringSubmatch(out)
+			Expect(m).To(HaveLen(2), out)
+			route := "custom-" + appName + "." + m[1]
+
+			out, err := env.Epinio(fmt.Sprintf("app route add %s %s", appName, route), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Route added."))
+
+			out, err = env.Epinio("app route list "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("https://" + route))
+			Expect(out).To(MatchRegexp(`https://` + appName + `\.`))
+
+			out, err = helpers.Kubectl(fmt.Sprintf("get certificates --namespace %s", org))
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring(appName + "." + route))
+
+			out, err = env.Epinio(fmt.Sprintf("app route add %s %s", appName, route), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("already"))
+
+			out, err = env.Epinio(fmt.Sprintf("app route remove %s %s", appName, route), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Route removed."))
+
+			out, err = env.Epinio("app route list "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(ContainSubstring("https://" + route))
+		})
+	})
+
+	When("pushing a container image", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("deploys the image without staging", func() {
+			out, err := env.Epinio(fmt.Sprintf("apps push %s --container-image splatform/sample-app", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("App is online"))
+			Expect(out).ToNot(ContainSubstring("Collecting the application sources"))
+
+			Eventually(func() string {
+				out, err := env.Epinio("app show "+appName, "")
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}, "1m").Should(MatchRegexp(`Status\s*\|\s*1\/1\s*\|`))
+		})
+
+		It("runs the workers next to the application", func() {
+			out, err := env.Epinio(fmt.Sprintf("apps push %s --container-image splatform/sample-app --worker 'ticker=sleep 3600'", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("App is online"))
+
+			Eventually(func() string {
+				out, err := helpers.Kubectl(fmt.Sprintf("get deployment --namespace %s %s-ticker -o jsonpath={.status.readyReplicas}", org, appName))
+				Expect(err).ToNot(HaveOccurred(), out)
+				return out
+			}, "2m").Should(Equal("1"))
+
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Workers\s*\|\s*ticker\s*\|`))
+			Expect(out).To(MatchRegexp(`Port\s*\|\s*8080\s*\|`))
+
+			By("removing the workers not declared anymore")
+			out, err = env.Epinio(fmt.Sprintf("apps push %s --container-image splatform/sample-app", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = helpers.Kubectl(fmt.Sprintf("get deployment --namespace %s -l app.kubernetes.io/component=worker -o name", org))
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(ContainSubstring(appName + "-ticker"))
+		})
+
+		It("rejects sources together with the image", func() {
+			out, err := env.Epinio(fmt.Sprintf("apps push %s ../assets/sample-app --container-image splatform/sample-app", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("no sources to push with --container-image"))
+		})
+	})
+
 	Describe("push and delete", func() {
 		It("shows the staging logs", func() {
 			By("pushing the app")
@@ -213,6 +434,39 @@ var _ = Describe("Apps", func() {
 			})
 		})
 
+		It("deploys an app with chosen buildpacks", func() {
+			currentDir, err := os.Getwd()
+			Expect(err).ToNot(HaveOccurred())
+			appDir := path.Join(currentDir, "../assets/golang-sample-app")
+
+			out, err := env.Epinio(fmt.Sprintf("apps push %s --buildpack paketo-buildpacks/go", appName), appDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Buildpacks\s*\|\s*paketo-buildpacks/go`))
+			Expect(out).To(ContainSubstring("Using the buildpacks: paketo-buildpacks/go"))
+			Expect(out).To(ContainSubstring("App is online"))
+
+			env.DeleteApp(appName)
+		})
+
+		It("deploys an app with a Dockerfile", func() {
+			out := env.MakeDockerfileApp(appName, 1, true)
+			Expect(out).To(MatchRegexp(`Build Strategy\s*\|\s*dockerfile`))
+
+			routeRegexp := regexp.MustCompile(`https:\/\/.*omg.howdoi.website`)
+			route := string(routeRegexp.Find([]byte(out)))
+
+			Eventually(func() string {
+				resp, err := env.Curl("GET", route, strings.NewReader(""))
+				Expect(err).ToNot(HaveOccurred())
+				defer resp.Body.Close()
+				body, err := ioutil.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				return string(body)
+			}, 30*time.Second, 1*time.Second).Should(ContainSubstring("Built from a Dockerfile"))
+
+			env.DeleteApp(appName)
+		})
+
 		It("deploys an app from the current dir", func() {
 			By("pushing the app in the current working directory")
 			out := env.MakeApp(appName, 1, true)
@@ -333,6 +587,86 @@ var _ = Describe("Apps", func() {
 		})
 	})
 
+	Describe("releases and rollback", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("rolls back to the previous release", func() {
+			stageIDOf := func() string {
+				out, err := env.Epinio("app show "+appName, "")
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				m := regexp.MustCompile(`StageId\s*\|\s*(\w+)\s*\|`).FindStringSubmatch(out)
+				ExpectWithOffset(1, m).To(HaveLen(2), out)
+				return m[1]
+			}
+
+			env.MakeApp(appName, 1, true)
+			first := stageIDOf()
+
+			out, err := env.Epinio(fmt.Sprintf("apps env set %s MYVAR myvalue", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			env.MakeApp(appName, 1, true)
+			second := stageIDOf()
+			Expect(second).ToNot(Equal(first))
+
+			out, err = env.Epinio("app releases "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(second + `\s*\|.*\|\s*\*\s*\|`))
+			Expect(out).To(ContainSubstring(first))
+
+			out, err = env.Epinio("app rollback "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Rolled back"))
+
+			By("recording the rollback as the active release")
+			rollback := stageIDOf()
+			Expect(rollback).ToNot(Equal(first))
+			Expect(rollback).ToNot(Equal(second))
+
+			out, err = env.Epinio("app releases "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(rollback + `\s*\|.*\|\s*` + first + `\s*\|\s*\*\s*\|`))
+
+			By("restoring the environment of the release")
+			out, err = env.Epinio("app env list "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(ContainSubstring("MYVAR"))
+
+			By("showing the staging logs of the past release")
+			out, err = env.Epinio(fmt.Sprintf("app logs --staging=%s %s", second, appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`.*step-create.*Configuring PHP Application.*`))
+		})
+
+		It("keeps the staging logs of past releases", func() {
+			env.MakeApp(appName, 1, true)
+			out, err := env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			m := regexp.MustCompile(`StageId\s*\|\s*(\w+)\s*\|`).FindStringSubmatch(out)
+			Expect(m).To(HaveLen(2), out)
+			first := m[1]
+
+			By("waiting for the server to save the logs")
+			Eventually(func() string {
+				out, _ := helpers.Kubectl(fmt.Sprintf("get configmap --namespace %s -l app.kubernetes.io/component=staging-logs,epinio.suse.org/stage-id=%s", org, first))
+				return out
+			}, "1m").Should(ContainSubstring(appName))
+
+			env.MakeApp(appName, 1, true)
+
+			out, err = env.Epinio(fmt.Sprintf("app logs --staging=%s %s", first, appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`.*step-create.*Configuring PHP Application.*`))
+
+			out, err = env.Epinio(fmt.Sprintf("app logs --staging=%s --include 'Using feature' --timestamps %s", first, appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`step-create.*\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}.*Using feature -- PHP`))
+			Expect(out).ToNot(ContainSubstring("Configuring PHP Application"))
+		})
+	})
+
 	Describe("update", func() {
 		It("respects the desired number of instances", func() {
 			env.MakeApp(appName, 1, true)
@@ -355,6 +689,114 @@ var _ = Describe("Apps", func() {
 			}, "1m").Should(MatchRegexp(`Status\s*\|\s*3\/3\s*\|`))
 		})
 
+		It("limits the resources within the maximums of the org", func() {
+			out, err := env.Epinio(fmt.Sprintf("org limits set %s --default-memory 128Mi --max-memory 512Mi", org), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("org limits show "+org, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`memory\s*\|\s*128Mi\s*\|\s*512Mi`))
+
+			env.MakeApp(appName, 1, true)
+
+			out, err = env.Epinio(fmt.Sprintf("app update %s --memory 1Gi", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("exceeds the maximum 512Mi"))
+
+			out, err = env.Epinio(fmt.Sprintf("app update %s --memory 256Mi --cpu 100m", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Memory\s*\|\s*256Mi`))
+			Expect(out).To(MatchRegexp(`CPU\s*\|\s*100m`))
+
+			Eventually(func() string {
+				out, err := env.Epinio("app show "+appName, "")
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}, "1m").Should(MatchRegexp(`Status\s*\|\s*1\/1\s*\|`))
+
+			By("lowering the maximum below the resources of the app")
+			out, err = env.Epinio(fmt.Sprintf("org limits set %s --max-memory 128Mi", org), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio(fmt.Sprintf("apps push %s", appName), "../assets/sample-app")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("memory 256Mi exceeds the maximum 128Mi"))
+
+			out, err = env.Epinio(fmt.Sprintf("app update %s --memory 128Mi", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio(fmt.Sprintf("apps push %s", appName), "../assets/sample-app")
+			Expect(err).ToNot(HaveOccurred(), out)
+		})
+
+		It("restarts, stops and starts the app", func() {
+			env.MakeApp(appName, 2, true)
+
+			out, err := env.Epinio("app restart "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Application restarted"))
+
+			out, err = env.Epinio("app stop "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Eventually(func() string {
+				out, err := env.Epinio("app show "+appName, "")
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}, "1m").Should(MatchRegexp(`Status\s*\|\s*0\/0 \(stopped\)\s*\|`))
+
+			out, err = env.Epinio(fmt.Sprintf("app update %s -i 3", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("instances of a stopped application cannot be set"))
+
+			out, err = env.Epinio("app stop "+appName, "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Application is already stopped"))
+
+			out, err = env.Epinio("app start "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Application started"))
+
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Status\s*\|\s*2\/2\s*\|`))
+		})
+
+		It("autoscales the app, and rejects manual instances meanwhile", func() {
+			env.MakeApp(appName, 1, true)
+
+			out, err := env.Epinio(fmt.Sprintf("app autoscale %s --min 1 --max 3 --cpu-percent 50", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Unable to autoscale application without cpu"))
+
+			out, err = env.Epinio(fmt.Sprintf("app update %s --cpu 100m", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio(fmt.Sprintf("app autoscale %s --min 1 --max 3 --cpu-percent 50", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Autoscaling\s*\|\s*1 - 3 instances at 50% cpu`))
+
+			out, err = env.Epinio(fmt.Sprintf("app update %s -i 2", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("instances of an autoscaled application cannot be set"))
+
+			out, err = env.Epinio(fmt.Sprintf("app autoscale %s --off", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(ContainSubstring("Autoscaling"))
+
+			out, err = env.Epinio(fmt.Sprintf("app update %s -i 2", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+		})
+
 		AfterEach(func() {
 			env.DeleteApp(appName)
 		})
@@ -396,6 +838,9 @@ var _ = Describe("Apps", func() {
 				Expect(err).ToNot(HaveOccurred(), out)
 				return out
 			}, "1m").Should(MatchRegexp(`Status .*\|.* 1\/1`))
+
+			Expect(out).To(ContainSubstring("Instances:"))
+			Expect(out).To(MatchRegexp(appName + `-[a-z0-9-]+\s*\|\s*\|\s*Running\s*\|\s*true\s*\|\s*0\s*\|`))
 		})
 
 		Describe("no instances", func() {
@@ -421,6 +866,111 @@ var _ = Describe("Apps", func() {
 		})
 	})
 
+	Describe("exec", func() {
+		BeforeEach(func() {
+			env.MakeApp(appName, 1, true)
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("runs a command in an instance of the app", func() {
+			out, err := env.Epinio(fmt.Sprintf("app exec %s -- echo hello from the app", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("hello from the app"))
+		})
+
+		It("passes stdin, and the exit code of the command", func() {
+			input, err := ioutil.TempFile("", "exec-input")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.Remove(input.Name())
+			_, err = input.WriteString("piped into the app\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.Close()).To(Succeed())
+
+			out, err := env.Epinio(fmt.Sprintf("app exec %s --instance 0 -- cat < %s", appName, input.Name()), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("piped into the app"))
+
+			out, err = env.Epinio(fmt.Sprintf("app exec %s -- sh -c 'exit 3'", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(err.Error()).To(ContainSubstring("exit status 3"))
+		})
+
+		It("rejects an unknown instance", func() {
+			out, err := env.Epinio(fmt.Sprintf("app exec %s --instance bogus -- true", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Application has no instance 'bogus'"))
+		})
+	})
+
+	Describe("port-forward", func() {
+		BeforeEach(func() {
+			env.MakeApp(appName, 1, true)
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("forwards a local port to the app", func() {
+			p, err := proc.Get(nodeTmpDir+"/epinio app port-forward "+appName+" 18080:8080", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			defer func() {
+				if p.Process != nil {
+					p.Process.Kill()
+				}
+			}()
+			reader, err := p.StdoutPipe()
+			Expect(err).NotTo(HaveOccurred())
+			go p.Run()
+
+			scanner := bufio.NewScanner(reader)
+			Eventually(func() string {
+				scanner.Scan()
+				return scanner.Text()
+			}, "1m").Should(ContainSubstring("Forwarding from 127.0.0.1:18080 -> 8080"))
+
+			resp, err := http.Get("http://127.0.0.1:18080/")
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("rejects a bad port", func() {
+			out, err := env.Epinio("app port-forward "+appName+" 18080:bogus", "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("bad port 'bogus'"))
+		})
+	})
+
+	Describe("top", func() {
+		BeforeEach(func() {
+			env.MakeApp(appName, 1, true)
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("shows the usage of the instances of the app", func() {
+			// The metrics server needs a while to see new instances
+			Eventually(func() string {
+				out, err := env.Epinio("app top "+appName, "")
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}, "3m", "10s").Should(MatchRegexp(appName + `-[a-z0-9-]+\s*\|[^|]*\|\s*\d+m\s*\|\s*[0-9.]+Mi\s*\|`))
+		})
+
+		It("rejects an unknown app", func() {
+			out, err := env.Epinio("app top bogus", "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Application 'bogus' does not exist"))
+		})
+	})
+
 	Describe("logs", func() {
 		var (
 			route     string
@@ -457,6 +1007,78 @@ var _ = Describe("Apps", func() {
 			Expect(out).ToNot(MatchRegexp(`linkerd-.*`))
 		})
 
+		It("filters the logs", func() {
+			out, err := env.Epinio("app logs --staging --include 'Using feature' --exclude 'Python' "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Expect(out).To(MatchRegexp(`.*step-create.*Using feature -- PHP.*`))
+			Expect(out).ToNot(ContainSubstring("Configuring PHP Application"))
+			Expect(out).ToNot(ContainSubstring("Python"))
+		})
+
+		It("shows the timestamps of the logs", func() {
+			out, err := env.Epinio("app logs --staging --timestamps --tail 5 "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Expect(out).To(MatchRegexp(`.*step-create.*\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}.*`))
+		})
+
+		It("shows the logs of a single instance", func() {
+			out, err := env.Epinio("app logs --instance 0 "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			podNames := env.GetPodNames(appName, org)
+			Expect(out).To(ContainSubstring(podNames[0]))
+		})
+
+		It("prints the logs as json", func() {
+			out, err := env.Epinio("app logs --staging --output json "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			Expect(lines).ToNot(BeEmpty())
+			for _, line := range lines {
+				var logLine tailer.ContainerLogLine
+				Expect(json.Unmarshal([]byte(line), &logLine)).To(Succeed(), line)
+				Expect(logLine.PodName).ToNot(BeEmpty())
+				Expect(logLine.Timestamp.IsZero()).To(BeFalse())
+			}
+			Expect(out).To(ContainSubstring("Using feature -- PHP"))
+		})
+
+		It("prints the logs through a template", func() {
+			out, err := env.Epinio("app logs --staging --output 'template={{.ContainerName}}|{{.Message}}' "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Expect(out).To(MatchRegexp(`(?m)^step-create\|.*Using feature -- PHP`))
+			Expect(out).ToNot(ContainSubstring("Streaming application logs"))
+		})
+
+		It("saves the logs to a file", func() {
+			dir, err := ioutil.TempDir("", "epinio-logs")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+			file := path.Join(dir, "app.log")
+
+			out, err := env.Epinio(fmt.Sprintf("app logs --staging --file %s %s", file, appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Logs saved"))
+
+			content, err := ioutil.ReadFile(file)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(MatchRegexp(`\[.*\] step-create .*Using feature -- PHP`))
+
+			out, err = env.Epinio(fmt.Sprintf("app logs --follow --file %s %s", file, appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("--file cannot be combined with --follow"))
+		})
+
+		It("rejects a bad regular expression", func() {
+			out, err := env.Epinio("app logs --include '(' "+appName, "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("bad regular expression '('"))
+		})
+
 		It("follows logs", func() {
 			p, err := proc.Get(nodeTmpDir+"/epinio app logs --follow "+appName, "")
 			Expect(err).NotTo(HaveOccurred())
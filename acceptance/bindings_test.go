@@ -1,7 +1,10 @@
 package acceptance_test
 
 import (
+	"fmt"
+
 	"github.com/epinio/epinio/acceptance/helpers/catalog"
+	"github.com/epinio/epinio/internal/application"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -49,4 +52,57 @@ var _ = Describe("Bounds between Apps & Services", func() {
 			env.CleanupService(serviceName)
 		})
 	})
+
+	Describe("Environment", func() {
+		var appName string
+		var serviceName string
+		BeforeEach(func() {
+			appName = catalog.NewAppName()
+			serviceName = catalog.NewServiceName()
+
+			env.MakeApp(appName, 1, true)
+			env.MakeCustomService(serviceName)
+		})
+		AfterEach(func() {
+			env.CleanupApp(appName)
+			env.CleanupService(serviceName)
+		})
+
+		It("passes the binding as environment variables, until unbound", func() {
+			variable := application.BindingEnvName(serviceName, "username") + "=epinio-user"
+			servicesJSON := fmt.Sprintf(`%s={"%s":{"username":"epinio-user"}}`, application.ServicesEnvVar, serviceName)
+
+			out, err := env.Epinio(fmt.Sprintf("service bind %s %s --env --json", serviceName, appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			env.VerifyAppServiceBound(appName, serviceName, org, 1)
+
+			// Binding restarts the app, the old instance may answer
+			// for a while
+			Eventually(func() string {
+				out, err := env.Epinio(fmt.Sprintf("app exec %s -- env", appName), "")
+				Expect(err).ToNot(HaveOccurred(), out)
+				return out
+			}, "5m").Should(And(ContainSubstring(variable), ContainSubstring(servicesJSON)))
+
+			out, err = env.Epinio(fmt.Sprintf("service unbind %s %s", serviceName, appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Eventually(func() string {
+				out, err := env.Epinio(fmt.Sprintf("app exec %s -- env", appName), "")
+				Expect(err).ToNot(HaveOccurred(), out)
+				return out
+			}, "5m").ShouldNot(Or(ContainSubstring(variable), ContainSubstring(application.ServicesEnvVar)))
+		})
+
+		It("refuses to set a variable of the binding in the application", func() {
+			name := application.BindingEnvName(serviceName, "username")
+
+			out, err := env.Epinio(fmt.Sprintf("service bind %s %s --env", serviceName, appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio(fmt.Sprintf("apps env set %s %s other", appName, name), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("environment variable '%s' is set by a service binding", name))
+		})
+	})
 })
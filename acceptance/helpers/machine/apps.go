@@ -24,6 +24,14 @@ func (m *Machine) MakeGolangApp(appName string, instances int, deployFromCurrent
 	return m.MakeAppWithDir(appName, instances, deployFromCurrentDir, appDir)
 }
 
+func (m *Machine) MakeDockerfileApp(appName string, instances int, deployFromCurrentDir bool) string {
+	currentDir, err := os.Getwd()
+	ExpectWithOffset(1, err).ToNot(HaveOccurred())
+	appDir := path.Join(currentDir, m.root, "assets/dockerfile-sample-app")
+
+	return m.MakeAppWithDir(appName, instances, deployFromCurrentDir, appDir)
+}
+
 func (m *Machine) MakeAppWithDir(appName string, instances int, deployFromCurrentDir bool, appDir string) string {
 	var pushOutput string
 	var err error
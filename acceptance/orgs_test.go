@@ -37,6 +37,50 @@ var _ = Describe("Orgs", func() {
 		})
 	})
 
+	Describe("org logs", func() {
+		var (
+			org      string
+			appName1 string
+			appName2 string
+		)
+
+		BeforeEach(func() {
+			org = catalog.NewOrgName()
+			env.SetupAndTargetOrg(org)
+
+			appName1 = catalog.NewAppName()
+			appName2 = catalog.NewAppName()
+			env.MakeApp(appName1, 1, true)
+			env.MakeApp(appName2, 1, true)
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName1)
+			env.DeleteApp(appName2)
+		})
+
+		It("merges the logs of all apps in the org, and of their stagings", func() {
+			out, err := env.Epinio("org logs", "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Expect(out).To(MatchRegexp(appName1 + ` \[` + appName1))
+			Expect(out).To(MatchRegexp(appName2 + ` \[` + appName2))
+			Expect(out).To(MatchRegexp(`step-create.*Using feature -- PHP`))
+		})
+
+		It("shows the logs of the chosen apps only", func() {
+			out, err := env.Epinio("org logs --app "+appName1, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Expect(out).To(ContainSubstring(appName1))
+			Expect(out).ToNot(ContainSubstring(appName2))
+
+			out, err = env.Epinio("org logs --app bogus", "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Application 'bogus' does not exist"))
+		})
+	})
+
 	Describe("org delete", func() {
 		It("deletes an org", func() {
 			org := catalog.NewOrgName()
@@ -134,7 +134,8 @@ func (k Epinio) apply(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI,
 
 	issuer := options.GetStringNG("tls-issuer")
 	nodePort := options.GetBoolNG("use-internal-registry-node-port")
-	if out, err := k.applyEpinioConfigYaml(ctx, c, ui, authAPI, issuer, nodePort); err != nil {
+	builders := BuilderImages(options)
+	if out, err := k.applyEpinioConfigYaml(ctx, c, ui, authAPI, issuer, nodePort, builders); err != nil {
 		return errors.Wrap(err, out)
 	}
 
@@ -241,7 +242,7 @@ func (k Epinio) Upgrade(ctx context.Context, c *kubernetes.Cluster, ui *termui.U
 }
 
 // Replaces ##current_epinio_version## with version.Version and applies the embedded yaml
-func (k Epinio) applyEpinioConfigYaml(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI, auth auth.PasswordAuth, issuer string, nodePort bool) (string, error) {
+func (k Epinio) applyEpinioConfigYaml(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI, auth auth.PasswordAuth, issuer string, nodePort bool, builders []string) (string, error) {
 	// (xxx) Apply traefik v2 middleware. This will fail for a
 	// traefik v1 controller.  Ignore error if it was due due to a
 	// missing Middleware CRD. That indicates presence of the
@@ -296,6 +297,12 @@ func (k Epinio) applyEpinioConfigYaml(ctx context.Context, c *kubernetes.Cluster
 	re = regexp.MustCompile(`##use_internal_registry_node_port##`)
 	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(strconv.FormatBool(nodePort)))
 
+	re = regexp.MustCompile(`##builder_image##`)
+	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(builders[0]))
+
+	re = regexp.MustCompile(`##additional_builder_images##`)
+	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(strings.Join(builders[1:], ",")))
+
 	re = regexp.MustCompile(`##trace_level##`)
 	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(strconv.Itoa(viper.GetInt("trace-level"))))
 
@@ -51,7 +51,12 @@ const (
 	tektonPipelineReleaseYamlPath = "tekton/pipeline-v0.23.0.yaml"
 	tektonAdminRoleYamlPath       = "tekton/admin-role.yaml"
 	tektonStagingYamlPath         = "tekton/staging.yaml"
+	tektonKanikoYamlPath          = "tekton/kaniko.yaml"
 	tektonPipelineYamlPath        = "tekton/pipeline.yaml"
+
+	// DefaultBuilderImage is the buildpacks builder used when
+	// nothing else was chosen at installation.
+	DefaultBuilderImage = "paketobuildpacks/builder:full"
 )
 
 func (k *Tekton) ID() string {
@@ -253,7 +258,11 @@ func (k Tekton) apply(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI,
 
 	out, err = helpers.WaitForCommandCompletion(ui, message,
 		func() (string, error) {
-			return "", applyTektonStaging(ctx, c, domain)
+			err := applyTektonTask(ctx, c, tektonStagingYamlPath, "create", "/etc/ssl/certs")
+			if err != nil {
+				return "", err
+			}
+			return "", applyTektonTask(ctx, c, tektonKanikoYamlPath, "build", "/kaniko/ssl/certs")
 		},
 	)
 	if err != nil {
@@ -287,8 +296,8 @@ func (k Tekton) Deploy(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI
 		return err
 	}
 
-	s := ui.Progress("Warming up cluster with builder image")
-	err = k.warmupBuilder(ctx, c)
+	s := ui.Progress("Warming up cluster with builder images")
+	err = k.warmupBuilder(ctx, c, BuilderImages(options))
 	if err != nil {
 		return err
 	}
@@ -336,10 +345,13 @@ func getRegistryCAHash(ctx context.Context, c *kubernetes.Cluster) (string, erro
 	return hash, nil
 }
 
-func applyTektonStaging(ctx context.Context, c *kubernetes.Cluster, domain string) error {
-	yamlPathOnDisk, err := helpers.ExtractFile(tektonStagingYamlPath)
+// applyTektonTask creates the tekton Task found in the embedded yaml
+// file. The named step of the task is set up to trust the registry CA,
+// placed into the given certificate directory.
+func applyTektonTask(ctx context.Context, c *kubernetes.Cluster, yamlPath, stepName, certDir string) error {
+	yamlPathOnDisk, err := helpers.ExtractFile(yamlPath)
 	if err != nil {
-		return errors.New("Failed to extract embedded file: " + tektonStagingYamlPath + " - " + err.Error())
+		return errors.New("Failed to extract embedded file: " + yamlPath + " - " + err.Error())
 	}
 	defer os.Remove(yamlPathOnDisk)
 
@@ -382,12 +394,12 @@ func applyTektonStaging(ctx context.Context, c *kubernetes.Cluster, domain strin
 
 		volumeMount := corev1.VolumeMount{
 			Name:      "registry-certs",
-			MountPath: fmt.Sprintf("%s/%s", "/etc/ssl/certs", caHash),
+			MountPath: fmt.Sprintf("%s/%s", certDir, caHash),
 			SubPath:   "ca.crt",
 			ReadOnly:  true,
 		}
 		for stepIndex, step := range tektonTask.Spec.Steps {
-			if step.Name == "create" {
+			if step.Name == stepName {
 				tektonTask.Spec.Steps[stepIndex].VolumeMounts = append(tektonTask.Spec.Steps[stepIndex].VolumeMounts, volumeMount)
 				break
 			}
@@ -480,16 +492,50 @@ func (k Tekton) createClusterRegistryCredsSecret(ctx context.Context, c *kuberne
 	return nil
 }
 
-// This function creates a dummy Job using the buildpack builder image
-// in order to avoid pulling it the first time we an application is staged.
+// BuilderImages returns the builder images chosen at installation, the
+// default builder first.
+func BuilderImages(options kubernetes.InstallationOptions) []string {
+	return BuilderImageList(options.GetStringNG("builder-image"), options.GetStringNG("additional-builder-images"))
+}
+
+// BuilderImageList returns the default builder image, DefaultBuilderImage
+// when not set, followed by the other images of the comma-separated
+// list of additional images.
+func BuilderImageList(defaultImage, additional string) []string {
+	if defaultImage == "" {
+		defaultImage = DefaultBuilderImage
+	}
+	images := []string{defaultImage}
+
+	for _, image := range strings.Split(additional, ",") {
+		image = strings.TrimSpace(image)
+		if image != "" && image != defaultImage {
+			images = append(images, image)
+		}
+	}
+
+	return images
+}
+
+// This function creates a dummy Job using the buildpack builder images
+// in order to avoid pulling them the first time we an application is staged.
 // TODO: This doesn't work in a multi-node cluster because it will only pull
-// the image on one node. Maybe we could use a dummy daemonset for that.
-func (k Tekton) warmupBuilder(ctx context.Context, c *kubernetes.Cluster) error {
+// the images on one node. Maybe we could use a dummy daemonset for that.
+func (k Tekton) warmupBuilder(ctx context.Context, c *kubernetes.Cluster, images []string) error {
 	client, err := typedbatchv1.NewForConfig(c.RestConfig)
 	if err != nil {
 		return err
 	}
 
+	containers := []corev1.Container{}
+	for i, image := range images {
+		containers = append(containers, corev1.Container{
+			Name:    fmt.Sprintf("warmup-%d", i),
+			Image:   image,
+			Command: []string{"/bin/ls"},
+		})
+	}
+
 	jobName := "buildpack-builder-warmup"
 	var backoffLimit = int32(1)
 	if _, err = client.Jobs(TektonStagingNamespace).Create(
@@ -514,12 +560,7 @@ func (k Tekton) warmupBuilder(ctx context.Context, c *kubernetes.Cluster) error
 						},
 					},
 					Spec: corev1.PodSpec{
-						Containers: []corev1.Container{
-							{
-								Name:    "warmup",
-								Image:   "paketobuildpacks/builder:full", // TODO: DRY this
-								Command: []string{"/bin/ls"},
-							}},
+						Containers:    containers,
 						RestartPolicy: "Never",
 					}}}},
 		metav1.CreateOptions{},
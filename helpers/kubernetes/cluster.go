@@ -5,6 +5,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -33,7 +36,9 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	typedbatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
 	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
 
 	// https://github.com/kubernetes/client-go/issues/345
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
@@ -363,6 +368,39 @@ func (c *Cluster) WaitForDeploymentCompleted(ctx context.Context, ui *termui.UI,
 	return wait.PollImmediate(time.Second, timeout, c.IsDeploymentCompleted(ctx, deploymentName, namespace))
 }
 
+// IsDeploymentRolledOut returns a condition function that indicates
+// whether all replicas of the latest revision of the given deployment
+// are updated and ready, i.e. pass their readiness checks.
+func (c *Cluster) IsDeploymentRolledOut(ctx context.Context, deploymentName, namespace string) wait.ConditionFunc {
+	return func() (bool, error) {
+		deployment, err := c.Kubectl.AppsV1().Deployments(namespace).Get(ctx,
+			deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+
+		status := deployment.Status
+		return status.ObservedGeneration >= deployment.Generation &&
+			status.UpdatedReplicas == replicas &&
+			status.ReadyReplicas == replicas &&
+			status.Replicas == replicas, nil
+	}
+}
+
+// WaitForDeploymentRolledOut waits up to timeout for the rollout of
+// the latest revision of the deployment to complete.
+func (c *Cluster) WaitForDeploymentRolledOut(ctx context.Context, ui *termui.UI, namespace, deploymentName string, timeout time.Duration) error {
+	s := ui.Progressf("Waiting for deployment %s in %s to be ready", deploymentName, namespace)
+	defer s.Stop()
+
+	return wait.PollImmediate(time.Second, timeout, c.IsDeploymentRolledOut(ctx, deploymentName, namespace))
+}
+
 // ListPods returns the list of currently scheduled or running pods in `namespace` with the given selector
 func (c *Cluster) ListPods(ctx context.Context, namespace, selector string) (*v1.PodList, error) {
 	listOptions := metav1.ListOptions{}
@@ -639,6 +677,105 @@ func (c *Cluster) ListIngress(ctx context.Context, namespace, selector string) (
 	return ingressList, nil
 }
 
+// ExecStream runs the command in the container of the pod, connected to
+// the given streams. With tty the command gets a terminal, sized as
+// reported by resize, and stderr is merged into stdout. It returns when
+// the command ends.
+func (c *Cluster) ExecStream(namespace, podName, containerName string, command []string, tty bool,
+	stdin io.Reader, stdout, stderr io.Writer, resize remotecommand.TerminalSizeQueue) error {
+	req := c.Kubectl.CoreV1().RESTClient().Post().Resource("pods").Name(podName).
+		Namespace(namespace).SubResource("exec")
+	option := &v1.PodExecOptions{
+		Container: containerName,
+		Command:   command,
+		Stdin:     stdin != nil,
+		Stdout:    true,
+		Stderr:    !tty,
+		TTY:       tty,
+	}
+	req.VersionedParams(
+		option,
+		scheme.ParameterCodec,
+	)
+	exec, err := remotecommand.NewSPDYExecutor(c.RestConfig, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+
+	options := remotecommand.StreamOptions{
+		Stdin:             stdin,
+		Stdout:            stdout,
+		Tty:               tty,
+		TerminalSizeQueue: resize,
+	}
+	if !tty {
+		options.Stderr = stderr
+	}
+	return exec.Stream(options)
+}
+
+// PortForward connects to the port of the pod, and relays in to it, and
+// what comes back to out. It returns when both directions ended.
+func (c *Cluster) PortForward(namespace, podName string, port int, in io.Reader, out io.Writer) error {
+	transport, upgrader, err := spdy.RoundTripperFor(c.RestConfig)
+	if err != nil {
+		return err
+	}
+	req := c.Kubectl.CoreV1().RESTClient().Post().Resource("pods").Name(podName).
+		Namespace(namespace).SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+
+	streamConn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return errors.Wrap(err, "failed to dial the pod")
+	}
+	defer streamConn.Close()
+
+	// A single connection is forwarded, as request 0
+	headers := http.Header{}
+	headers.Set(v1.StreamType, v1.StreamTypeError)
+	headers.Set(v1.PortHeader, strconv.Itoa(port))
+	headers.Set(v1.PortForwardRequestIDHeader, "0")
+	errorStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the error stream")
+	}
+	// Nothing is sent on the error stream
+	errorStream.Close()
+
+	// Buffered, so the reader ends even when the data fails first
+	errorChan := make(chan error, 1)
+	go func() {
+		message, err := ioutil.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			errorChan <- errors.Wrap(err, "failed to read the error stream")
+		case len(message) > 0:
+			errorChan <- errors.New(string(message))
+		}
+		close(errorChan)
+	}()
+
+	headers.Set(v1.StreamType, v1.StreamTypeData)
+	dataStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the data stream")
+	}
+
+	go func() {
+		// Closing the stream tells the pod that no more data comes
+		_, _ = io.Copy(dataStream, in)
+		dataStream.Close()
+	}()
+
+	_, err = io.Copy(out, dataStream)
+	if err != nil {
+		return err
+	}
+
+	return <-errorChan
+}
+
 func (c *Cluster) execPod(namespace, podName, containerName string,
 	command string, stdin io.Reader, stdout, stderr io.Writer) error {
 	cmd := []string{
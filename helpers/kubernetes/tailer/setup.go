@@ -39,8 +39,13 @@ type ContainerLogLine struct {
 	ContainerName string
 	PodName       string
 	Namespace     string
+	AppName       string    // Taken from the AppNameLabel of the pod
+	Timestamp     time.Time // Only set when the Timestamps are requested
 }
 
+// AppNameLabel is the label of pods naming the application they belong to
+const AppNameLabel = "app.kubernetes.io/name"
+
 // FetchLogs writes all the logs of the matching containers to the logChan.
 // If ctx is Done() the method stops even if not all logs are fetched.
 func FetchLogs(ctx context.Context, logChan chan ContainerLogLine, wg *sync.WaitGroup, config *Config, cluster *kubernetes.Cluster) error {
@@ -60,7 +65,7 @@ func FetchLogs(ctx context.Context, logChan chan ContainerLogLine, wg *sync.Wait
 
 	tails := []*Tail{}
 	newTail := func(pod corev1.Pod, c corev1.Container) *Tail {
-		return NewTail(pod.Namespace, pod.Name, c.Name,
+		return NewTail(pod.Namespace, pod.Name, c.Name, pod.Labels[AppNameLabel],
 			tracelog.NewLogger().WithName("log-tracing"),
 			cluster.Kubectl,
 			&TailOptions{
@@ -85,6 +90,9 @@ func FetchLogs(ctx context.Context, logChan chan ContainerLogLine, wg *sync.Wait
 	}
 
 	for _, pod := range podList.Items {
+		if config.PodQuery != nil && !config.PodQuery.MatchString(pod.Name) {
+			continue
+		}
 		for _, c := range pod.Spec.Containers {
 			if !acceptable(c) {
 				continue
@@ -137,7 +145,7 @@ func StreamLogs(ctx context.Context, logChan chan ContainerLogLine, wg *sync.Wai
 				break
 			}
 
-			tail := NewTail(p.Namespace, p.Pod, p.Container,
+			tail := NewTail(p.Namespace, p.Pod, p.Container, p.AppName,
 				tracelog.NewLogger().WithName("log-tracing"),
 				cluster.Kubectl,
 				&TailOptions{
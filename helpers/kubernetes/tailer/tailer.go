@@ -7,6 +7,7 @@ import (
 	"io"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -17,6 +18,7 @@ type Tail struct {
 	Namespace     string
 	PodName       string
 	ContainerName string
+	AppName       string
 	Options       *TailOptions
 	logger        logr.Logger
 	clientSet     *kubernetes.Clientset
@@ -34,11 +36,12 @@ type TailOptions struct {
 }
 
 // NewTail returns a new tail for a Kubernetes container inside a pod
-func NewTail(namespace, podName, containerName string, logger logr.Logger, clientSet *kubernetes.Clientset, options *TailOptions) *Tail {
+func NewTail(namespace, podName, containerName, appName string, logger logr.Logger, clientSet *kubernetes.Clientset, options *TailOptions) *Tail {
 	return &Tail{
 		Namespace:     namespace,
 		PodName:       podName,
 		ContainerName: containerName,
+		AppName:       appName,
 		Options:       options,
 		logger:        logger,
 		clientSet:     clientSet,
@@ -88,6 +91,11 @@ OUTER:
 
 		str := strings.TrimRight(string(line), "\r\n\t ")
 
+		var timestamp time.Time
+		if t.Options.Timestamps {
+			timestamp, str = SplitTimestamp(str)
+		}
+
 		for _, rex := range t.Options.Exclude {
 			if rex.MatchString(str) {
 				continue OUTER
@@ -112,6 +120,23 @@ OUTER:
 			ContainerName: t.ContainerName,
 			PodName:       t.PodName,
 			Namespace:     t.Namespace,
+			AppName:       t.AppName,
+			Timestamp:     timestamp,
 		}
 	}
 }
+
+// SplitTimestamp splits the timestamp leading a log line requested with
+// timestamps from the message following it, separated by a space. A
+// line without a timestamp is all message, with a zero timestamp.
+func SplitTimestamp(line string) (time.Time, string) {
+	parts := strings.SplitN(line, " ", 2)
+	timestamp, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, line
+	}
+	if len(parts) == 1 {
+		return timestamp, ""
+	}
+	return timestamp, parts[1]
+}
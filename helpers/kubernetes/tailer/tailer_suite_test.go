@@ -0,0 +1,13 @@
+package tailer_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestTailer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tailer Suite")
+}
@@ -0,0 +1,39 @@
+package tailer_test
+
+import (
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes/tailer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SplitTimestamp", func() {
+	It("splits the timestamp from the message", func() {
+		timestamp, message := tailer.SplitTimestamp("2021-07-01T10:20:30.123456789Z listening on :8080")
+		Expect(timestamp).To(Equal(time.Date(2021, 7, 1, 10, 20, 30, 123456789, time.UTC)))
+		Expect(message).To(Equal("listening on :8080"))
+	})
+
+	It("keeps the spaces of the message", func() {
+		_, message := tailer.SplitTimestamp("2021-07-01T10:20:30Z  indented  text")
+		Expect(message).To(Equal(" indented  text"))
+	})
+
+	It("returns an empty message for a timestamp alone", func() {
+		timestamp, message := tailer.SplitTimestamp("2021-07-01T10:20:30Z")
+		Expect(timestamp.IsZero()).To(BeFalse())
+		Expect(message).To(BeEmpty())
+	})
+
+	It("returns the line as message without a timestamp", func() {
+		timestamp, message := tailer.SplitTimestamp("listening on :8080")
+		Expect(timestamp.IsZero()).To(BeTrue())
+		Expect(message).To(Equal("listening on :8080"))
+
+		timestamp, message = tailer.SplitTimestamp("")
+		Expect(timestamp.IsZero()).To(BeTrue())
+		Expect(message).To(BeEmpty())
+	})
+})
@@ -19,6 +19,7 @@ type Target struct {
 	Namespace string
 	Pod       string
 	Container string
+	AppName   string
 }
 
 // GetID returns the ID of the object
@@ -77,6 +78,7 @@ func Watch(ctx context.Context, i v1.PodInterface, podFilter *regexp.Regexp, con
 								Namespace: pod.Namespace,
 								Pod:       pod.Name,
 								Container: c.Name,
+								AppName:   pod.Labels[AppNameLabel],
 							}
 						}
 					}
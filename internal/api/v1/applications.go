@@ -3,8 +3,11 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
@@ -20,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 type ApplicationsController struct {
@@ -153,6 +157,11 @@ func (hc ApplicationsController) Show(w http.ResponseWriter, r *http.Request) AP
 		app.Status = `Inactive, without workload. Launch via "epinio app push"`
 	}
 
+	app.Staging, err = application.CurrentStaging(ctx, cluster, app.AppRef())
+	if err != nil {
+		return InternalError(err)
+	}
+
 	js, err := json.Marshal(app)
 	if err != nil {
 		return InternalError(err)
@@ -204,9 +213,9 @@ func (hc ApplicationsController) Update(w http.ResponseWriter, r *http.Request)
 	}
 
 	if app == nil {
-		// App without workload cannot be scaled at the moment.
+		// App without workload cannot be scaled or limited at the moment.
 		// TODO: Extend to stash the request in the app or attached resource
-		return NewAPIError("Unable to scale application without workload", "", http.StatusBadRequest)
+		return NewAPIError("Unable to update application without workload", "", http.StatusBadRequest)
 	}
 
 	defer r.Body.Close()
@@ -221,15 +230,78 @@ func (hc ApplicationsController) Update(w http.ResponseWriter, r *http.Request)
 		return BadRequest(err)
 	}
 
-	if updateRequest.Instances < 0 {
+	if updateRequest.Instances != nil && *updateRequest.Instances < 0 {
 		return NewBadRequest("instances param should be integer equal or greater than zero")
 	}
 
+	if updateRequest.Instances != nil {
+		autoscaling, err := application.Autoscaler(ctx, cluster.Kubectl, app.AppRef())
+		if err != nil {
+			return InternalError(err)
+		}
+		if autoscaling != nil {
+			return NewBadRequest("instances of an autoscaled application cannot be set, change or turn off its autoscaling instead")
+		}
+		if app.Stopped {
+			return NewBadRequest("instances of a stopped application cannot be set, start it first")
+		}
+	}
+
+	limits, err := organizations.Limits(ctx, cluster.Kubectl, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if apierr := checkResource("memory", updateRequest.Memory, limits.MaxMemory); apierr != nil {
+		return apierr
+	}
+	if apierr := checkResource("cpu", updateRequest.CPU, limits.MaxCPU); apierr != nil {
+		return apierr
+	}
+
 	workload := application.NewWorkload(cluster, app.AppRef())
-	err = workload.Scale(r.Context(), updateRequest.Instances)
+
+	if updateRequest.Instances != nil {
+		err = workload.Scale(ctx, *updateRequest.Instances)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	if updateRequest.Memory != "" || updateRequest.CPU != "" {
+		err = workload.SetResources(ctx, updateRequest.Memory, updateRequest.CPU)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	return nil
+}
+
+// checkResource validates the quantity requested for the named
+// resource, against the maximum of the organization, if any.
+func checkResource(name, quantity, max string) APIErrors {
+	if quantity == "" {
+		return nil
+	}
+
+	q, err := resource.ParseQuantity(quantity)
+	if err != nil {
+		return NewBadRequest(fmt.Sprintf("bad %s '%s': %s", name, quantity, err.Error()))
+	}
+	if q.Sign() <= 0 {
+		return NewBadRequest(fmt.Sprintf("%s should be greater than zero", name))
+	}
+
+	if max == "" {
+		return nil
+	}
+	maxQ, err := resource.ParseQuantity(max)
 	if err != nil {
 		return InternalError(err)
 	}
+	if q.Cmp(maxQ) > 0 {
+		return NewBadRequest(fmt.Sprintf("%s %s exceeds the maximum %s of the organization", name, quantity, max))
+	}
 
 	return nil
 }
@@ -289,6 +361,41 @@ func (hc ApplicationsController) Logs(w http.ResponseWriter, r *http.Request) {
 	queryValues := r.URL.Query()
 	followStr := queryValues.Get("follow")
 
+	options, apierr := logOptions(queryValues)
+	if apierr != nil {
+		jsonErrorResponse(w, apierr)
+		return
+	}
+
+	if options.Instance != "" {
+		if appName == "" {
+			jsonErrorResponse(w, NewBadRequest("instance param is only supported for the logs of applications"))
+			return
+		}
+		instances, err := application.PodInstances(ctx, cluster.Kubectl, models.NewAppRef(appName, org))
+		if err != nil {
+			jsonErrorResponse(w, InternalError(err))
+			return
+		}
+		// The choice is non-empty here, by name or by index. An
+		// unknown instance is an error, there is no fallback
+		options.Instance, apierr = selectInstance(instances, options.Instance)
+		if apierr != nil {
+			jsonErrorResponse(w, apierr)
+			return
+		}
+	}
+
+	// The logs of past staging runs are kept beyond their pods
+	var saved []tailer.ContainerLogLine
+	if stageID != "" {
+		saved, err = application.SavedStagingLogs(ctx, cluster.Kubectl, org, stageID)
+		if err != nil {
+			jsonErrorResponse(w, InternalError(err))
+			return
+		}
+	}
+
 	var upgrader = websocket.Upgrader{}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -304,19 +411,60 @@ func (hc ApplicationsController) Logs(w http.ResponseWriter, r *http.Request) {
 	log := tracelog.Logger(ctx)
 
 	hc.conn = conn
-	err = hc.streamPodLogs(ctx, org, appName, stageID, cluster, follow)
+	err = hc.streamPodLogs(ctx, func(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.WaitGroup) error {
+		if saved != nil {
+			return application.ReplayLogs(ctx, logChan, saved, options)
+		}
+		return application.Logs(ctx, logChan, wg, cluster, follow, appName, stageID, org, options)
+	})
 	if err != nil {
 		log.V(1).Error(err, "error occured after upgrading the websockets connection")
 		return
 	}
 }
 
-// streamPodLogs sends the logs of any containers matching orgName, appName
-// and stageID to hc.conn (websockets) until ctx is Done or the connection is
-// closed.
+// logOptions returns the log options found in the query parameters,
+// see models.LogOptions.
+func logOptions(queryValues url.Values) (models.LogOptions, APIErrors) {
+	options := models.LogOptions{
+		Timestamps: queryValues.Get("timestamps") == "true",
+		Include:    queryValues["include"],
+		Exclude:    queryValues["exclude"],
+		Instance:   queryValues.Get("instance"),
+	}
+
+	if since := queryValues.Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d < 0 {
+			return options, NewBadRequest("since param should be a positive duration")
+		}
+		options.Since = d
+	}
+
+	if tail := queryValues.Get("tail"); tail != "" {
+		lines, err := strconv.ParseInt(tail, 10, 64)
+		if err != nil || lines < 0 {
+			return options, NewBadRequest("tail param should be integer equal or greater than zero")
+		}
+		options.Tail = &lines
+	}
+
+	if _, _, err := options.Filters(); err != nil {
+		return options, BadRequest(err)
+	}
+
+	return options, nil
+}
+
+// logsFunc writes log lines to the logChan, like application.Logs
+type logsFunc func(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.WaitGroup) error
+
+// streamPodLogs sends the logs written by the logs function, e.g. those of any
+// containers matching an org, app and stageID, to hc.conn (websockets) until
+// ctx is Done or the connection is closed.
 // Internally this uses two concurrent "threads" talking with each other
 // over the logChan. This is a channel of ContainerLogLine.
-// The first thread runs `application.Logs` in a go routine. It spins up a number of supporting go routines
+// The first thread runs the logs function, e.g. `application.Logs`, in a go routine. It spins up a number of supporting go routines
 // that are stopped when the passed context is "Done()". The parent go routine
 // waits until all the subordinate routines are stopped. It does this by waiting on a WaitGroup.
 // When that happens the parent go routine closes the logChan. This signals
@@ -326,7 +474,7 @@ func (hc ApplicationsController) Logs(w http.ResponseWriter, r *http.Request) {
 // connection is closed. In any case it will call the cancel func that will stop
 // all the children go routines described above and then will wait for their parent
 // go routine to stop too (using another WaitGroup).
-func (hc ApplicationsController) streamPodLogs(ctx context.Context, orgName, appName, stageID string, cluster *kubernetes.Cluster, follow bool) error {
+func (hc ApplicationsController) streamPodLogs(ctx context.Context, logs logsFunc) error {
 	logger := tracelog.NewLogger().WithName("streaming-logs-to-websockets").V(1)
 	logChan := make(chan tailer.ContainerLogLine)
 	logCtx, logCancelFunc := context.WithCancel(ctx)
@@ -335,7 +483,7 @@ func (hc ApplicationsController) streamPodLogs(ctx context.Context, orgName, app
 	wg.Add(1)
 	go func(outerWg *sync.WaitGroup) {
 		var tailWg sync.WaitGroup
-		err := application.Logs(logCtx, logChan, &tailWg, cluster, follow, appName, stageID, orgName)
+		err := logs(logCtx, logChan, &tailWg)
 		if err != nil {
 			logger.Error(err, "setting up log routines failed")
 		}
@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/organizations"
+)
+
+// Autoscale turns on the horizontal autoscaling of the application, or
+// changes its settings. The application needs a workload to scale.
+func (hc ApplicationsController) Autoscale(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var req models.AutoscaleRequest
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		return BadRequest(err)
+	}
+
+	if req.Min != nil && *req.Min < 1 {
+		return NewBadRequest("min param should be integer greater than zero")
+	}
+	if req.Max < 1 {
+		return NewBadRequest("max param should be integer greater than zero")
+	}
+	if req.Min != nil && *req.Min > req.Max {
+		return NewBadRequest("min param should not be greater than max")
+	}
+	if req.CPUPercent < 0 {
+		return NewBadRequest("cpu_percent param should be integer equal or greater than zero")
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+
+	app, err := application.Get(ctx, cluster, appRef)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return AppIsNotKnown(appName)
+		}
+		return InternalError(err)
+	}
+
+	workload, err := application.Lookup(ctx, cluster, org, appName)
+	if err != nil {
+		return InternalError(err)
+	}
+	if workload == nil {
+		return NewBadRequest("Unable to autoscale application without workload")
+	}
+
+	// The cpu usage is measured against the cpu requested by the
+	// instances. Without a request the autoscaler does nothing.
+	_, cpu, err := application.DeployedResources(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	limits, err := organizations.Limits(ctx, cluster.Kubectl, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if cpu == "" && limits.DefaultCPU == "" {
+		return NewBadRequest("Unable to autoscale application without cpu, set it with 'epinio app update --cpu'")
+	}
+
+	owner := metav1.OwnerReference{
+		APIVersion: app.GetAPIVersion(),
+		Kind:       app.GetKind(),
+		Name:       app.GetName(),
+		UID:        app.GetUID(),
+	}
+
+	log.Info("autoscaling app", "org", org, "app", appName, "min", req.Min, "max", req.Max, "cpu", req.CPUPercent)
+
+	err = application.Autoscale(ctx, cluster.Kubectl, appRef, owner, req)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// AutoscaleDelete turns the autoscaling of the application off. The
+// application keeps the instances it has at the time.
+func (hc ApplicationsController) AutoscaleDelete(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+	if apierr := checkApp(ctx, cluster, appRef); apierr != nil {
+		return apierr
+	}
+
+	log.Info("stop autoscaling app", "org", org, "app", appName)
+
+	err = application.DeleteAutoscaler(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
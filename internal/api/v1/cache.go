@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/organizations"
+)
+
+// CacheClear removes the build cache of the application. The next
+// staging of the application starts from scratch.
+func (hc ApplicationsController) CacheClear(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+
+	exists, err = application.Exists(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return AppIsNotKnown(appName)
+	}
+
+	staging, err := application.CurrentStaging(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	if staging != "" {
+		return NewBadRequest("cannot clear the build cache while the application is staging")
+	}
+
+	log.Info("clearing build cache", "org", org, "app", appName)
+
+	err = application.ClearCache(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,514 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
+	tekton "github.com/tektoncd/pipeline/pkg/client/clientset/versioned/typed/pipeline/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	k8s "k8s.io/client-go/kubernetes"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/randstr"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/domain"
+)
+
+// deployParam holds the settings of a single deployment of an
+// application workload
+type deployParam struct {
+	models.AppRef
+	StageID      string
+	Image        string
+	Instances    *int32
+	Routes       []string
+	Port         int32
+	Processes    []models.Process
+	Environment  models.EnvVariableList
+	HealthChecks models.HealthChecks
+}
+
+// Deploy creates or updates the workload of the application, i.e. its
+// Deployment, Service and Ingress, using the image of a staging run,
+// or a prebuilt image when no staging run is referenced. The
+// deployment is recorded as a new release of the application.
+func (hc ApplicationsController) Deploy(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	p := httprouter.ParamsFromContext(ctx)
+	org := p.ByName("org")
+	name := p.ByName("app")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	req := models.DeployRequest{}
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		return NewBadRequest("Failed to construct an Application from the request", err.Error())
+	}
+
+	if name != req.App.Name {
+		return NewBadRequest("name parameter from URL does not match name param in body")
+	}
+	if org != req.App.Org {
+		return NewBadRequest("org parameter from URL does not match org param in body")
+	}
+
+	if req.Image.ID == "" && req.Stage.ID == "" {
+		return NewBadRequest("image parameter is missing")
+	}
+	if apierr := validateDeploySettings(req.DeploySettings); apierr != nil {
+		return apierr
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err, "failed to get access to a kube client")
+	}
+
+	user, _, _ := r.BasicAuth()
+	routes, apierr := deploy(ctx, cluster, req, user, DeployedByClient)
+	if apierr != nil {
+		return apierr
+	}
+
+	err = jsonResponse(w, models.DeployResponse{Routes: routes})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// deploy deploys the image of the request, and records it as a new
+// release of the application, made by the user. The image of a staging
+// run is the one the run built for the application. The run is claimed
+// by the claimant, DeployedByClient or DeployedByServer, for the other
+// not to deploy it as well. A run claimed by the other is not deployed
+// again, its routes are returned as they are.
+func deploy(ctx context.Context, cluster *kubernetes.Cluster, req models.DeployRequest, user, claimant string) ([]string, APIErrors) {
+	log := tracelog.Logger(ctx)
+
+	// check application resource
+	app, err := application.Get(ctx, cluster, req.App)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, AppIsNotKnown("cannot deploy app, application resource is missing")
+		}
+		return nil, InternalError(err, "failed to get the application resource")
+	}
+
+	owner := metav1.OwnerReference{
+		APIVersion: app.GetAPIVersion(),
+		Kind:       app.GetKind(),
+		Name:       app.GetName(),
+		UID:        app.GetUID(),
+	}
+
+	// Without an explicit port the application keeps the port it
+	// was deployed with
+	port := req.Port
+	if port == 0 {
+		port, err = application.DeployedPort(ctx, cluster.Kubectl, req.App)
+		if err != nil {
+			return nil, InternalError(err)
+		}
+	}
+
+	release := models.Release{
+		ID:        req.Stage.ID,
+		Image:     req.Image.ID,
+		Port:      port,
+		Processes: req.Processes,
+		Created:   time.Now().UTC(),
+		User:      user,
+	}
+
+	if req.Stage.ID == "" {
+		// A prebuilt image, without staging. Give the release an
+		// identity of its own.
+		release.ID, err = randstr.Hex16()
+		if err != nil {
+			return nil, InternalError(err, "failed to generate a uid")
+		}
+	} else {
+		pr, apierr := stagingRun(ctx, cluster, req.App, req.Stage.ID)
+		if apierr != nil {
+			return nil, apierr
+		}
+		image, err := stagedImage(ctx, pr)
+		if err != nil {
+			return nil, InternalError(err)
+		}
+		if req.Image.ID != "" && req.Image.ID != image {
+			return nil, NewBadRequest(fmt.Sprintf("image '%s' was not built by staging run '%s'", req.Image.ID, req.Stage.ID))
+		}
+		release.Image = image
+		if digest := stagingResult(pr, "APP_IMAGE_DIGEST"); digest != "" {
+			release.Image = fmt.Sprintf("%s@%s", release.Image, digest)
+		}
+		release.Git = stagingGitRef(pr)
+		release.BuilderImage = app.GetAnnotations()[application.BuilderImageAnnotation]
+		release.Buildpacks, release.ProcessType = stagingBuild(pr)
+
+		claimed, err := claimStaging(ctx, cluster, req.Stage.ID, claimant)
+		if err != nil {
+			return nil, InternalError(err, "failed to mark the staging run as deployed")
+		}
+		if !claimed {
+			log.Info("staged app deployed already", "org", req.App.Org, "app", req.App.Name, "stage", req.Stage.ID)
+
+			routes, err := application.DeployedRoutes(ctx, cluster.Kubectl, req.App)
+			if err != nil {
+				return nil, InternalError(err)
+			}
+			return routes, nil
+		}
+	}
+
+	// determine runtime environment, if any
+	release.Environment, err = application.Environment(ctx, cluster, req.App)
+	if err != nil {
+		return nil, InternalError(err, "failed to access application runtime environment")
+	}
+
+	log.Info("deploying app", "org", req.App.Org, "app", req.App, "image", release.Image)
+
+	routes, apierr := deployApp(ctx, cluster, owner, deployParam{
+		AppRef:       req.App,
+		StageID:      release.ID,
+		Image:        release.Image,
+		Instances:    req.Instances,
+		Routes:       req.Routes,
+		Port:         release.Port,
+		Processes:    release.Processes,
+		Environment:  release.Environment,
+		HealthChecks: req.HealthChecks,
+	})
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	err = application.RecordRelease(ctx, cluster.Kubectl, req.App, owner, release)
+	if err != nil {
+		return nil, InternalError(err, "failed to record the release")
+	}
+
+	log.Info("deployed app", "org", req.App.Org, "app", req.App, "stage", req.Stage.ID)
+
+	return routes, nil
+}
+
+// deployApp renders the workload of the application, and ensures the
+// certificates for its routes. Without routes the application keeps
+// the routes it has, or gets the default route when it has none.
+// Missing health checks are kept the same way. It returns the routes.
+func deployApp(ctx context.Context, cluster *kubernetes.Cluster, owner metav1.OwnerReference, param deployParam) ([]string, APIErrors) {
+	// find out the instances. Those of an autoscaled application are
+	// managed by its autoscaler. A stopped application stays stopped.
+	autoscaling, err := application.Autoscaler(ctx, cluster.Kubectl, param.AppRef)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+	stopped, err := application.Stopped(ctx, cluster, param.AppRef)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
+	var instances int32
+	if param.Instances != nil && autoscaling == nil && !stopped {
+		instances = int32(*param.Instances)
+	} else {
+		instances, err = existingReplica(ctx, cluster.Kubectl, param.AppRef)
+		if err != nil {
+			return nil, InternalError(err)
+		}
+	}
+
+	// The resources are kept from the current workload. The maximums
+	// of the organization may have been lowered since.
+	if apierr := checkDeployedResources(ctx, cluster, param.AppRef); apierr != nil {
+		return nil, apierr
+	}
+
+	routesLock.Lock()
+	defer routesLock.Unlock()
+
+	previous, err := application.DeployedRoutes(ctx, cluster.Kubectl, param.AppRef)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
+	routes := param.Routes
+	if len(routes) == 0 {
+		routes = previous
+	}
+	if len(routes) == 0 {
+		mainDomain, err := domain.MainDomain(ctx)
+		if err != nil {
+			return nil, InternalError(err)
+		}
+		routes = []string{fmt.Sprintf("%s.%s", param.Name, mainDomain)}
+	}
+
+	if apierr := checkRoutes(ctx, cluster, param.AppRef, routes); apierr != nil {
+		return nil, apierr
+	}
+
+	healthChecks, err := application.DeployedHealthChecks(ctx, cluster.Kubectl, param.AppRef)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+	if param.HealthChecks.Liveness != nil {
+		healthChecks.Liveness = param.HealthChecks.Liveness
+	}
+	if param.HealthChecks.Readiness != nil {
+		healthChecks.Readiness = param.HealthChecks.Readiness
+	}
+
+	// The JSON of the bindings gets the current credentials of the
+	// services
+	err = application.RefreshServicesJSON(ctx, cluster.Kubectl, param.AppRef)
+	if err != nil {
+		return nil, InternalError(err, "failed to refresh the bindings of the application")
+	}
+
+	err = application.Deploy(ctx, cluster.Kubectl, application.DeployParam{
+		AppRef:       param.AppRef,
+		Owner:        owner,
+		StageID:      param.StageID,
+		Image:        param.Image,
+		Instances:    instances,
+		Routes:       routes,
+		Port:         param.Port,
+		Processes:    param.Processes,
+		Environment:  param.Environment,
+		HealthChecks: healthChecks,
+		Stopped:      stopped,
+	})
+	if err != nil {
+		return nil, InternalError(err, "failed to deploy the application workload")
+	}
+
+	if apierr := syncCertificates(ctx, cluster, owner, param.AppRef, previous, routes); apierr != nil {
+		return nil, apierr
+	}
+
+	return routes, nil
+}
+
+// validateDeploySettings checks the settings of a deploy, or stage,
+// request.
+func validateDeploySettings(settings models.DeploySettings) APIErrors {
+	if settings.Instances != nil && *settings.Instances < 0 {
+		return NewBadRequest("instances param should be integer equal or greater than zero")
+	}
+	if settings.Port < 0 || settings.Port > 65535 {
+		return NewBadRequest("port param should be an integer between 1 and 65535")
+	}
+	if apierr := validateProcesses(settings.Processes); apierr != nil {
+		return apierr
+	}
+	if apierr := validateHealthCheck("liveness", settings.HealthChecks.Liveness); apierr != nil {
+		return apierr
+	}
+	return validateHealthCheck("readiness", settings.HealthChecks.Readiness)
+}
+
+// validateProcesses checks the non-web processes of a deploy request
+// for names usable in kubernetes resources, without duplicates.
+func validateProcesses(processes []models.Process) APIErrors {
+	seen := map[string]bool{}
+	for _, process := range processes {
+		if errs := validation.IsDNS1123Label(process.Name); len(errs) > 0 {
+			return NewBadRequest(fmt.Sprintf("bad process name '%s': %s", process.Name, strings.Join(errs, ", ")))
+		}
+		if process.Name == models.DefaultProcessType {
+			return NewBadRequest(fmt.Sprintf("process '%s' is the application itself", process.Name))
+		}
+		if seen[process.Name] {
+			return NewBadRequest(fmt.Sprintf("process '%s' is declared more than once", process.Name))
+		}
+		seen[process.Name] = true
+		if process.Instances != nil && *process.Instances < 0 {
+			return NewBadRequest(fmt.Sprintf("instances of process '%s' should be integer equal or greater than zero", process.Name))
+		}
+	}
+	return nil
+}
+
+// validateHealthCheck checks the named health check of a deploy
+// request, if any.
+func validateHealthCheck(name string, check *models.HealthCheck) APIErrors {
+	if check == nil {
+		return nil
+	}
+
+	switch check.Type {
+	case models.HealthCheckHTTP:
+		if check.Path != "" && !strings.HasPrefix(check.Path, "/") {
+			return NewBadRequest(fmt.Sprintf("path of the %s check has to start with /", name))
+		}
+	case models.HealthCheckTCP, models.HealthCheckNone:
+	case models.HealthCheckExec:
+		if len(check.Command) == 0 {
+			return NewBadRequest(fmt.Sprintf("the %s check has no command", name))
+		}
+	default:
+		return NewBadRequest(fmt.Sprintf("unknown type '%s' of the %s check, expected http, tcp, exec or none", check.Type, name))
+	}
+
+	if check.Port < 0 || check.Port > 65535 {
+		return NewBadRequest(fmt.Sprintf("port of the %s check should be an integer between 1 and 65535", name))
+	}
+	if check.InitialDelay < 0 || check.Period < 0 || check.Timeout < 0 || check.FailureThreshold < 0 {
+		return NewBadRequest(fmt.Sprintf("times and threshold of the %s check should not be negative", name))
+	}
+
+	return nil
+}
+
+// stagingRun returns the identified staging run of the application,
+// after checking that it completed successfully.
+func stagingRun(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, id string) (*v1beta1.PipelineRun, APIErrors) {
+	client, err := stagingClient(cluster)
+	if err != nil {
+		return nil, InternalError(err, "failed to get access to a tekton client")
+	}
+
+	pr, err := client.Get(ctx, id, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, NewNotFoundError(fmt.Sprintf("staging run '%s' does not exist", id))
+		}
+		return nil, InternalError(err)
+	}
+	if pr.Labels["app.kubernetes.io/name"] != appRef.Name || pr.Labels["app.kubernetes.io/part-of"] != appRef.Org {
+		return nil, NewNotFoundError(fmt.Sprintf("staging run '%s' does not exist", id))
+	}
+
+	if !stagingSucceeded(pr) {
+		return nil, NewBadRequest(fmt.Sprintf("staging run '%s' has not completed successfully", id))
+	}
+
+	return pr, nil
+}
+
+// stagingClient returns a client for the staging runs
+func stagingClient(cluster *kubernetes.Cluster) (tekton.PipelineRunInterface, error) {
+	cs, err := versioned.NewForConfig(cluster.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+	return cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace), nil
+}
+
+// stagedImage returns the image the staging run built, as it is pulled
+// by the workload of the application.
+func stagedImage(ctx context.Context, pr *v1beta1.PipelineRun) (string, error) {
+	registry, _, err := registries(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	param := stageParam{
+		AppRef: models.NewAppRef(pr.Labels["app.kubernetes.io/name"], pr.Labels["app.kubernetes.io/part-of"]),
+		Git:    stagingGitRef(pr),
+	}
+	if param.Git == nil {
+		return "", fmt.Errorf("staging run '%s' has no sources", pr.Name)
+	}
+
+	return param.ImageURL(registry), nil
+}
+
+// stagingSucceeded returns true if the PipelineRun has completed
+// without failure
+func stagingSucceeded(pr *v1beta1.PipelineRun) bool {
+	for _, c := range pr.Status.Conditions {
+		if c.Type == "Succeeded" {
+			return c.IsTrue()
+		}
+	}
+	return false
+}
+
+// stagingResult returns the value of the named result of the
+// PipelineRun, or the empty string if there is no such result.
+func stagingResult(pr *v1beta1.PipelineRun, name string) string {
+	for _, result := range pr.Status.PipelineResults {
+		if result.Name == name {
+			return result.Value
+		}
+	}
+	return ""
+}
+
+// stagingBuild returns the buildpacks and the process type the
+// PipelineRun was staged with. The default process type is returned as
+// empty.
+func stagingBuild(pr *v1beta1.PipelineRun) ([]string, string) {
+	var buildpacks []string
+	processType := ""
+	for _, param := range pr.Spec.Params {
+		switch param.Name {
+		case "BUILDPACKS":
+			if len(param.Value.ArrayVal) > 0 {
+				buildpacks = param.Value.ArrayVal
+			}
+		case "PROCESS_TYPE":
+			if param.Value.StringVal != models.DefaultProcessType {
+				processType = param.Value.StringVal
+			}
+		}
+	}
+	return buildpacks, processType
+}
+
+// stagingGitRef returns the sources the PipelineRun was staged from
+func stagingGitRef(pr *v1beta1.PipelineRun) *models.GitRef {
+	for _, resource := range pr.Spec.Resources {
+		if resource.Name != "source-repo" || resource.ResourceSpec == nil {
+			continue
+		}
+		ref := &models.GitRef{}
+		for _, param := range resource.ResourceSpec.Params {
+			switch param.Name {
+			case "revision":
+				ref.Revision = param.Value
+			case "url":
+				ref.URL = param.Value
+			}
+		}
+		return ref
+	}
+	return nil
+}
+
+func existingReplica(ctx context.Context, client *k8s.Clientset, app models.AppRef) (int32, error) {
+	// if a deployment exists, use that deployment's replica count
+	result, err := client.AppsV1().Deployments(app.Org).Get(ctx, app.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return DefaultInstances, nil
+		}
+		return 0, err
+	}
+	return *result.Spec.Replicas, nil
+}
@@ -0,0 +1,193 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	tekton "github.com/tektoncd/pipeline/pkg/client/clientset/versioned/typed/pipeline/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+)
+
+const (
+	// DeployAnnotation holds the deploy request of a staging run,
+	// for its deployment by the server
+	DeployAnnotation = "epinio.suse.org/deploy"
+	// DeployUserAnnotation holds the user who staged the run
+	DeployUserAnnotation = "epinio.suse.org/deploy-user"
+	// DeployedAnnotation marks a staging run as deployed. It holds
+	// who claimed the deployment, DeployedByClient or
+	// DeployedByServer.
+	DeployedAnnotation = "epinio.suse.org/deployed"
+	// DeployedByClient claims a staging run for its client
+	DeployedByClient = "client"
+	// DeployedByServer claims a staging run for the server
+	DeployedByServer = "server"
+
+	// stagedDeployGrace is the time the client has to deploy a
+	// completed staging run, before the server deploys it
+	stagedDeployGrace = time.Minute
+)
+
+// DeployStaged deploys, every interval until the context is done, the
+// staging runs which completed successfully, but were not deployed by
+// their client in time. This way an interrupted client does not leave
+// its application staged, but not deployed.
+func DeployStaged(ctx context.Context, logger logr.Logger, interval time.Duration) {
+	ctx = context.WithValue(ctx, tracelog.CtxLoggerKey{}, logger)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := deployStaged(ctx, time.Now()); err != nil {
+				logger.Error(err, "deploying staged applications")
+			}
+		}
+	}
+}
+
+// deployStaged deploys the staging runs due for deployment by the
+// server, as of now.
+func deployStaged(ctx context.Context, now time.Time) error {
+	log := tracelog.Logger(ctx)
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return err
+	}
+	client, err := stagingClient(cluster)
+	if err != nil {
+		return err
+	}
+
+	runs, err := client.List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/managed-by=epinio,app.kubernetes.io/component=staging",
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range runs.Items {
+		pr := &runs.Items[i]
+		if !stagedDeployDue(pr, now) {
+			continue
+		}
+
+		req := models.DeployRequest{}
+		if err := json.Unmarshal([]byte(pr.Annotations[DeployAnnotation]), &req); err != nil {
+			log.Error(err, "bad deploy request of staging run", "uid", pr.Name)
+			continue
+		}
+
+		// A release made after the staging, e.g. of a prebuilt
+		// image, is not replaced by it
+		releases, err := application.Releases(ctx, cluster.Kubectl, req.App)
+		if err != nil {
+			log.Error(err, "listing releases", "org", req.App.Org, "app", req.App.Name)
+			continue
+		}
+		newer := len(releases) > 0 && releases[0].Created.After(pr.Status.CompletionTime.Time)
+
+		// Marking the run conflicts with a client claiming it
+		// at the same time. The client wins, and deploys it. A
+		// client coming later finds the run claimed by the
+		// server, and does not deploy it again.
+		err = markDeployed(ctx, client, pr, DeployedByServer)
+		if err != nil {
+			if !apierrors.IsConflict(err) {
+				log.Error(err, "marking staging run as deployed", "uid", pr.Name)
+			}
+			continue
+		}
+		if newer {
+			continue
+		}
+
+		log.Info("deploying staged app", "org", req.App.Org, "app", req.App.Name, "uid", pr.Name)
+
+		_, apierr := deploy(ctx, cluster, req, pr.Annotations[DeployUserAnnotation], DeployedByServer)
+		if apierr != nil {
+			for _, e := range apierr.Errors() {
+				log.Info("failed to deploy staged app", "org", req.App.Org, "app", req.App.Name,
+					"uid", pr.Name, "error", e.Title, "details", e.Details)
+			}
+		}
+	}
+
+	return nil
+}
+
+// stagedDeployDue returns true if the staging run is for the server
+// to deploy as of now, i.e. it succeeded more than the grace period
+// ago, and nobody deployed it yet.
+func stagedDeployDue(pr *v1beta1.PipelineRun, now time.Time) bool {
+	if _, ok := pr.Annotations[DeployAnnotation]; !ok {
+		return false
+	}
+	if _, ok := pr.Annotations[DeployedAnnotation]; ok {
+		return false
+	}
+	if !stagingSucceeded(pr) || pr.Status.CompletionTime == nil {
+		return false
+	}
+	return now.Sub(pr.Status.CompletionTime.Time) > stagedDeployGrace
+}
+
+// claimStaging claims the identified staging run for its deployment by
+// the claimant, DeployedByClient or DeployedByServer. It returns false
+// if the run is claimed by the other already, i.e. it is not for the
+// claimant to deploy it.
+func claimStaging(ctx context.Context, cluster *kubernetes.Cluster, id, claimant string) (bool, error) {
+	client, err := stagingClient(cluster)
+	if err != nil {
+		return false, err
+	}
+	return claimRun(ctx, client, id, claimant)
+}
+
+// claimRun claims the identified staging run for the claimant, as
+// claimStaging does.
+func claimRun(ctx context.Context, client tekton.PipelineRunInterface, id, claimant string) (bool, error) {
+	claimed := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pr, err := client.Get(ctx, id, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if by, ok := pr.Annotations[DeployedAnnotation]; ok {
+			claimed = by == claimant
+			return nil
+		}
+		claimed = true
+		return markDeployed(ctx, client, pr, claimant)
+	})
+
+	return claimed, err
+}
+
+// markDeployed marks the staging run as deployed by the claimant. It
+// fails with a conflict if the run changed since it was read.
+func markDeployed(ctx context.Context, client tekton.PipelineRunInterface, pr *v1beta1.PipelineRun, claimant string) error {
+	pr = pr.DeepCopy()
+	if pr.Annotations == nil {
+		pr.Annotations = map[string]string{}
+	}
+	pr.Annotations[DeployedAnnotation] = claimant
+
+	_, err := client.Update(ctx, pr, metav1.UpdateOptions{})
+	return err
+}
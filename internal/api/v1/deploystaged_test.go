@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/client/clientset/versioned/fake"
+	tekton "github.com/tektoncd/pipeline/pkg/client/clientset/versioned/typed/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("stagedDeployDue", func() {
+	var (
+		pr  *v1beta1.PipelineRun
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+		pr = &v1beta1.PipelineRun{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{DeployAnnotation: "{}"},
+			},
+		}
+		pr.Status.MarkSucceeded("Succeeded", "")
+		pr.Status.CompletionTime = &metav1.Time{Time: now.Add(-2 * stagedDeployGrace)}
+	})
+
+	It("is due for a succeeded run past the grace period", func() {
+		Expect(stagedDeployDue(pr, now)).To(BeTrue())
+	})
+
+	It("is not due within the grace period", func() {
+		pr.Status.CompletionTime = &metav1.Time{Time: now.Add(-stagedDeployGrace / 2)}
+		Expect(stagedDeployDue(pr, now)).To(BeFalse())
+	})
+
+	It("is not due for a deployed run", func() {
+		pr.Annotations[DeployedAnnotation] = DeployedByClient
+		Expect(stagedDeployDue(pr, now)).To(BeFalse())
+	})
+
+	It("is not due for a run without deploy request", func() {
+		pr.Annotations = map[string]string{}
+		Expect(stagedDeployDue(pr, now)).To(BeFalse())
+	})
+
+	It("is not due for a failed run", func() {
+		pr.Status.Conditions[0].Status = corev1.ConditionFalse
+		Expect(stagedDeployDue(pr, now)).To(BeFalse())
+	})
+})
+
+var _ = Describe("claimRun", func() {
+	var (
+		ctx    context.Context
+		client tekton.PipelineRunInterface
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = fake.NewSimpleClientset().TektonV1beta1().PipelineRuns("tekton-staging")
+		_, err := client.Create(ctx, &v1beta1.PipelineRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "run", Namespace: "tekton-staging"},
+		}, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	claimant := func() string {
+		pr, err := client.Get(ctx, "run", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return pr.Annotations[DeployedAnnotation]
+	}
+
+	It("claims an unclaimed run", func() {
+		claimed, err := claimRun(ctx, client, "run", DeployedByClient)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claimed).To(BeTrue())
+		Expect(claimant()).To(Equal(DeployedByClient))
+	})
+
+	It("keeps a run claimed by the claimant", func() {
+		_, err := claimRun(ctx, client, "run", DeployedByServer)
+		Expect(err).ToNot(HaveOccurred())
+
+		claimed, err := claimRun(ctx, client, "run", DeployedByServer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claimed).To(BeTrue())
+	})
+
+	It("refuses a run claimed by the server to a late client", func() {
+		_, err := claimRun(ctx, client, "run", DeployedByServer)
+		Expect(err).ToNot(HaveOccurred())
+
+		claimed, err := claimRun(ctx, client, "run", DeployedByClient)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claimed).To(BeFalse())
+		Expect(claimant()).To(Equal(DeployedByServer))
+	})
+})
@@ -2,6 +2,7 @@ package v1
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -191,6 +192,10 @@ func (hc ApplicationsController) EnvSet(w http.ResponseWriter, r *http.Request)
 
 	err = application.EnvironmentSet(ctx, cluster, app, setRequest)
 	if err != nil {
+		var conflict application.EnvConflictError
+		if errors.As(err, &conflict) {
+			return BadRequest(err)
+		}
 		return InternalError(err)
 	}
 
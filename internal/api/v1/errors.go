@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
 )
 
 // APIActionFunc is matched by all actions. Actions can return a list of errors.
@@ -164,3 +166,24 @@ func ServiceIsNotBound(service string) APIError {
 		"",
 		http.StatusBadRequest)
 }
+
+func RouteAlreadyKnown(route string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Route '%s' already exists", route),
+		"",
+		http.StatusConflict)
+}
+
+func RouteIsNotKnown(route string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Route '%s' does not exist", route),
+		"",
+		http.StatusNotFound)
+}
+
+func RouteInUse(route string, app models.AppRef) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Route '%s' is used by application '%s' in organization '%s'", route, app.Name, app.Org),
+		"",
+		http.StatusConflict)
+}
@@ -0,0 +1,263 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+)
+
+// DefaultExecCommand is the command run by Exec when the client asks
+// for none
+var DefaultExecCommand = []string{"/bin/sh"}
+
+// Exec runs a command in an instance of the application, connected to
+// the websocket of the client. See models.ExecStdin for the messages
+// exchanged. The query parameters select the instance, by name or by
+// its index in the instance list, the command, one parameter per
+// argument, and whether the command gets a terminal.
+func (hc ApplicationsController) Exec(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	queryValues := r.URL.Query()
+	command := queryValues["command"]
+	if len(command) == 0 {
+		command = DefaultExecCommand
+	}
+	tty := queryValues.Get("tty") == "true"
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+
+	appRef := models.NewAppRef(appName, org)
+	if apierr := checkApp(ctx, cluster, appRef); apierr != nil {
+		jsonErrorResponse(w, apierr)
+		return
+	}
+
+	instances, err := application.PodInstances(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+	instance, apierr := selectInstance(instances, queryValues.Get("instance"))
+	if apierr != nil {
+		jsonErrorResponse(w, apierr)
+		return
+	}
+
+	pod, err := cluster.Kubectl.CoreV1().Pods(org).Get(ctx, instance, metav1.GetOptions{})
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+	container := pod.Spec.Containers[0].Name
+
+	var upgrader = websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+
+	log.Info("exec in app", "org", org, "app", appName, "instance", instance, "command", command)
+
+	err = runExec(cluster, conn, org, instance, container, command, tty)
+	if err != nil {
+		log.V(1).Error(err, "error occured after upgrading the websockets connection")
+	}
+}
+
+// selectInstance returns the name of the chosen instance. Without
+// a choice it is the first ready instance serving the web traffic.
+func selectInstance(instances models.AppInstanceList, choice string) (string, APIErrors) {
+	if choice == "" {
+		for _, instance := range instances {
+			if instance.Worker == "" && instance.Ready {
+				return instance.Name, nil
+			}
+		}
+		return "", NewBadRequest("Application has no ready instance")
+	}
+
+	for _, instance := range instances {
+		if instance.Name == choice {
+			return instance.Name, nil
+		}
+	}
+	if index, err := strconv.Atoi(choice); err == nil && index >= 0 && index < len(instances) {
+		return instances[index].Name, nil
+	}
+
+	return "", NewBadRequest(fmt.Sprintf("Application has no instance '%s'", choice))
+}
+
+// runExec runs the command, and relays its streams over the websocket
+// connection until the command ends. The connection is closed at the
+// end.
+func runExec(cluster *kubernetes.Cluster, conn *websocket.Conn, org, pod, container string, command []string, tty bool) error {
+	return relayExec(conn, func(stdin io.Reader, stdout, stderr io.Writer, resize remotecommand.TerminalSizeQueue) error {
+		return cluster.ExecStream(org, pod, container, command, tty, stdin, stdout, stderr, resize)
+	})
+}
+
+// execFunc runs a command with the given streams, until it ends
+type execFunc func(stdin io.Reader, stdout, stderr io.Writer, resize remotecommand.TerminalSizeQueue) error
+
+// relayExec runs the command, and relays its streams over the websocket
+// connection until the command ends. The connection is closed at the
+// end, and the relay of the client's messages ended.
+func relayExec(conn *websocket.Conn, exec execFunc) error {
+	logger := tracelog.NewLogger().WithName("exec-over-websockets").V(1)
+	out := &execConn{conn: conn}
+
+	stdin, stdinWriter := io.Pipe()
+	done := make(chan struct{})
+	resize := resizeQueue{sizes: make(chan remotecommand.TerminalSize), done: done}
+
+	var relay sync.WaitGroup
+	relay.Add(1)
+	defer relay.Wait()
+
+	// Relay stdin and resizes of the client, until the connection is
+	// closed. An empty stdin message closes stdin.
+	go func() {
+		defer relay.Done()
+		defer stdinWriter.Close()
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if len(message) == 0 {
+				continue
+			}
+
+			switch message[0] {
+			case models.ExecStdin:
+				if len(message) == 1 {
+					stdinWriter.Close()
+					continue
+				}
+				if _, err := stdinWriter.Write(message[1:]); err != nil {
+					// The command ended
+					if err == io.EOF {
+						return
+					}
+					logger.Error(err, "failed to write stdin")
+				}
+			case models.ExecResize:
+				var size models.TerminalSize
+				if err := json.Unmarshal(message[1:], &size); err != nil {
+					logger.Error(err, "bad resize message")
+					continue
+				}
+				select {
+				case resize.sizes <- remotecommand.TerminalSize{Width: size.Width, Height: size.Height}:
+				case <-done:
+				}
+			}
+		}
+	}()
+
+	err := exec(stdin,
+		execWriter{conn: out, stream: models.ExecStdout},
+		execWriter{conn: out, stream: models.ExecStderr},
+		resize)
+	close(done)
+
+	// Nothing reads stdin anymore. A write of the relay pending, or
+	// to come, fails.
+	stdin.CloseWithError(io.EOF)
+
+	response := models.ExecResponse{}
+	if err != nil {
+		if exitErr, ok := err.(utilexec.ExitError); ok && exitErr.Exited() {
+			response.ExitCode = exitErr.ExitStatus()
+		} else {
+			response.ExitCode = 1
+			response.Error = err.Error()
+		}
+	}
+
+	result, err := json.Marshal(response)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if err := out.send(models.ExecResult, result); err != nil {
+		conn.Close()
+		return err
+	}
+
+	if err := conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Time{}); err != nil {
+		conn.Close()
+		return err
+	}
+
+	return conn.Close()
+}
+
+// execConn serializes the messages sent over the websocket connection
+type execConn struct {
+	conn *websocket.Conn
+	lock sync.Mutex
+}
+
+func (c *execConn) send(stream byte, data []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.conn.WriteMessage(websocket.BinaryMessage, append([]byte{stream}, data...))
+}
+
+// execWriter sends everything written to it as messages of its stream
+type execWriter struct {
+	conn   *execConn
+	stream byte
+}
+
+func (w execWriter) Write(p []byte) (int, error) {
+	if err := w.conn.send(w.stream, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// resizeQueue passes the terminal sizes sent by the client to the
+// command, until done
+type resizeQueue struct {
+	sizes chan remotecommand.TerminalSize
+	done  chan struct{}
+}
+
+func (q resizeQueue) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-q.sizes:
+		return &size
+	case <-q.done:
+		return nil
+	}
+}
@@ -0,0 +1,116 @@
+package v1
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/websocket"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+var _ = Describe("selectInstance", func() {
+	instances := models.AppInstanceList{
+		{Name: "sample-worker-abc", Worker: "worker", Ready: true},
+		{Name: "sample-def", Ready: false},
+		{Name: "sample-ghi", Ready: true},
+	}
+
+	It("chooses the first ready web instance without a choice", func() {
+		name, apierr := selectInstance(instances, "")
+		Expect(apierr).To(BeNil())
+		Expect(name).To(Equal("sample-ghi"))
+	})
+
+	It("fails without a ready web instance", func() {
+		_, apierr := selectInstance(instances[:2], "")
+		Expect(apierr).ToNot(BeNil())
+		Expect(apierr.FirstStatus()).To(Equal(http.StatusBadRequest))
+
+		_, apierr = selectInstance(models.AppInstanceList{}, "")
+		Expect(apierr).ToNot(BeNil())
+	})
+
+	It("chooses an instance by name, ready or not, worker or not", func() {
+		name, apierr := selectInstance(instances, "sample-def")
+		Expect(apierr).To(BeNil())
+		Expect(name).To(Equal("sample-def"))
+
+		name, apierr = selectInstance(instances, "sample-worker-abc")
+		Expect(apierr).To(BeNil())
+		Expect(name).To(Equal("sample-worker-abc"))
+	})
+
+	It("chooses an instance by index", func() {
+		name, apierr := selectInstance(instances, "0")
+		Expect(apierr).To(BeNil())
+		Expect(name).To(Equal("sample-worker-abc"))
+
+		name, apierr = selectInstance(instances, "2")
+		Expect(apierr).To(BeNil())
+		Expect(name).To(Equal("sample-ghi"))
+	})
+
+	It("fails for an unknown instance", func() {
+		for _, choice := range []string{"sample-xyz", "3", "-1"} {
+			_, apierr := selectInstance(instances, choice)
+			Expect(apierr).ToNot(BeNil(), choice)
+			Expect(apierr.FirstStatus()).To(Equal(http.StatusBadRequest))
+		}
+	})
+})
+
+var _ = Describe("relayExec", func() {
+	var (
+		server   *httptest.Server
+		returned chan error
+		client   *websocket.Conn
+	)
+
+	BeforeEach(func() {
+		returned = make(chan error, 1)
+		upgrader := websocket.Upgrader{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				returned <- err
+				return
+			}
+			// The command ends at once, without reading stdin
+			returned <- relayExec(conn, func(stdin io.Reader, stdout, stderr io.Writer, resize remotecommand.TerminalSizeQueue) error {
+				time.Sleep(100 * time.Millisecond)
+				return nil
+			})
+		}))
+
+		var err error
+		client, _, err = websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		server.Close()
+	})
+
+	It("ends when the command exits while the client still sends stdin", func() {
+		go func() {
+			defer GinkgoRecover()
+			for {
+				err := client.WriteMessage(websocket.BinaryMessage, []byte{models.ExecStdin, 'x'})
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		Eventually(returned, "5s").Should(Receive(BeNil()))
+	})
+})
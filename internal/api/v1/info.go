@@ -4,17 +4,19 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/epinio/epinio/internal/version"
+	"github.com/spf13/viper"
 )
 
 type InfoController struct {
 }
 
 func (hc InfoController) Info(w http.ResponseWriter, r *http.Request) APIErrors {
-	info := struct {
-		Version string
-	}{
-		Version: version.Version,
+	info := models.InfoResponse{
+		Version:       version.Version,
+		BuilderImages: builderImages(),
 	}
 	js, err := json.Marshal(info)
 	if err != nil {
@@ -28,3 +30,20 @@ func (hc InfoController) Info(w http.ResponseWriter, r *http.Request) APIErrors
 
 	return nil
 }
+
+// builderImages returns the builder images offered for staging, as
+// chosen at installation. The default builder comes first.
+func builderImages() []string {
+	return deployments.BuilderImageList(viper.GetString("builder-image"), viper.GetString("additional-builder-images"))
+}
+
+// offeredBuilder returns true if the builder image is offered for
+// staging
+func offeredBuilder(image string) bool {
+	for _, offered := range builderImages() {
+		if image == offered {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+)
+
+// Instances returns the instances of the application, with their
+// state, restarts, and recent events. An application without
+// workload has no instances.
+func (hc ApplicationsController) Instances(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+	if apierr := checkApp(ctx, cluster, appRef); apierr != nil {
+		return apierr
+	}
+
+	instances, err := application.Instances(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, instances)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
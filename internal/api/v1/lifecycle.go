@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+)
+
+// Restart replaces the instances of the application one by one,
+// keeping the application available.
+func (hc ApplicationsController) Restart(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+	if apierr := checkWorkload(ctx, cluster, appRef); apierr != nil {
+		return apierr
+	}
+
+	stopped, err := application.Stopped(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	if stopped {
+		return NewBadRequest("Unable to restart stopped application, start it instead")
+	}
+
+	log.Info("restarting app", "org", org, "app", appName)
+
+	err = application.RefreshServicesJSON(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = application.Restart(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Stop removes all instances of the application and its workers,
+// remembering how many there were.
+func (hc ApplicationsController) Stop(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+	if apierr := checkWorkload(ctx, cluster, appRef); apierr != nil {
+		return apierr
+	}
+
+	stopped, err := application.Stopped(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	if stopped {
+		return NewBadRequest("Application is already stopped")
+	}
+
+	log.Info("stopping app", "org", org, "app", appName)
+
+	err = application.Stop(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Start brings a stopped application and its workers back to the
+// instances they had before the stop.
+func (hc ApplicationsController) Start(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+	if apierr := checkWorkload(ctx, cluster, appRef); apierr != nil {
+		return apierr
+	}
+
+	stopped, err := application.Stopped(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !stopped {
+		return NewBadRequest("Application is not stopped")
+	}
+
+	log.Info("starting app", "org", org, "app", appName)
+
+	err = application.Start(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// checkWorkload verifies that org and application exist, and that the
+// application has a workload
+func checkWorkload(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) APIErrors {
+	if apierr := checkApp(ctx, cluster, appRef); apierr != nil {
+		return apierr
+	}
+
+	workload, err := application.Lookup(ctx, cluster, appRef.Org, appRef.Name)
+	if err != nil {
+		return InternalError(err)
+	}
+	if workload == nil {
+		return NewBadRequest("Unable to change application without workload")
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/julienschmidt/httprouter"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Limits returns the resource defaults and maximums of the org
+func (oc OrganizationsController) Limits(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	limits, err := organizations.Limits(ctx, cluster.Kubectl, org)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, limits)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// LimitsSet replaces the resource defaults and maximums of the org.
+// They apply to instances created afterwards.
+func (oc OrganizationsController) LimitsSet(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var limits models.OrgLimits
+	if err := json.Unmarshal(bodyBytes, &limits); err != nil {
+		return BadRequest(err)
+	}
+
+	if apierr := checkLimit("memory", limits.DefaultMemory, limits.MaxMemory); apierr != nil {
+		return apierr
+	}
+	if apierr := checkLimit("cpu", limits.DefaultCPU, limits.MaxCPU); apierr != nil {
+		return apierr
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	log.Info("setting limits", "org", org, "limits", limits)
+
+	err = organizations.SetLimits(ctx, cluster.Kubectl, org, limits)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// checkLimit validates default and maximum of the named resource. The
+// default must not exceed the maximum.
+func checkLimit(name, def, max string) APIErrors {
+	if apierr := checkResource("default "+name, def, ""); apierr != nil {
+		return apierr
+	}
+	if apierr := checkResource("maximum "+name, max, ""); apierr != nil {
+		return apierr
+	}
+	if def == "" || max == "" {
+		return nil
+	}
+
+	defQ, maxQ := resource.MustParse(def), resource.MustParse(max)
+	if defQ.Cmp(maxQ) > 0 {
+		return NewBadRequest(fmt.Sprintf("default %s %s exceeds the maximum %s", name, def, max))
+	}
+
+	return nil
+}
+
+// checkDeployedResources validates the resources of the deployed
+// application against the maximums of its organization. Resources
+// beyond them have to be lowered with an update of the application
+// before it can be deployed again.
+func checkDeployedResources(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) APIErrors {
+	limits, err := organizations.Limits(ctx, cluster.Kubectl, appRef.Org)
+	if err != nil {
+		return InternalError(err)
+	}
+	memory, cpu, err := application.DeployedResources(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if apierr := checkResource("memory", memory, limits.MaxMemory); apierr != nil {
+		return apierr
+	}
+	if apierr := checkResource("cpu", cpu, limits.MaxCPU); apierr != nil {
+		return apierr
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limits", func() {
+	// status returns the status of the errors, 0 without errors
+	status := func(apierr APIErrors) int {
+		if apierr == nil {
+			return 0
+		}
+		return apierr.FirstStatus()
+	}
+
+	table.DescribeTable("checkResource",
+		func(quantity, max string, expected int) {
+			Expect(status(checkResource("memory", quantity, max))).To(Equal(expected))
+		},
+		table.Entry("accepts no quantity", "", "1Gi", 0),
+		table.Entry("accepts a quantity without maximum", "2Gi", "", 0),
+		table.Entry("accepts a quantity below the maximum", "512Mi", "1Gi", 0),
+		table.Entry("accepts a quantity at the maximum", "1024Mi", "1Gi", 0),
+		table.Entry("rejects a quantity beyond the maximum", "2Gi", "1Gi", http.StatusBadRequest),
+		table.Entry("rejects a bad quantity", "lots", "", http.StatusBadRequest),
+		table.Entry("rejects a zero quantity", "0", "", http.StatusBadRequest),
+		table.Entry("rejects a negative quantity", "-1Gi", "", http.StatusBadRequest),
+	)
+
+	table.DescribeTable("checkLimit",
+		func(def, max string, expected int) {
+			Expect(status(checkLimit("cpu", def, max))).To(Equal(expected))
+		},
+		table.Entry("accepts no limits", "", "", 0),
+		table.Entry("accepts a default alone", "500m", "", 0),
+		table.Entry("accepts a maximum alone", "", "2", 0),
+		table.Entry("accepts a default below the maximum", "500m", "2", 0),
+		table.Entry("accepts a default at the maximum", "2000m", "2", 0),
+		table.Entry("rejects a default beyond the maximum", "3", "2", http.StatusBadRequest),
+		table.Entry("rejects a bad default", "fast", "2", http.StatusBadRequest),
+		table.Entry("rejects a bad maximum", "500m", "fast", http.StatusBadRequest),
+		table.Entry("rejects a zero maximum", "", "0", http.StatusBadRequest),
+	)
+})
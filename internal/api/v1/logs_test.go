@@ -0,0 +1,62 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("logOptions", func() {
+	It("returns the defaults without parameters", func() {
+		options, apierr := logOptions(url.Values{})
+		Expect(apierr).To(BeNil())
+		Expect(options.Since).To(BeZero())
+		Expect(options.Tail).To(BeNil())
+		Expect(options.Timestamps).To(BeFalse())
+		Expect(options.Include).To(BeEmpty())
+		Expect(options.Exclude).To(BeEmpty())
+		Expect(options.Instance).To(BeEmpty())
+	})
+
+	It("returns the options of the parameters", func() {
+		options, apierr := logOptions(url.Values{
+			"since":      {"1h30m"},
+			"tail":       {"20"},
+			"timestamps": {"true"},
+			"include":    {"GET", "POST"},
+			"exclude":    {"health"},
+			"instance":   {"1"},
+		})
+		Expect(apierr).To(BeNil())
+		Expect(options.Since).To(Equal(90 * time.Minute))
+		Expect(*options.Tail).To(Equal(int64(20)))
+		Expect(options.Timestamps).To(BeTrue())
+		Expect(options.Include).To(Equal([]string{"GET", "POST"}))
+		Expect(options.Exclude).To(Equal([]string{"health"}))
+		Expect(options.Instance).To(Equal("1"))
+	})
+
+	It("accepts a tail of zero", func() {
+		options, apierr := logOptions(url.Values{"tail": {"0"}})
+		Expect(apierr).To(BeNil())
+		Expect(*options.Tail).To(BeZero())
+	})
+
+	table.DescribeTable("rejects bad parameters",
+		func(name, value string) {
+			_, apierr := logOptions(url.Values{name: {value}})
+			Expect(apierr).ToNot(BeNil())
+			Expect(apierr.FirstStatus()).To(Equal(http.StatusBadRequest))
+		},
+		table.Entry("a since without unit", "since", "10"),
+		table.Entry("a negative since", "since", "-1h"),
+		table.Entry("a tail which is no number", "tail", "ten"),
+		table.Entry("a negative tail", "tail", "-1"),
+		table.Entry("a bad include", "include", "(GET"),
+		table.Entry("a bad exclude", "exclude", "[a-"),
+	)
+})
@@ -0,0 +1,47 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+)
+
+// Metrics returns the cpu and memory usage of the instances of the
+// application, and, where linkerd meshes them, their traffic. Without
+// a metrics API in the cluster the usage is missing. The query
+// parameter traffic=false leaves the traffic out, for the usage alone.
+func (hc ApplicationsController) Metrics(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+	if apierr := checkApp(ctx, cluster, appRef); apierr != nil {
+		return apierr
+	}
+
+	traffic := r.URL.Query().Get("traffic") != "false"
+
+	metrics, err := application.Metrics(ctx, cluster, appRef, traffic)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, metrics)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
@@ -2,18 +2,38 @@ package models
 
 const (
 	EpinioStageIDLabel = "epinio.suse.org/stage-id"
+	EpinioProcessLabel = "epinio.suse.org/process"
 )
 
 // App has all the app properties, like the routes and stage ID.
 // It is used in the CLI and  API responses.
 type App struct {
-	Active        bool     `json:"active,omitempty"`
-	StageID       string   `json:"stage_id,omitempty"`
-	Name          string   `json:"name,omitempty"`
-	Organization  string   `json:"organization,omitempty"`
-	Status        string   `json:"status,omitempty"`
-	Routes        []string `json:"routes,omitempty"`
-	BoundServices []string `json:"bound_services,omitempty"`
+	Active        bool         `json:"active,omitempty"`
+	StageID       string       `json:"stage_id,omitempty"`
+	Name          string       `json:"name,omitempty"`
+	Organization  string       `json:"organization,omitempty"`
+	Status        string       `json:"status,omitempty"`
+	Instances     int32        `json:"instances,omitempty"`
+	Routes        []string     `json:"routes,omitempty"`
+	BoundServices []string     `json:"bound_services,omitempty"`
+	Staging       string       `json:"staging,omitempty"`
+	Port          int32        `json:"port,omitempty"`
+	Workers       []string     `json:"workers,omitempty"`
+	HealthChecks  HealthChecks `json:"health_checks,omitempty"`
+	Memory        string       `json:"memory,omitempty"`
+	CPU           string       `json:"cpu,omitempty"`
+	Autoscaling   *Autoscaling `json:"autoscaling,omitempty"`
+	Stopped       bool         `json:"stopped,omitempty"`
+}
+
+// Autoscaling describes the horizontal autoscaling of an application,
+// and the instances the autoscaler currently sees and wants.
+type Autoscaling struct {
+	Min             int32 `json:"min"`
+	Max             int32 `json:"max"`
+	CPUPercent      int32 `json:"cpu_percent"`
+	CurrentReplicas int32 `json:"current_replicas"`
+	DesiredReplicas int32 `json:"desired_replicas"`
 }
 
 // NewApp returns a new app for name and org
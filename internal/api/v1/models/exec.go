@@ -0,0 +1,31 @@
+package models
+
+// This subsection of models provides structures related to running
+// commands in the instances of applications.
+
+// Streams of the exec websocket. Every binary message starts with the
+// byte of its stream, followed by the data. The client sends stdin and
+// resize messages, the server stdout, stderr and, at the end, a single
+// result message.
+const (
+	ExecStdin  byte = 0
+	ExecStdout byte = 1
+	ExecStderr byte = 2
+	ExecResult byte = 3
+	ExecResize byte = 4
+)
+
+// TerminalSize is the data of a resize message, the size of the
+// terminal of the client in characters.
+type TerminalSize struct {
+	Width  uint16 `json:"width"`
+	Height uint16 `json:"height"`
+}
+
+// ExecResponse is the data of the result message. Error is set when the
+// command could not be run, or failed with an exit code not reported
+// in ExitCode.
+type ExecResponse struct {
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
@@ -0,0 +1,75 @@
+package models
+
+// This subsection of models provides structures related to the health
+// checks of applications.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of health checks. A check of kind none removes the check from
+// the application.
+const (
+	HealthCheckHTTP = "http"
+	HealthCheckTCP  = "tcp"
+	HealthCheckExec = "exec"
+	HealthCheckNone = "none"
+)
+
+// HealthChecks holds the checks of an application. The liveness check
+// restarts an instance failing it, the readiness check takes it out of
+// the routing until it passes again. In a deploy request a missing
+// check keeps the check of the deployed application.
+type HealthChecks struct {
+	Liveness  *HealthCheck `json:"liveness,omitempty"`
+	Readiness *HealthCheck `json:"readiness,omitempty"`
+}
+
+// HealthCheck describes a single check. Path is the endpoint of an
+// http check, Command the program run by an exec check. Port defaults
+// to the port of the application. The times are in seconds. Zero
+// values use the kubernetes defaults.
+type HealthCheck struct {
+	Type             string   `json:"type"`
+	Path             string   `json:"path,omitempty"`
+	Port             int32    `json:"port,omitempty"`
+	Command          []string `json:"command,omitempty"`
+	InitialDelay     int32    `json:"initial_delay,omitempty"`
+	Period           int32    `json:"period,omitempty"`
+	Timeout          int32    `json:"timeout,omitempty"`
+	FailureThreshold int32    `json:"failure_threshold,omitempty"`
+}
+
+// String returns a human readable description of the check
+func (hc HealthCheck) String() string {
+	var target string
+	switch hc.Type {
+	case HealthCheckHTTP:
+		target = hc.Path
+	case HealthCheckExec:
+		target = strings.Join(hc.Command, " ")
+	}
+	if hc.Port != 0 {
+		target = fmt.Sprintf(":%d%s", hc.Port, target)
+	}
+
+	desc := []string{hc.Type}
+	if target != "" {
+		desc = append(desc, target)
+	}
+	if hc.InitialDelay != 0 {
+		desc = append(desc, fmt.Sprintf("delay=%ds", hc.InitialDelay))
+	}
+	if hc.Period != 0 {
+		desc = append(desc, fmt.Sprintf("period=%ds", hc.Period))
+	}
+	if hc.Timeout != 0 {
+		desc = append(desc, fmt.Sprintf("timeout=%ds", hc.Timeout))
+	}
+	if hc.FailureThreshold != 0 {
+		desc = append(desc, fmt.Sprintf("failures=%d", hc.FailureThreshold))
+	}
+
+	return strings.Join(desc, " ")
+}
@@ -0,0 +1,45 @@
+package models
+
+// This subsection of models provides structures related to the
+// instances, i.e. pods, of applications.
+
+import "time"
+
+// AppInstance describes a single instance of an application. Worker
+// is the non-web process the instance runs, and empty for instances
+// serving the web traffic. Reason tells why the container is currently
+// not running, like CrashLoopBackOff or ImagePullBackOff, and
+// LastTermination why it stopped last, like OOMKilled or Error.
+type AppInstance struct {
+	Name            string    `json:"name"`
+	Worker          string    `json:"worker,omitempty"`
+	Phase           string    `json:"phase"`
+	Ready           bool      `json:"ready"`
+	Restarts        int32     `json:"restarts"`
+	Reason          string    `json:"reason,omitempty"`
+	LastTermination string    `json:"last_termination,omitempty"`
+	Node            string    `json:"node,omitempty"`
+	Created         time.Time `json:"created"`
+	Events          []string  `json:"events,omitempty"`
+}
+
+// List Response
+type AppInstanceList []AppInstance
+
+// Implement the Sort interface for instance slices. The instances of
+// the web process sort first, then those of the workers, oldest first.
+
+func (il AppInstanceList) Len() int {
+	return len(il)
+}
+
+func (il AppInstanceList) Swap(i, j int) {
+	il[i], il[j] = il[j], il[i]
+}
+
+func (il AppInstanceList) Less(i, j int) bool {
+	if il[i].Worker != il[j].Worker {
+		return il[i].Worker < il[j].Worker
+	}
+	return il[i].Created.Before(il[j].Created)
+}
@@ -0,0 +1,53 @@
+package models
+
+// This subsection of models provides structures related to the logs
+// of applications.
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// LogOptions select the log lines sent by the logs endpoints, where
+// they are query parameters of the same names, in lower case. Since
+// reaches into the past, by default as far as the log history of the
+// server. Tail limits the lines taken from the past of each container.
+// Include keeps only lines matching one of its regular expressions,
+// Exclude drops the lines matching one of its. Instance limits the logs
+// of an application to one of its instances, by name or index, as for
+// exec.
+type LogOptions struct {
+	Since      time.Duration
+	Tail       *int64
+	Timestamps bool
+	Include    []string
+	Exclude    []string
+	Instance   string
+}
+
+// Filters compiles the regular expressions of Include and Exclude. It
+// fails for the first bad expression.
+func (o LogOptions) Filters() ([]*regexp.Regexp, []*regexp.Regexp, error) {
+	include, err := compileAll(o.Include)
+	if err != nil {
+		return nil, nil, err
+	}
+	exclude, err := compileAll(o.Exclude)
+	if err != nil {
+		return nil, nil, err
+	}
+	return include, exclude, nil
+}
+
+func compileAll(expressions []string) ([]*regexp.Regexp, error) {
+	result := []*regexp.Regexp{}
+	for _, expression := range expressions {
+		rex, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("bad regular expression '%s': %s", expression, err.Error())
+		}
+		result = append(result, rex)
+	}
+	return result, nil
+}
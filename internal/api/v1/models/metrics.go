@@ -0,0 +1,59 @@
+package models
+
+// This subsection of models provides structures related to the
+// resource usage and traffic of applications.
+
+// InstanceMetrics describes the resource usage and the traffic of a
+// single instance of an application. The usage is missing when the
+// cluster has no metrics API, the requests when the instance has no
+// linkerd proxy.
+type InstanceMetrics struct {
+	Name     string          `json:"name"`
+	Worker   string          `json:"worker,omitempty"`
+	Usage    *ResourceUsage  `json:"usage,omitempty"`
+	Requests *RequestMetrics `json:"requests,omitempty"`
+}
+
+// ResourceUsage is the current cpu, in millicores, and memory, in
+// bytes, used by an instance, as reported by the metrics API.
+type ResourceUsage struct {
+	CPU    int64 `json:"cpu"`
+	Memory int64 `json:"memory"`
+}
+
+// RequestMetrics describes the requests served by an instance, as
+// counted by its linkerd proxy. The total is counted since the instance
+// started. The others are taken over a few seconds, the current rate per
+// second, the fraction of successful responses, and the percentiles of
+// the latencies, in milliseconds.
+type RequestMetrics struct {
+	Total       float64 `json:"total"`
+	Rate        float64 `json:"rate"`
+	SuccessRate float64 `json:"success_rate"`
+	LatencyP50  float64 `json:"latency_p50"`
+	LatencyP95  float64 `json:"latency_p95"`
+	LatencyP99  float64 `json:"latency_p99"`
+}
+
+// List Response, in the order of the AppInstanceList
+type AppMetrics []InstanceMetrics
+
+// HasUsage returns true if the usage of any instance is known
+func (m AppMetrics) HasUsage() bool {
+	for _, instance := range m {
+		if instance.Usage != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRequests returns true if the requests of any instance are known
+func (m AppMetrics) HasRequests() bool {
+	for _, instance := range m {
+		if instance.Requests != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -32,18 +32,64 @@ type DeleteResponse struct {
 
 type BindRequest struct {
 	Names []string `json:"names"`
+	BindOptions
+}
+
+// BindOptions selects the ways a binding is passed into the application,
+// besides the files mounted at /services/NAME. Env passes each key of the
+// binding as an environment variable, prefixed with the service name.
+// JSON adds the binding to the JSON object of all the bindings, keyed by
+// service name, in the environment variable EPINIO_SERVICES.
+type BindOptions struct {
+	Env  bool `json:"env,omitempty"`
+	JSON bool `json:"json,omitempty"`
 }
 
 type BindResponse struct {
 	WasBound []string `json:"wasbound"`
 }
 
+// InfoResponse describes the Epinio server
+type InfoResponse struct {
+	Version string `json:"version"`
+	// The buildpacks builder images offered for staging, the default first
+	BuilderImages []string `json:"builder_images,omitempty"`
+}
+
 type ApplicationCreateRequest struct {
 	Name string `json:"name"`
 }
 
+// UpdateAppRequest changes the instances and the resources of an
+// application. Fields not set are not changed. Memory and CPU are the
+// limits of each instance, as kubernetes quantities like 256Mi and
+// 500m.
 type UpdateAppRequest struct {
-	Instances int32 `json:"instances"`
+	Instances *int32 `json:"instances,omitempty"`
+	Memory    string `json:"memory,omitempty"`
+	CPU       string `json:"cpu,omitempty"`
+}
+
+// AutoscaleRequest turns on the horizontal autoscaling of an
+// application, between Min and Max instances. The instances are scaled
+// to keep their cpu usage at CPUPercent of the requested cpu. A missing
+// Min, and a zero CPUPercent, use the defaults, 1 instance at least,
+// and 80 percent.
+type AutoscaleRequest struct {
+	Min        *int32 `json:"min,omitempty"`
+	Max        int32  `json:"max"`
+	CPUPercent int32  `json:"cpu_percent,omitempty"`
+}
+
+// OrgLimits are the resources given to the instances of applications
+// in an organization which do not set their own, and the maximum
+// resources an instance may have. Values are kubernetes quantities.
+// Empty values are not set.
+type OrgLimits struct {
+	DefaultMemory string `json:"default_memory,omitempty"`
+	DefaultCPU    string `json:"default_cpu,omitempty"`
+	MaxMemory     string `json:"max_memory,omitempty"`
+	MaxCPU        string `json:"max_cpu,omitempty"`
 }
 
 // TODO: CreateOrgRequest
@@ -54,15 +100,82 @@ type UploadResponse struct {
 	Git *GitRef `json:"git,omitempty"`
 }
 
+// Build strategies for the staging of sources. The default strategy
+// is buildpacks.
+const (
+	BuildStrategyBuildpacks = "buildpacks"
+	BuildStrategyDockerfile = "dockerfile"
+)
+
+// DefaultProcessType is the process type of the buildpacks image which
+// serves the web traffic of the application
+const DefaultProcessType = "web"
+
+// StageRequest references the sources to stage. With a ContainerImage
+// the staging is skipped, and the image is deployed as is. BuilderImage
+// and Buildpacks override the default builder, and its detection of
+// buildpacks, for the buildpacks strategy. ProcessType selects the
+// process the image runs by default, for the same strategy.
+//
+// The server deploys the staged image with the DeploySettings, unless
+// the client deploys it itself shortly after the staging completed.
+// Route is deprecated, in favour of Routes.
 type StageRequest struct {
-	App       AppRef  `json:"app,omitempty"`
-	Instances *int32  `json:"instances,omitempty"`
-	Git       *GitRef `json:"git,omitempty"`
-	Route     string  `json:"route,omitempty"`
+	App              AppRef          `json:"app,omitempty"`
+	Git              *GitRef         `json:"git,omitempty"`
+	ContainerImage   string          `json:"container_image,omitempty"`
+	BuildStrategy    string          `json:"build_strategy,omitempty"`
+	BuilderImage     string          `json:"builder_image,omitempty"`
+	Buildpacks       []string        `json:"buildpacks,omitempty"`
+	ProcessType      string          `json:"process_type,omitempty"`
+	BuildEnvironment EnvVariableList `json:"build_environment,omitempty"`
+	Route            string          `json:"route,omitempty"`
+	DeploySettings
 }
 
 type StageResponse struct {
 	Stage StageRef `json:"stage,omitempty"`
+	Image ImageRef `json:"image,omitempty"`
+}
+
+// DeployRequest references the image to deploy. The image of a
+// staging run is known to the server, and can be left out.
+type DeployRequest struct {
+	App   AppRef   `json:"app,omitempty"`
+	Stage StageRef `json:"stage,omitempty"`
+	Image ImageRef `json:"image,omitempty"`
+	DeploySettings
+}
+
+// DeploySettings are the settings of a deployment. Without Routes the
+// application keeps its routes. Port is the port the application
+// listens on, the default port when not set. Processes are additional,
+// non-web processes run from the same image. HealthChecks replace the
+// checks of the application.
+type DeploySettings struct {
+	Instances    *int32       `json:"instances,omitempty"`
+	Routes       []string     `json:"routes,omitempty"`
+	Port         int32        `json:"port,omitempty"`
+	Processes    []Process    `json:"processes,omitempty"`
+	HealthChecks HealthChecks `json:"health_checks,omitempty"`
+}
+
+// Process is a non-web process of an application, like a worker. It
+// runs without service and route. Without a command the process of
+// the same name of a buildpacks image is run. Instances defaults to 1.
+type Process struct {
+	Name      string   `json:"name"`
+	Command   []string `json:"command,omitempty"`
+	Instances *int32   `json:"instances,omitempty"`
+}
+
+type DeployResponse struct {
+	Routes []string `json:"routes,omitempty"`
+}
+
+// RouteRequest names a route to add to an application
+type RouteRequest struct {
+	Route string `json:"route"`
 }
 
 type ApplicationDeleteResponse struct {
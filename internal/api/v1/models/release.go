@@ -0,0 +1,57 @@
+package models
+
+// This subsection of models provides structures related to the
+// release history of applications.
+
+import "time"
+
+// Release records a deployment of an application, i.e. the image and
+// environment of a staging run, and who pushed it. BuilderImage is the
+// builder chosen for the staging, empty for the default builder.
+// Buildpacks and ProcessType are those given to the staging, empty for
+// the detected buildpacks and the default process type. A
+// rollback is a release of its own, made by the user rolling back,
+// with RollbackOf the ID of the release it deployed again.
+type Release struct {
+	ID           string          `json:"id"`
+	Git          *GitRef         `json:"git,omitempty"`
+	Image        string          `json:"image"`
+	BuilderImage string          `json:"builder_image,omitempty"`
+	Buildpacks   []string        `json:"buildpacks,omitempty"`
+	ProcessType  string          `json:"process_type,omitempty"`
+	Environment  EnvVariableList `json:"environment,omitempty"`
+	Port         int32           `json:"port,omitempty"`
+	Processes    []Process       `json:"processes,omitempty"`
+	Created      time.Time       `json:"created"`
+	User         string          `json:"user,omitempty"`
+	RollbackOf   string          `json:"rollback_of,omitempty"`
+	Active       bool            `json:"active,omitempty"`
+}
+
+// List Response
+type ReleaseList []Release
+
+// Implement the Sort interface for release slices. The newest release
+// sorts first.
+
+func (rl ReleaseList) Len() int {
+	return len(rl)
+}
+
+func (rl ReleaseList) Swap(i, j int) {
+	rl[i], rl[j] = rl[j], rl[i]
+}
+
+func (rl ReleaseList) Less(i, j int) bool {
+	return rl[i].Created.After(rl[j].Created)
+}
+
+// Rollback Request. An empty release references the release
+// deployed before the active one.
+type RollbackRequest struct {
+	Release string `json:"release,omitempty"`
+}
+
+type RollbackResponse struct {
+	Release Release `json:"release"`
+}
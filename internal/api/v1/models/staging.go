@@ -0,0 +1,44 @@
+package models
+
+// This subsection of models provides structures related to the
+// progress of staging runs.
+
+import "time"
+
+// States of a staging run, and of its tasks
+const (
+	StagingPending   = "pending"
+	StagingRunning   = "running"
+	StagingSucceeded = "succeeded"
+	StagingFailed    = "failed"
+	StagingCancelled = "cancelled"
+)
+
+// StagingStatus describes the progress of a staging run. Reason and
+// Message explain a failure.
+type StagingStatus struct {
+	ID             string              `json:"id"`
+	App            AppRef              `json:"app"`
+	State          string              `json:"state"`
+	Reason         string              `json:"reason,omitempty"`
+	Message        string              `json:"message,omitempty"`
+	StartTime      *time.Time          `json:"start_time,omitempty"`
+	CompletionTime *time.Time          `json:"completion_time,omitempty"`
+	Tasks          []StagingTaskStatus `json:"tasks,omitempty"`
+}
+
+// StagingTaskStatus describes the progress of a single task of a
+// staging run, i.e. clone, stage, or clean.
+type StagingTaskStatus struct {
+	Name           string     `json:"name"`
+	State          string     `json:"state"`
+	Reason         string     `json:"reason,omitempty"`
+	Message        string     `json:"message,omitempty"`
+	StartTime      *time.Time `json:"start_time,omitempty"`
+	CompletionTime *time.Time `json:"completion_time,omitempty"`
+}
+
+// Done returns true if the staging run has ended, for whatever reason
+func (s StagingStatus) Done() bool {
+	return s.State == StagingSucceeded || s.State == StagingFailed || s.State == StagingCancelled
+}
@@ -0,0 +1,89 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/kubernetes/tailer"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/organizations"
+)
+
+// OrgLogs streams the logs of the applications of the organization, of
+// their workers, and of their stagings, to the websocket of the client,
+// as Logs does. The query parameter app, repeated, limits them to the
+// named applications. The other parameters are those of Logs, except
+// for the instance.
+func (hc ApplicationsController) OrgLogs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+
+	queryValues := r.URL.Query()
+	follow := queryValues.Get("follow") == "true"
+	apps := queryValues["app"]
+
+	options, apierr := logOptions(queryValues)
+	if apierr != nil {
+		jsonErrorResponse(w, apierr)
+		return
+	}
+	if options.Instance != "" {
+		jsonErrorResponse(w, NewBadRequest("instance param is only supported for the logs of applications"))
+		return
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+	if !exists {
+		jsonErrorResponse(w, OrgIsNotKnown(org))
+		return
+	}
+
+	for _, appName := range apps {
+		exists, err := application.Exists(ctx, cluster, models.NewAppRef(appName, org))
+		if err != nil {
+			jsonErrorResponse(w, InternalError(err))
+			return
+		}
+		if !exists {
+			jsonErrorResponse(w, AppIsNotKnown(appName))
+			return
+		}
+	}
+
+	var upgrader = websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+
+	log.Info("streaming org logs", "org", org, "apps", apps, "follow", follow)
+
+	hc.conn = conn
+	err = hc.streamPodLogs(ctx, func(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.WaitGroup) error {
+		return application.OrgLogs(ctx, logChan, wg, cluster, follow, org, apps, options)
+	})
+	if err != nil {
+		log.V(1).Error(err, "error occured after upgrading the websockets connection")
+	}
+}
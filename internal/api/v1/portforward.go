@@ -0,0 +1,145 @@
+package v1
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+)
+
+// PortForward connects the websocket of the client to a port of an
+// instance of the application. The binary messages of the connection
+// carry the data in both directions, an empty message from the client
+// ends its data. Each connection forwards a single tcp connection. The
+// query parameters select the port, and the instance, as for Exec.
+func (hc ApplicationsController) PortForward(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	queryValues := r.URL.Query()
+	port, err := strconv.Atoi(queryValues.Get("port"))
+	if err != nil || port < 1 || port > 65535 {
+		jsonErrorResponse(w, NewBadRequest("port param should be integer between 1 and 65535"))
+		return
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+
+	appRef := models.NewAppRef(appName, org)
+	if apierr := checkApp(ctx, cluster, appRef); apierr != nil {
+		jsonErrorResponse(w, apierr)
+		return
+	}
+
+	instances, err := application.PodInstances(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+	instance, apierr := selectInstance(instances, queryValues.Get("instance"))
+	if apierr != nil {
+		jsonErrorResponse(w, apierr)
+		return
+	}
+
+	var upgrader = websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+
+	log.Info("port forward to app", "org", org, "app", appName, "instance", instance, "port", port)
+
+	err = runPortForward(cluster, conn, org, instance, port)
+	if err != nil {
+		log.V(1).Error(err, "error occured after upgrading the websockets connection")
+	}
+}
+
+// runPortForward relays between the websocket connection and the port
+// of the pod, until either side closes. The connection is closed at
+// the end, with the error of the forwarding, if any.
+func runPortForward(cluster *kubernetes.Cluster, conn *websocket.Conn, org, pod string, port int) error {
+	return relayPortForward(conn, func(in io.Reader, out io.Writer) error {
+		return cluster.PortForward(org, pod, port, in, out)
+	})
+}
+
+// relayPortForward relays between the websocket connection and the
+// forwarding, until either side closes, as runPortForward does. The
+// relay of the client's messages is ended on return.
+func relayPortForward(conn *websocket.Conn, forward func(in io.Reader, out io.Writer) error) error {
+	in, inWriter := io.Pipe()
+
+	var relay sync.WaitGroup
+	relay.Add(1)
+	defer relay.Wait()
+
+	go func() {
+		defer relay.Done()
+		defer inWriter.Close()
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if len(message) == 0 {
+				inWriter.Close()
+				continue
+			}
+			if _, err := inWriter.Write(message); err != nil {
+				return
+			}
+		}
+	}()
+
+	forwardErr := forward(in, wsWriter{conn: conn})
+
+	// Nothing reads the input anymore. A write of the relay pending,
+	// or to come, fails.
+	in.Close()
+
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if forwardErr != nil {
+		closeMessage = websocket.FormatCloseMessage(websocket.CloseInternalServerErr, forwardErr.Error())
+	}
+	if err := conn.WriteControl(websocket.CloseMessage, closeMessage, time.Time{}); err != nil {
+		conn.Close()
+		return err
+	}
+	if err := conn.Close(); err != nil {
+		return err
+	}
+
+	return forwardErr
+}
+
+// wsWriter sends everything written to it as binary messages
+type wsWriter struct {
+	conn *websocket.Conn
+}
+
+func (w wsWriter) Write(p []byte) (int, error) {
+	if err := w.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
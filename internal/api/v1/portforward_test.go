@@ -0,0 +1,62 @@
+package v1
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gorilla/websocket"
+)
+
+var _ = Describe("relayPortForward", func() {
+	var (
+		server   *httptest.Server
+		returned chan error
+		client   *websocket.Conn
+	)
+
+	BeforeEach(func() {
+		returned = make(chan error, 1)
+		upgrader := websocket.Upgrader{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				returned <- err
+				return
+			}
+			// The forwarding ends at once, without reading the input
+			returned <- relayPortForward(conn, func(in io.Reader, out io.Writer) error {
+				time.Sleep(100 * time.Millisecond)
+				return nil
+			})
+		}))
+
+		var err error
+		client, _, err = websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		server.Close()
+	})
+
+	It("ends when the forwarding ends while the client still sends data", func() {
+		go func() {
+			defer GinkgoRecover()
+			for {
+				err := client.WriteMessage(websocket.BinaryMessage, []byte("data"))
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		Eventually(returned, "5s").Should(Receive(BeNil()))
+	})
+})
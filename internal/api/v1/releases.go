@@ -0,0 +1,248 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/randstr"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/organizations"
+)
+
+// Releases returns the release history of the application, newest
+// first. The release currently deployed is marked active.
+func (hc ApplicationsController) Releases(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+
+	exists, err = application.Exists(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return AppIsNotKnown(appName)
+	}
+
+	releases, err := application.Releases(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	active, err := activeRelease(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	for i := range releases {
+		releases[i].Active = releases[i].ID == active
+	}
+
+	err = jsonResponse(w, releases)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Rollback deploys the image of a past release of the application
+// again, without staging. The current instances, routes and health
+// checks of the application are kept. Environment, port and processes
+// are those of the release, and the environment and builder image of
+// the application are restored to those of the release. The rollback
+// is recorded as a new release, see rollbackRelease.
+func (hc ApplicationsController) Rollback(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var req models.RollbackRequest
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		return BadRequest(err)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+
+	app, err := application.Get(ctx, cluster, appRef)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return AppIsNotKnown(appName)
+		}
+		return InternalError(err)
+	}
+
+	releases, err := application.Releases(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	active, err := activeRelease(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	target, apierr := rollbackTarget(releases, active, req.Release)
+	if apierr != nil {
+		return apierr
+	}
+
+	user, _, _ := r.BasicAuth()
+	release, err := rollbackRelease(target, user, time.Now().UTC())
+	if err != nil {
+		return InternalError(err, "failed to generate a uid")
+	}
+
+	owner := metav1.OwnerReference{
+		APIVersion: app.GetAPIVersion(),
+		Kind:       app.GetKind(),
+		Name:       app.GetName(),
+		UID:        app.GetUID(),
+	}
+
+	log.Info("rolling back app", "org", org, "app", appName, "release", target.ID)
+
+	err = application.EnvironmentRestore(ctx, cluster, appRef, release.Environment)
+	if err != nil {
+		return InternalError(err, "failed to restore the application runtime environment")
+	}
+
+	// The stagings after the rollback use the builder of the release
+	if release.Git != nil {
+		err = application.SetBuilderImage(ctx, cluster, appRef, release.BuilderImage)
+		if err != nil {
+			return InternalError(err, "failed to restore the builder image")
+		}
+	}
+
+	_, apierr = deployApp(ctx, cluster, owner, deployParam{
+		AppRef:      appRef,
+		StageID:     release.ID,
+		Image:       release.Image,
+		Port:        release.Port,
+		Processes:   release.Processes,
+		Environment: release.Environment,
+	})
+	if apierr != nil {
+		return apierr
+	}
+
+	err = application.RecordRelease(ctx, cluster.Kubectl, appRef, owner, release)
+	if err != nil {
+		return InternalError(err, "failed to record the release")
+	}
+
+	release.Active = true
+	err = jsonResponse(w, models.RollbackResponse{Release: release})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// rollbackTarget returns the release to roll back to. Without an
+// explicitly requested release this is the release deployed before
+// the active one. As rollbacks are releases too, a second rollback
+// returns to the release active before the first.
+func rollbackTarget(releases models.ReleaseList, active, requested string) (models.Release, APIErrors) {
+	if requested != "" {
+		for _, release := range releases {
+			if release.ID == requested {
+				return release, nil
+			}
+		}
+		return models.Release{}, NewNotFoundError(fmt.Sprintf("release '%s' does not exist", requested))
+	}
+
+	for i, release := range releases {
+		if release.ID == active && i+1 < len(releases) {
+			return releases[i+1], nil
+		}
+	}
+
+	return models.Release{}, NewNotFoundError("no previous release to roll back to")
+}
+
+// rollbackRelease returns the release recording the rollback to the
+// target release, by the user, at the given time. It deploys the
+// image, environment, port and processes of the target again, under
+// an ID of its own.
+func rollbackRelease(target models.Release, user string, now time.Time) (models.Release, error) {
+	id, err := randstr.Hex16()
+	if err != nil {
+		return models.Release{}, err
+	}
+
+	release := target
+	release.ID = id
+	release.RollbackOf = target.ID
+	release.Created = now
+	release.User = user
+	release.Active = false
+
+	return release, nil
+}
+
+// activeRelease returns the ID of the release currently deployed, or
+// the empty string if the application has no workload.
+func activeRelease(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (string, error) {
+	deployment, err := cluster.Kubectl.AppsV1().Deployments(appRef.Org).Get(ctx, appRef.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return deployment.Spec.Template.Labels[models.EpinioStageIDLabel], nil
+}
@@ -0,0 +1,111 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("rollbackTarget", func() {
+	releases := models.ReleaseList{{ID: "three"}, {ID: "two"}, {ID: "one"}}
+
+	It("returns the release before the active one", func() {
+		release, apierr := rollbackTarget(releases, "three", "")
+		Expect(apierr).To(BeNil())
+		Expect(release.ID).To(Equal("two"))
+
+		release, apierr = rollbackTarget(releases, "two", "")
+		Expect(apierr).To(BeNil())
+		Expect(release.ID).To(Equal("one"))
+	})
+
+	It("returns the requested release", func() {
+		release, apierr := rollbackTarget(releases, "three", "one")
+		Expect(apierr).To(BeNil())
+		Expect(release.ID).To(Equal("one"))
+	})
+
+	It("fails for an unknown requested release", func() {
+		_, apierr := rollbackTarget(releases, "three", "four")
+		Expect(apierr).ToNot(BeNil())
+		Expect(apierr.FirstStatus()).To(Equal(http.StatusNotFound))
+	})
+
+	It("fails without a release before the active one", func() {
+		_, apierr := rollbackTarget(releases, "one", "")
+		Expect(apierr).ToNot(BeNil())
+		Expect(apierr.FirstStatus()).To(Equal(http.StatusNotFound))
+	})
+
+	It("fails without an active release", func() {
+		_, apierr := rollbackTarget(releases, "", "")
+		Expect(apierr).ToNot(BeNil())
+		Expect(apierr.FirstStatus()).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns to the release active before a rollback", func() {
+		releases := models.ReleaseList{{ID: "four", RollbackOf: "one"}, {ID: "three"}, {ID: "two"}, {ID: "one"}}
+
+		release, apierr := rollbackTarget(releases, "four", "")
+		Expect(apierr).To(BeNil())
+		Expect(release.ID).To(Equal("three"))
+	})
+})
+
+var _ = Describe("rollbackRelease", func() {
+	It("records the rollback as a release of its own", func() {
+		now := time.Now().UTC()
+		target := models.Release{
+			ID:          "one",
+			Image:       "registry.example.com/apps/sample-abc",
+			Environment: models.EnvVariableList{{Name: "MODE", Value: "production"}},
+			Port:        8080,
+			Created:     now.Add(-time.Hour),
+			User:        "pusher",
+			Active:      true,
+		}
+
+		release, err := rollbackRelease(target, "admin", now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(release.ID).ToNot(BeEmpty())
+		Expect(release.ID).ToNot(Equal("one"))
+		Expect(release.RollbackOf).To(Equal("one"))
+		Expect(release.Created).To(Equal(now))
+		Expect(release.User).To(Equal("admin"))
+		Expect(release.Active).To(BeFalse())
+		Expect(release.Image).To(Equal(target.Image))
+		Expect(release.Environment).To(Equal(target.Environment))
+		Expect(release.Port).To(Equal(target.Port))
+	})
+})
+
+var _ = Describe("stagingBuild", func() {
+	It("returns the buildpacks and process type of the staging", func() {
+		pr := &v1beta1.PipelineRun{}
+		pr.Spec.Params = []v1beta1.Param{
+			{Name: "BUILDPACKS", Value: *v1beta1.NewArrayOrString("paketo-buildpacks/go", "paketo-buildpacks/procfile")},
+			{Name: "PROCESS_TYPE", Value: *v1beta1.NewArrayOrString("api")},
+		}
+
+		buildpacks, processType := stagingBuild(pr)
+		Expect(buildpacks).To(Equal([]string{"paketo-buildpacks/go", "paketo-buildpacks/procfile"}))
+		Expect(processType).To(Equal("api"))
+	})
+
+	It("returns nothing for the defaults", func() {
+		pr := &v1beta1.PipelineRun{}
+		pr.Spec.Params = []v1beta1.Param{
+			{Name: "BUILDPACKS", Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray}},
+			{Name: "PROCESS_TYPE", Value: *v1beta1.NewArrayOrString(models.DefaultProcessType)},
+		}
+
+		buildpacks, processType := stagingBuild(pr)
+		Expect(buildpacks).To(BeNil())
+		Expect(processType).To(BeEmpty())
+	})
+})
@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/epinio/epinio/helpers/routes"
+	"github.com/epinio/epinio/internal/metrics"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -73,10 +74,48 @@ var Routes = routes.NamedRoutes{
 	"AppLogs":     get("/orgs/:org/applications/:app/logs", ApplicationsController{}.Logs),
 	"StagingLogs": get("/orgs/:org/staging/:stage_id/logs", ApplicationsController{}.Logs),
 	"AppDelete":   delete("/orgs/:org/applications/:app", errorHandler(ApplicationsController{}.Delete)),
-	"AppUpload":   post("/orgs/:org/applications/:app/store", errorHandler(ApplicationsController{}.Upload)), // See upload.go
-	"AppStage":    post("/orgs/:org/applications/:app/stage", errorHandler(ApplicationsController{}.Stage)),  // See stage.go
+	"AppUpload":   post("/orgs/:org/applications/:app/store", errorHandler(ApplicationsController{}.Upload)),  // See upload.go
+	"AppStage":    post("/orgs/:org/applications/:app/stage", errorHandler(ApplicationsController{}.Stage)),   // See stage.go
+	"AppDeploy":   post("/orgs/:org/applications/:app/deploy", errorHandler(ApplicationsController{}.Deploy)), // See deploy.go
 	"AppUpdate":   patch("/orgs/:org/applications/:app", errorHandler(ApplicationsController{}.Update)),
 
+	// See stage.go
+	"StagingShow":   get("/orgs/:org/staging/:stage_id", errorHandler(ApplicationsController{}.StagingShow)),
+	"StagingCancel": delete("/orgs/:org/staging/:stage_id", errorHandler(ApplicationsController{}.StagingCancel)),
+
+	// See exec.go
+	"AppExec": get("/orgs/:org/applications/:app/exec", ApplicationsController{}.Exec),
+
+	// See portforward.go
+	"AppPortForward": get("/orgs/:org/applications/:app/portforward", ApplicationsController{}.PortForward),
+
+	// See instances.go
+	"AppInstances": get("/orgs/:org/applications/:app/instances", errorHandler(ApplicationsController{}.Instances)),
+
+	// See metrics.go
+	"AppMetrics": get("/orgs/:org/applications/:app/metrics", errorHandler(ApplicationsController{}.Metrics)),
+
+	// See releases.go
+	"AppReleases": get("/orgs/:org/applications/:app/releases", errorHandler(ApplicationsController{}.Releases)),
+	"AppRollback": post("/orgs/:org/applications/:app/rollback", errorHandler(ApplicationsController{}.Rollback)),
+
+	// See lifecycle.go
+	"AppRestart": post("/orgs/:org/applications/:app/restart", errorHandler(ApplicationsController{}.Restart)),
+	"AppStop":    post("/orgs/:org/applications/:app/stop", errorHandler(ApplicationsController{}.Stop)),
+	"AppStart":   post("/orgs/:org/applications/:app/start", errorHandler(ApplicationsController{}.Start)),
+
+	// See cache.go
+	"AppCacheClear": delete("/orgs/:org/applications/:app/cache", errorHandler(ApplicationsController{}.CacheClear)),
+
+	// See autoscale.go
+	"AppAutoscale":       post("/orgs/:org/applications/:app/autoscale", errorHandler(ApplicationsController{}.Autoscale)),
+	"AppAutoscaleDelete": delete("/orgs/:org/applications/:app/autoscale", errorHandler(ApplicationsController{}.AutoscaleDelete)),
+
+	// See routes.go
+	"AppRoutes":      get("/orgs/:org/applications/:app/routes", errorHandler(ApplicationsController{}.RouteIndex)),
+	"AppRouteAdd":    post("/orgs/:org/applications/:app/routes", errorHandler(ApplicationsController{}.RouteAdd)),
+	"AppRouteDelete": delete("/orgs/:org/applications/:app/routes/:route", errorHandler(ApplicationsController{}.RouteDelete)),
+
 	// See env.go
 	"EnvList":  get("/orgs/:org/applications/:app/environment", errorHandler(ApplicationsController{}.EnvIndex)),
 	"EnvMatch": get("/orgs/:org/applications/:app/environment/:env/match/:pattern", errorHandler(ApplicationsController{}.EnvMatch)),
@@ -95,6 +134,13 @@ var Routes = routes.NamedRoutes{
 	"OrgCreate": post("/orgs", errorHandler(OrganizationsController{}.Create)),
 	"OrgDelete": delete("/orgs/:org", errorHandler(OrganizationsController{}.Delete)),
 
+	// See limits.go
+	"OrgLimits":    get("/orgs/:org/limits", errorHandler(OrganizationsController{}.Limits)),
+	"OrgLimitsSet": post("/orgs/:org/limits", errorHandler(OrganizationsController{}.LimitsSet)),
+
+	// See orglogs.go
+	"OrgLogs": get("/orgs/:org/logs", ApplicationsController{}.OrgLogs),
+
 	// List, show, create and delete services, catalog and custom
 	"Services":            get("/orgs/:org/services", errorHandler(ServicesController{}.Index)),
 	"ServiceShow":         get("/orgs/:org/services/:service", errorHandler(ServicesController{}.Show)),
@@ -110,8 +156,8 @@ var Routes = routes.NamedRoutes{
 func Router() *httprouter.Router {
 	router := httprouter.New()
 
-	for _, r := range Routes {
-		router.HandlerFunc(r.Method, r.Path, r.Handler)
+	for name, r := range Routes {
+		router.HandlerFunc(r.Method, r.Path, metrics.Instrument(name, r.Handler))
 	}
 
 	router.NotFound = http.NotFoundHandler()
@@ -0,0 +1,289 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/organizations"
+)
+
+// routesLock serializes the changes to the routes of all applications,
+// making the check that a route is not in use by another application,
+// and the taking of the route, one step. This works because the server
+// runs as a single instance.
+var routesLock sync.Mutex
+
+// RouteIndex returns the routes of the application
+func (hc ApplicationsController) RouteIndex(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+
+	if apierr := checkApp(ctx, cluster, appRef); apierr != nil {
+		return apierr
+	}
+
+	routes, err := application.DeployedRoutes(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	if routes == nil {
+		routes = []string{}
+	}
+
+	err = jsonResponse(w, routes)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// RouteAdd makes the application reachable under an additional route,
+// with a certificate of its own
+func (hc ApplicationsController) RouteAdd(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var req models.RouteRequest
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		return BadRequest(err)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+
+	if apierr := checkApp(ctx, cluster, appRef); apierr != nil {
+		return apierr
+	}
+
+	routesLock.Lock()
+	defer routesLock.Unlock()
+
+	routes, err := application.DeployedRoutes(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	if routes == nil {
+		return NewBadRequest("application has no workload, push it first")
+	}
+	for _, route := range routes {
+		if route == req.Route {
+			return RouteAlreadyKnown(req.Route)
+		}
+	}
+
+	updated := append(append([]string{}, routes...), req.Route)
+	if apierr := checkRoutes(ctx, cluster, appRef, updated); apierr != nil {
+		return apierr
+	}
+
+	log.Info("adding route", "org", org, "app", appName, "route", req.Route)
+
+	return updateRoutes(ctx, cluster, appRef, routes, updated)
+}
+
+// RouteDelete removes a route from the application, and its certificate
+func (hc ApplicationsController) RouteDelete(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+	route := params.ByName("route")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	appRef := models.NewAppRef(appName, org)
+
+	if apierr := checkApp(ctx, cluster, appRef); apierr != nil {
+		return apierr
+	}
+
+	routesLock.Lock()
+	defer routesLock.Unlock()
+
+	routes, err := application.DeployedRoutes(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	updated := []string{}
+	for _, r := range routes {
+		if r != route {
+			updated = append(updated, r)
+		}
+	}
+	if len(updated) == len(routes) {
+		return RouteIsNotKnown(route)
+	}
+	if len(updated) == 0 {
+		return NewBadRequest("cannot remove the last route of the application")
+	}
+
+	log.Info("removing route", "org", org, "app", appName, "route", route)
+
+	return updateRoutes(ctx, cluster, appRef, routes, updated)
+}
+
+// checkApp ensures that org and application exist
+func checkApp(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) APIErrors {
+	exists, err := organizations.Exists(ctx, cluster, appRef.Org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(appRef.Org)
+	}
+
+	exists, err = application.Exists(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return AppIsNotKnown(appRef.Name)
+	}
+
+	return nil
+}
+
+// updateRoutes changes the routes of the deployed application from
+// previous to routes, together with their certificates
+func updateRoutes(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, previous, routes []string) APIErrors {
+	app, err := application.Get(ctx, cluster, appRef)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return AppIsNotKnown(appRef.Name)
+		}
+		return InternalError(err)
+	}
+
+	owner := metav1.OwnerReference{
+		APIVersion: app.GetAPIVersion(),
+		Kind:       app.GetKind(),
+		Name:       app.GetName(),
+		UID:        app.GetUID(),
+	}
+
+	err = application.SetRoutes(ctx, cluster.Kubectl, appRef, routes)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return syncCertificates(ctx, cluster, owner, appRef, previous, routes)
+}
+
+// checkRoutes validates the routes of the application. They have to be
+// DNS names, and must not be used by other applications. The caller
+// holds the routesLock until the routes are taken.
+func checkRoutes(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, routes []string) APIErrors {
+	seen := map[string]bool{}
+	for _, route := range routes {
+		if errs := validation.IsDNS1123Subdomain(route); len(errs) > 0 {
+			return NewBadRequest(fmt.Sprintf("bad route '%s': %s", route, strings.Join(errs, ", ")))
+		}
+		if seen[route] {
+			return NewBadRequest(fmt.Sprintf("route '%s' is given more than once", route))
+		}
+		seen[route] = true
+
+		user, err := application.RouteUser(ctx, cluster.Kubectl, route)
+		if err != nil {
+			return InternalError(err)
+		}
+		if user != nil && *user != appRef {
+			return RouteInUse(route, *user)
+		}
+	}
+	return nil
+}
+
+// syncCertificates ensures a certificate for each of the routes of the
+// application, and removes the certificates of the previous routes no
+// longer in use.
+func syncCertificates(ctx context.Context, cluster *kubernetes.Cluster, owner metav1.OwnerReference, appRef models.AppRef, previous, routes []string) APIErrors {
+	log := tracelog.Logger(ctx)
+
+	issuer := viper.GetString("tls-issuer")
+	current := map[string]bool{}
+
+	for _, route := range routes {
+		current[route] = true
+
+		cert := auth.CertParam{
+			Name:      application.CertName(appRef, route),
+			Namespace: appRef.Org,
+			Issuer:    issuer,
+			Host:      route,
+		}
+
+		log.Info("app cert", "host", cert.Host, "issuer", cert.Issuer)
+
+		err := auth.CreateCertificate(ctx, cluster, cert, &owner)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	for _, route := range previous {
+		if current[route] {
+			continue
+		}
+		err := auth.DeleteCertificate(ctx, cluster, appRef.Org, application.CertName(appRef, route))
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	// Applications deployed before the certificates per route have
+	// the certificate of their single route named after them
+	err := auth.DeleteCertificate(ctx, cluster, appRef.Org, appRef.Name)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
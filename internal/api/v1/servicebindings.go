@@ -102,12 +102,17 @@ func (hc ServicebindingsController) Create(w http.ResponseWriter, r *http.Reques
 	resp := models.BindResponse{}
 
 	for _, service := range theServices {
-		err = wl.Bind(ctx, service)
+		err = wl.Bind(ctx, service, bindRequest.BindOptions)
 		if err != nil {
 			if err.Error() == "service already bound" {
 				resp.WasBound = append(resp.WasBound, service.Name())
 				continue
 			}
+			var conflict application.EnvConflictError
+			if errors.As(err, &conflict) {
+				theIssues = append(theIssues, BadRequest(err))
+				continue
+			}
 
 			theIssues = append([]APIError{InternalError(err)}, theIssues...)
 			return MultiError{theIssues}
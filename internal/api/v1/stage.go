@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"strconv"
+	"net/url"
+	"os"
+	"os/exec"
 	"strings"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/apis/resource/v1alpha1"
@@ -18,7 +21,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/epinio/epinio/deployments"
 	"github.com/epinio/epinio/helpers/kubernetes"
@@ -26,7 +29,7 @@ import (
 	"github.com/epinio/epinio/helpers/tracelog"
 	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/epinio/epinio/internal/application"
-	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/cli/clients/gitea"
 	"github.com/epinio/epinio/internal/domain"
 )
 
@@ -37,13 +40,13 @@ const (
 
 type stageParam struct {
 	models.AppRef
-	Image       models.ImageRef
-	Git         *models.GitRef
-	Route       string
-	Stage       models.StageRef
-	Instances   int32
-	Owner       metav1.OwnerReference
-	Environment models.EnvVariableList
+	Git              *models.GitRef
+	BuildStrategy    string
+	BuilderImage     string
+	Buildpacks       []string
+	ProcessType      string
+	Environment      models.EnvVariableList
+	BuildEnvironment models.EnvVariableList
 }
 
 // GitURL returns the git URL by combining the server with the org and name
@@ -84,9 +87,37 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 	if org != req.App.Org {
 		return NewBadRequest("org parameter from URL does not match org param in body")
 	}
-
-	if req.Instances != nil && *req.Instances < 0 {
-		return NewBadRequest("instances param should be integer equal or greater than zero")
+	if req.ContainerImage != "" && req.Git != nil {
+		return NewBadRequest("container image and git sources are mutually exclusive")
+	}
+	if req.ContainerImage == "" && req.Git == nil {
+		return NewBadRequest("neither container image nor git sources given")
+	}
+	if strings.ContainsAny(req.ContainerImage, " \t\n") {
+		return NewBadRequest("container image reference must not contain whitespace")
+	}
+	switch req.BuildStrategy {
+	case "", models.BuildStrategyBuildpacks, models.BuildStrategyDockerfile:
+	default:
+		return NewBadRequest(fmt.Sprintf("unknown build strategy '%s'", req.BuildStrategy))
+	}
+	if req.BuildStrategy == models.BuildStrategyDockerfile && (req.BuilderImage != "" || len(req.Buildpacks) > 0 || req.ProcessType != "") {
+		return NewBadRequest("builder image, buildpacks and process type are not used by a dockerfile build")
+	}
+	if req.ProcessType != "" && len(validation.IsDNS1123Label(req.ProcessType)) > 0 {
+		return NewBadRequest(fmt.Sprintf("bad process type '%s'", req.ProcessType))
+	}
+	if req.BuilderImage != "" && !offeredBuilder(req.BuilderImage) {
+		return NewBadRequest(fmt.Sprintf("builder image '%s' is not offered, expected one of %s",
+			req.BuilderImage, strings.Join(builderImages(), ", ")))
+	}
+	for _, buildpack := range req.Buildpacks {
+		if buildpack == "" || strings.ContainsAny(buildpack, " \t\n\"") {
+			return NewBadRequest(fmt.Sprintf("bad buildpack '%s'", buildpack))
+		}
+	}
+	if apierr := validateDeploySettings(req.DeploySettings); apierr != nil {
+		return apierr
 	}
 
 	cluster, err := kubernetes.GetCluster(ctx)
@@ -103,6 +134,39 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 		return InternalError(err, "failed to get the application resource")
 	}
 
+	// Fail before the staging when the deployment would fail
+	if apierr := checkDeployedResources(ctx, cluster, req.App); apierr != nil {
+		return apierr
+	}
+
+	// A prebuilt image needs no staging. It is handed back for deployment as is.
+	if req.ContainerImage != "" {
+		log.Info("skipped staging of prebuilt image", "org", org, "app", req.App, "image", req.ContainerImage)
+
+		err = jsonResponse(w, models.StageResponse{Image: models.NewImage(req.ContainerImage)})
+		if err != nil {
+			return InternalError(err)
+		}
+		return nil
+	}
+
+	// Without a choice of strategy, or of a builder, the sources
+	// containing a Dockerfile are built from it.
+	if req.BuildStrategy == "" && req.BuilderImage == "" && len(req.Buildpacks) == 0 && req.ProcessType == "" {
+		req.BuildStrategy, err = detectBuildStrategy(ctx, req.Git)
+		if err != nil {
+			log.Error(err, "failed to detect the build strategy", "org", org, "app", req.App)
+			req.BuildStrategy = models.BuildStrategyBuildpacks
+		}
+	}
+
+	// A chosen builder is used for the later stagings of the
+	// application as well, as long as it is offered
+	builderImage := req.BuilderImage
+	if remembered := app.GetAnnotations()[application.BuilderImageAnnotation]; builderImage == "" && offeredBuilder(remembered) {
+		builderImage = remembered
+	}
+
 	log.Info("staging app", "org", org, "app", req)
 
 	cs, err := versioned.NewForConfig(cluster.RestConfig)
@@ -116,29 +180,12 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 		return InternalError(err, "failed to generate a uid")
 	}
 
-	l, err := client.List(ctx, metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("app.kubernetes.io/name=%s,app.kubernetes.io/part-of=%s", req.App.Name, req.App.Org),
-	})
+	staging, err := application.CurrentStaging(ctx, cluster, req.App)
 	if err != nil {
 		return InternalError(err)
 	}
-
-	// assume that completed pipelineruns are from the past and have a CompletionTime
-	for _, pr := range l.Items {
-		if pr.Status.CompletionTime == nil {
-			return NewBadRequest("pipelinerun for image ID still running")
-		}
-	}
-
-	// find out the instances
-	var instances int32
-	if req.Instances != nil {
-		instances = int32(*req.Instances)
-	} else {
-		instances, err = existingReplica(ctx, cluster.Kubectl, req.App)
-		if err != nil {
-			return InternalError(err)
-		}
+	if staging != "" {
+		return NewBadRequest("pipelinerun for image ID still running")
 	}
 
 	// determine runtime environment, if any
@@ -147,56 +194,68 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 		return InternalError(err, "failed to access application runtime environment")
 	}
 
-	owner := metav1.OwnerReference{
-		APIVersion: app.GetAPIVersion(),
-		Kind:       app.GetKind(),
-		Name:       app.GetName(),
-		UID:        app.GetUID(),
-	}
 	params := stageParam{
-		AppRef:      req.App,
-		Git:         req.Git,
-		Route:       req.Route,
-		Instances:   instances,
-		Owner:       owner,
-		Environment: env,
+		AppRef:           req.App,
+		Git:              req.Git,
+		BuildStrategy:    req.BuildStrategy,
+		BuilderImage:     builderImage,
+		Buildpacks:       req.Buildpacks,
+		ProcessType:      req.ProcessType,
+		Environment:      env,
+		BuildEnvironment: req.BuildEnvironment,
 	}
 
-	mainDomain, err := domain.MainDomain(ctx)
+	if params.BuildStrategy != models.BuildStrategyDockerfile {
+		err = application.EnsureCache(ctx, cluster.Kubectl, req.App)
+		if err != nil {
+			return InternalError(err, "failed to set up the build cache")
+		}
+	}
+
+	deploymentImageURL, registryURL, err := registries(ctx)
 	if err != nil {
 		return InternalError(err)
 	}
-	var deploymentImageURL string
-	registryURL := fmt.Sprintf("%s.%s/%s", deployments.RegistryDeploymentID, mainDomain, "apps")
-	if viper.GetBool("use-internal-registry-node-port") {
-		deploymentImageURL = LocalRegistry
-	} else {
-		deploymentImageURL = registryURL
-	}
 
-	pr := newPipelineRun(uid, params, mainDomain, registryURL, deploymentImageURL)
-	o, err := client.Create(ctx, pr, metav1.CreateOptions{})
+	// The settings for the deployment of the staged image by the
+	// server, when the client does not deploy it
+	settings := req.DeploySettings
+	if len(settings.Routes) == 0 && req.Route != "" {
+		settings.Routes = []string{req.Route}
+	}
+	deploy, err := json.Marshal(models.DeployRequest{
+		App:            req.App,
+		Stage:          models.NewStage(uid),
+		DeploySettings: settings,
+	})
 	if err != nil {
-		return InternalError(err, fmt.Sprintf("failed to create pipeline run: %#v", o))
+		return InternalError(err)
 	}
+	user, _, _ := r.BasicAuth()
 
-	cert := auth.CertParam{
-		Name:      params.Name,
-		Namespace: params.Org,
-		Issuer:    viper.GetString("tls-issuer"),
-		Domain:    mainDomain,
+	if req.BuilderImage != "" {
+		err = application.SetBuilderImage(ctx, cluster, req.App, req.BuilderImage)
+		if err != nil {
+			return InternalError(err, "failed to remember the builder image")
+		}
 	}
 
-	log.Info("app cert", "domain", cert.Domain, "issuer", cert.Issuer)
-
-	err = auth.CreateCertificate(ctx, cluster, cert, &owner)
+	pr := newPipelineRun(uid, params, registryURL)
+	pr.Annotations = map[string]string{
+		DeployAnnotation:     string(deploy),
+		DeployUserAnnotation: user,
+	}
+	o, err := client.Create(ctx, pr, metav1.CreateOptions{})
 	if err != nil {
-		return InternalError(err)
+		return InternalError(err, fmt.Sprintf("failed to create pipeline run: %#v", o))
 	}
 
 	log.Info("staged app", "org", org, "app", params.AppRef, "uid", uid)
 
-	resp := models.StageResponse{Stage: models.NewStage(uid)}
+	resp := models.StageResponse{
+		Stage: models.NewStage(uid),
+		Image: models.NewImage(params.ImageURL(deploymentImageURL)),
+	}
 	err = jsonResponse(w, resp)
 	if err != nil {
 		return InternalError(err)
@@ -205,33 +264,140 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 	return nil
 }
 
-func existingReplica(ctx context.Context, client *k8s.Clientset, app models.AppRef) (int32, error) {
-	// if a deployment exists, use that deployment's replica count
-	result, err := client.AppsV1().Deployments(app.Org).Get(ctx, app.Name, metav1.GetOptions{})
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return DefaultInstances, nil
+// detectBuildStrategy returns the build strategy for the sources,
+// dockerfile when they contain a Dockerfile at the top, else
+// buildpacks. Only the trees of the revision are fetched, not its
+// files.
+func detectBuildStrategy(ctx context.Context, git *models.GitRef) (string, error) {
+	remote := git.URL
+	if strings.HasPrefix(remote, deployments.GiteaURL) {
+		client, err := gitea.New(ctx)
+		if err != nil {
+			return "", err
 		}
-		return 0, err
+		u, err := url.Parse(remote)
+		if err != nil {
+			return "", err
+		}
+		u.User = url.UserPassword(client.Auth.Username, client.Auth.Password)
+		remote = u.String()
+	}
+
+	tmpDir, err := ioutil.TempDir("", "epinio-detect")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// The output is not part of the error, it may show the
+	// credentials of the remote
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", `
+cd "$1"
+git init -q
+git fetch -q --depth 1 --filter=blob:none "$2" "$3"
+git ls-tree --name-only FETCH_HEAD Dockerfile
+`, "detect", tmpDir, remote, git.Revision)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to inspect the sources")
+	}
+
+	if strings.TrimSpace(string(out)) == "Dockerfile" {
+		return models.BuildStrategyDockerfile, nil
 	}
-	return *result.Spec.Replicas, nil
+	return models.BuildStrategyBuildpacks, nil
 }
 
-func newPipelineRun(uid string, app stageParam, mainDomain, registryURL, deploymentImageURL string) *v1beta1.PipelineRun {
+// registries returns the URL of the registry the workloads pull the
+// staged images from, and the URL of the registry the staging pushes
+// them to.
+func registries(ctx context.Context) (string, string, error) {
+	mainDomain, err := domain.MainDomain(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	registryURL := fmt.Sprintf("%s.%s/%s", deployments.RegistryDeploymentID, mainDomain, "apps")
+	if viper.GetBool("use-internal-registry-node-port") {
+		return LocalRegistry, registryURL, nil
+	}
+	return registryURL, registryURL, nil
+}
+
+func newPipelineRun(uid string, app stageParam, registryURL string) *v1beta1.PipelineRun {
 	str := v1beta1.NewArrayOrString
 
 	stagingVariables := []string{}
-
-	assignments := []string{
-		fmt.Sprintf(`{ "name": "%s", "value": "%s"}`, `PORT`, `8080`),
-	}
 	for _, ev := range app.Environment {
-		assignments = append(assignments,
-			fmt.Sprintf(`{ "name": "%s", "valueFrom": { "secretKeyRef": {"key":"%s","name":"%s"}}}`,
-				ev.Name, ev.Name, application.EnvSecret(app.AppRef)))
 		stagingVariables = append(stagingVariables, fmt.Sprintf("%s=%s", ev.Name, ev.Value))
 	}
-	environment := `[` + strings.Join(assignments, ",") + `]`
+
+	// Build-time variables come last, to override runtime settings of the same name
+	for _, ev := range app.BuildEnvironment {
+		stagingVariables = append(stagingVariables, fmt.Sprintf("%s=%s", ev.Name, ev.Value))
+	}
+
+	params := []v1beta1.Param{
+		{Name: "APP_NAME", Value: *str(app.Name)},
+		{Name: "ORG", Value: *str(app.Org)},
+		{Name: "APP_IMAGE", Value: *str(app.ImageURL(registryURL))},
+		{Name: "STAGE_ID", Value: *str(uid)},
+	}
+
+	workspaces := []v1beta1.WorkspaceBinding{
+		{
+			Name: "source",
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaim{
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceName(corev1.ResourceStorage): resource.MustParse("1Gi"),
+					}},
+				},
+			},
+		},
+	}
+
+	// A Dockerfile build takes the variables as build arguments
+	pipeline := "staging-pipeline"
+	if app.BuildStrategy == models.BuildStrategyDockerfile {
+		pipeline = "staging-pipeline-dockerfile"
+		for i, variable := range stagingVariables {
+			stagingVariables[i] = "--build-arg=" + variable
+		}
+		params = append(params, v1beta1.Param{Name: "BUILD_ARGS", Value: v1beta1.ArrayOrString{
+			Type:     v1beta1.ParamTypeArray,
+			ArrayVal: stagingVariables,
+		}})
+	} else {
+		builderImage := app.BuilderImage
+		if builderImage == "" {
+			builderImage = viper.GetString("builder-image")
+		}
+		processType := app.ProcessType
+		if processType == "" {
+			processType = models.DefaultProcessType
+		}
+		params = append(params,
+			v1beta1.Param{Name: "ENV_VARS", Value: v1beta1.ArrayOrString{
+				Type:     v1beta1.ParamTypeArray,
+				ArrayVal: stagingVariables,
+			}},
+			v1beta1.Param{Name: "BUILDER_IMAGE", Value: *str(builderImage)},
+			v1beta1.Param{Name: "BUILDPACKS", Value: v1beta1.ArrayOrString{
+				Type:     v1beta1.ParamTypeArray,
+				ArrayVal: append([]string{}, app.Buildpacks...),
+			}},
+			v1beta1.Param{Name: "PROCESS_TYPE", Value: *str(processType)},
+		)
+
+		// The build cache persists across stagings
+		workspaces = append(workspaces, v1beta1.WorkspaceBinding{
+			Name: "cache",
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: application.CacheName(app.AppRef),
+			},
+		})
+	}
 
 	return &v1beta1.PipelineRun{
 		ObjectMeta: metav1.ObjectMeta{
@@ -246,38 +412,9 @@ func newPipelineRun(uid string, app stageParam, mainDomain, registryURL, deploym
 		},
 		Spec: v1beta1.PipelineRunSpec{
 			ServiceAccountName: "staging-triggers-admin",
-			PipelineRef:        &v1beta1.PipelineRef{Name: "staging-pipeline"},
-			Params: []v1beta1.Param{
-				{Name: "APP_NAME", Value: *str(app.Name)},
-				{Name: "ORG", Value: *str(app.Org)},
-				{Name: "ROUTE", Value: *str(app.Route)},
-				{Name: "INSTANCES", Value: *str(strconv.Itoa(int(app.Instances)))},
-				{Name: "APP_IMAGE", Value: *str(app.ImageURL(registryURL))},
-				{Name: "DEPLOYMENT_IMAGE", Value: *str(app.ImageURL(deploymentImageURL))},
-				{Name: "STAGE_ID", Value: *str(uid)},
-				{Name: "OWNER_APIVERSION", Value: *str(app.Owner.APIVersion)},
-				{Name: "OWNER_NAME", Value: *str(app.Owner.Name)},
-				{Name: "OWNER_KIND", Value: *str(app.Owner.Kind)},
-				{Name: "OWNER_UID", Value: *str(string(app.Owner.UID))},
-				{Name: "ENVIRONMENT", Value: *str(environment)},
-				{Name: "ENV_VARS", Value: v1beta1.ArrayOrString{
-					Type:     v1beta1.ParamTypeArray,
-					ArrayVal: stagingVariables},
-				},
-			},
-			Workspaces: []v1beta1.WorkspaceBinding{
-				{
-					Name: "source",
-					VolumeClaimTemplate: &corev1.PersistentVolumeClaim{
-						Spec: corev1.PersistentVolumeClaimSpec{
-							AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
-							Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
-								corev1.ResourceName(corev1.ResourceStorage): resource.MustParse("1Gi"),
-							}},
-						},
-					},
-				},
-			},
+			PipelineRef:        &v1beta1.PipelineRef{Name: pipeline},
+			Params:             params,
+			Workspaces:         workspaces,
 			Resources: []v1beta1.PipelineResourceBinding{
 				{
 					Name: "source-repo",
@@ -293,3 +430,80 @@ func newPipelineRun(uid string, app stageParam, mainDomain, registryURL, deploym
 		},
 	}
 }
+
+// StagingShow returns the progress of the identified staging run of
+// an application, including the state of each of its tasks.
+func (hc ApplicationsController) StagingShow(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	p := httprouter.ParamsFromContext(ctx)
+	org := p.ByName("org")
+	stageID := p.ByName("stage_id")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err, "failed to get access to a kube client")
+	}
+
+	status, err := application.StagingStatus(ctx, cluster, stageID)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return NewNotFoundError(fmt.Sprintf("staging run '%s' does not exist", stageID))
+		}
+		return InternalError(err)
+	}
+	if status.App.Org != org {
+		return NewNotFoundError(fmt.Sprintf("staging run '%s' does not exist", stageID))
+	}
+
+	err = jsonResponse(w, status)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// StagingCancel stops the identified staging run of an application,
+// and removes the volume holding its sources.
+func (hc ApplicationsController) StagingCancel(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	p := httprouter.ParamsFromContext(ctx)
+	org := p.ByName("org")
+	stageID := p.ByName("stage_id")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err, "failed to get access to a kube client")
+	}
+
+	cs, err := versioned.NewForConfig(cluster.RestConfig)
+	if err != nil {
+		return InternalError(err, "failed to get access to a tekton client")
+	}
+
+	pr, err := cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace).Get(ctx, stageID, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return NewNotFoundError(fmt.Sprintf("staging run '%s' does not exist", stageID))
+		}
+		return InternalError(err)
+	}
+	if pr.Labels["app.kubernetes.io/part-of"] != org {
+		return NewNotFoundError(fmt.Sprintf("staging run '%s' does not exist", stageID))
+	}
+	if pr.Status.CompletionTime != nil {
+		return NewBadRequest(fmt.Sprintf("staging run '%s' has already completed", stageID))
+	}
+
+	log.Info("cancelling staging", "org", org, "app", pr.Labels["app.kubernetes.io/name"], "uid", stageID)
+
+	err = application.CancelStaging(ctx, cluster, stageID)
+	if err != nil {
+		return InternalError(err, "failed to cancel the staging run")
+	}
+
+	return nil
+}
@@ -228,6 +228,12 @@ func Delete(ctx context.Context, cluster *kubernetes.Cluster, gitea GiteaInterfa
 		return err
 	}
 
+	// delete the build cache, also in the tekton-staging namespace
+	err = ClearCache(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return err
+	}
+
 	err = cluster.WaitForPodBySelectorMissing(ctx, nil,
 		appRef.Org,
 		fmt.Sprintf("app.kubernetes.io/name=%s", appRef.Name),
@@ -275,20 +281,23 @@ func Unstage(ctx context.Context, cluster *kubernetes.Cluster, appRef models.App
 // to close the logChan when done.
 // When stageID is an empty string, no staging logs are returned. If it is set,
 // then only logs from that staging process are returned.
-func Logs(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.WaitGroup, cluster *kubernetes.Cluster, follow bool, app, stageID, org string) error {
+// The options select the lines, see models.LogOptions. Their instance has to
+// be a pod name.
+func Logs(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.WaitGroup, cluster *kubernetes.Cluster, follow bool, app, stageID, org string, options models.LogOptions) error {
 	selector := labels.NewSelector()
 
 	var selectors [][]string
+	components := []string{"staging"}
 	if stageID == "" {
+		// The instances of the workers are part of the application
+		components = []string{"application", "worker"}
 		selectors = [][]string{
-			{"app.kubernetes.io/component", "application"},
 			{"app.kubernetes.io/managed-by", "epinio"},
 			{"app.kubernetes.io/part-of", org},
 			{"app.kubernetes.io/name", app},
 		}
 	} else {
 		selectors = [][]string{
-			{"app.kubernetes.io/component", "staging"},
 			{"app.kubernetes.io/managed-by", "epinio"},
 			{models.EpinioStageIDLabel, stageID},
 			{"app.kubernetes.io/part-of", org},
@@ -302,20 +311,95 @@ func Logs(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.Wa
 		}
 		selector = selector.Add(*req)
 	}
+	req, err := labels.NewRequirement("app.kubernetes.io/component", selection.In, components)
+	if err != nil {
+		return err
+	}
+	selector = selector.Add(*req)
+
+	return tailLogs(ctx, logChan, wg, cluster, follow, selector, options)
+}
+
+// OrgLogs method writes the log lines of the applications of the org, of their
+// workers, and of their stagings, to the specified logChan, as Logs does. Without apps the logs
+// of all applications in the org are written. The instance of the options is
+// not supported.
+func OrgLogs(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.WaitGroup, cluster *kubernetes.Cluster, follow bool, org string, apps []string, options models.LogOptions) error {
+	selector, err := orgLogsSelector(org, apps)
+	if err != nil {
+		return err
+	}
+
+	options.Instance = ""
+	return tailLogs(ctx, logChan, wg, cluster, follow, selector, options)
+}
+
+// orgLogsSelector selects the pods of the applications of the org, of
+// their workers, and of their stagings. Without apps all applications
+// of the org are selected.
+func orgLogsSelector(org string, apps []string) (labels.Selector, error) {
+	selector := labels.NewSelector()
+
+	selectors := [][]string{
+		{"app.kubernetes.io/managed-by", "epinio"},
+		{"app.kubernetes.io/part-of", org},
+	}
+	sets := map[string][]string{
+		"app.kubernetes.io/component": {"application", "worker", "staging"},
+	}
+	if len(apps) > 0 {
+		sets[tailer.AppNameLabel] = apps
+	}
+
+	for _, req := range selectors {
+		req, err := labels.NewRequirement(req[0], selection.Equals, []string{req[1]})
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*req)
+	}
+	for key, values := range sets {
+		req, err := labels.NewRequirement(key, selection.In, values)
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*req)
+	}
+
+	return selector, nil
+}
+
+// tailLogs writes the log lines of the containers of the pods matching the
+// selector to the logChan, as chosen by the options.
+func tailLogs(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.WaitGroup, cluster *kubernetes.Cluster, follow bool, selector labels.Selector, options models.LogOptions) error {
+	include, exclude, err := options.Filters()
+	if err != nil {
+		return err
+	}
+
+	since := duration.LogHistory()
+	if options.Since > 0 {
+		since = options.Since
+	}
+
+	podQuery := regexp.MustCompile(".*")
+	if options.Instance != "" {
+		podQuery = regexp.MustCompile("^" + regexp.QuoteMeta(options.Instance) + "$")
+	}
 
 	config := &tailer.Config{
 		ContainerQuery:        regexp.MustCompile(".*"),
 		ExcludeContainerQuery: regexp.MustCompile("linkerd-(proxy|init)"),
 		ContainerState:        "running",
-		Exclude:               nil,
-		Include:               nil,
-		Timestamps:            false,
-		Since:                 duration.LogHistory(),
+		Exclude:               exclude,
+		Include:               include,
+		Timestamps:            options.Timestamps,
+		Since:                 since,
 		AllNamespaces:         true,
 		LabelSelector:         selector,
-		TailLines:             nil,
+		TailLines:             options.Tail,
 		Namespace:             "",
-		PodQuery:              regexp.MustCompile(".*"),
+		PodQuery:              podQuery,
 	}
 
 	if follow {
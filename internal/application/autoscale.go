@@ -0,0 +1,116 @@
+package application
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// DefaultAutoscaleMin is the least number of instances of an autoscaled application
+	DefaultAutoscaleMin = int32(1)
+	// DefaultAutoscaleCPUPercent is the cpu usage an autoscaler aims for
+	DefaultAutoscaleCPUPercent = int32(80)
+)
+
+// Autoscale creates the HorizontalPodAutoscaler of the application, or
+// updates it when it exists already. The autoscaler scales the
+// Deployment of the application, and is owned by the application
+// resource.
+func Autoscale(ctx context.Context, client k8s.Interface, appRef models.AppRef, owner metav1.OwnerReference, req models.AutoscaleRequest) error {
+	autoscalers := client.AutoscalingV1().HorizontalPodAutoscalers(appRef.Org)
+	desired := newAutoscaler(appRef, owner, req)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := autoscalers.Get(ctx, appRef.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = autoscalers.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		current.Labels = desired.Labels
+		current.OwnerReferences = desired.OwnerReferences
+		current.Spec = desired.Spec
+
+		_, err = autoscalers.Update(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// DeleteAutoscaler removes the HorizontalPodAutoscaler of the
+// application. The application keeps the instances it has.
+func DeleteAutoscaler(ctx context.Context, client k8s.Interface, appRef models.AppRef) error {
+	err := client.AutoscalingV1().HorizontalPodAutoscalers(appRef.Org).Delete(ctx, appRef.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Autoscaler returns the autoscaling of the application, or nil if the
+// application is not autoscaled.
+func Autoscaler(ctx context.Context, client k8s.Interface, appRef models.AppRef) (*models.Autoscaling, error) {
+	hpa, err := client.AutoscalingV1().HorizontalPodAutoscalers(appRef.Org).Get(ctx, appRef.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	autoscaling := &models.Autoscaling{
+		Max:             hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+	}
+	if hpa.Spec.MinReplicas != nil {
+		autoscaling.Min = *hpa.Spec.MinReplicas
+	}
+	if hpa.Spec.TargetCPUUtilizationPercentage != nil {
+		autoscaling.CPUPercent = *hpa.Spec.TargetCPUUtilizationPercentage
+	}
+
+	return autoscaling, nil
+}
+
+func newAutoscaler(appRef models.AppRef, owner metav1.OwnerReference, req models.AutoscaleRequest) *autoscalingv1.HorizontalPodAutoscaler {
+	min := DefaultAutoscaleMin
+	if req.Min != nil {
+		min = *req.Min
+	}
+	cpuPercent := req.CPUPercent
+	if cpuPercent == 0 {
+		cpuPercent = DefaultAutoscaleCPUPercent
+	}
+
+	labels := appLabels(appRef)
+	labels["app.kubernetes.io/component"] = "autoscaler"
+
+	return &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            appRef.Name,
+			Namespace:       appRef.Org,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       appRef.Name,
+			},
+			MinReplicas:                    &min,
+			MaxReplicas:                    req.Max,
+			TargetCPUUtilizationPercentage: &cpuPercent,
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package application_test
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Autoscale", func() {
+	var (
+		ctx    context.Context
+		client *fake.Clientset
+		appRef models.AppRef
+		owner  metav1.OwnerReference
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = fake.NewSimpleClientset()
+		appRef = models.NewAppRef("sample", "workspace")
+		owner = metav1.OwnerReference{
+			APIVersion: "app.k8s.io/v1beta1",
+			Kind:       "App",
+			Name:       "sample",
+			UID:        "1234",
+		}
+	})
+
+	It("has no autoscaling without autoscaler", func() {
+		autoscaling, err := application.Autoscaler(ctx, client, appRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(autoscaling).To(BeNil())
+	})
+
+	It("creates, updates and deletes the autoscaler of the application", func() {
+		Expect(application.Autoscale(ctx, client, appRef, owner, models.AutoscaleRequest{Max: 4})).To(Succeed())
+
+		hpa, err := client.AutoscalingV1().HorizontalPodAutoscalers("workspace").Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hpa.OwnerReferences).To(ConsistOf(owner))
+		Expect(hpa.Labels).To(HaveKeyWithValue("app.kubernetes.io/component", "autoscaler"))
+		Expect(hpa.Spec.ScaleTargetRef.Kind).To(Equal("Deployment"))
+		Expect(hpa.Spec.ScaleTargetRef.Name).To(Equal("sample"))
+
+		autoscaling, err := application.Autoscaler(ctx, client, appRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*autoscaling).To(Equal(models.Autoscaling{
+			Min:        application.DefaultAutoscaleMin,
+			Max:        4,
+			CPUPercent: application.DefaultAutoscaleCPUPercent,
+		}))
+
+		By("updating it")
+		min := int32(2)
+		Expect(application.Autoscale(ctx, client, appRef, owner, models.AutoscaleRequest{Min: &min, Max: 6, CPUPercent: 50})).To(Succeed())
+
+		autoscaling, err = application.Autoscaler(ctx, client, appRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(autoscaling.Min).To(Equal(int32(2)))
+		Expect(autoscaling.Max).To(Equal(int32(6)))
+		Expect(autoscaling.CPUPercent).To(Equal(int32(50)))
+
+		By("deleting it")
+		Expect(application.DeleteAutoscaler(ctx, client, appRef)).To(Succeed())
+		Expect(application.DeleteAutoscaler(ctx, client, appRef)).To(Succeed())
+
+		autoscaling, err = application.Autoscaler(ctx, client, appRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(autoscaling).To(BeNil())
+	})
+})
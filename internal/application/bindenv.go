@@ -0,0 +1,303 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// ServicesEnvVar is the environment variable holding the bindings of
+// the services bound with JSON, as a JSON object keyed by service name
+const ServicesEnvVar = "EPINIO_SERVICES"
+
+// EnvConflictError is returned when an environment variable of a
+// binding is already set in the application, when several keys of a
+// binding map to the same variable, or when a variable set by a
+// binding is set in the application
+type EnvConflictError struct {
+	Name string
+	// Keys are the keys of the binding mapping to the variable, if
+	// several do
+	Keys []string
+	// Bound is true for a variable set by a binding already
+	Bound bool
+}
+
+func (e EnvConflictError) Error() string {
+	switch {
+	case len(e.Keys) > 1:
+		return fmt.Sprintf("keys '%s' of the binding all map to the environment variable '%s'",
+			strings.Join(e.Keys, "', '"), e.Name)
+	case e.Bound:
+		return fmt.Sprintf("environment variable '%s' is set by a service binding", e.Name)
+	default:
+		return fmt.Sprintf("environment variable '%s' of the binding is already set", e.Name)
+	}
+}
+
+var notEnvName = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// BindingEnvName returns the name of the environment variable for the
+// key of the binding of the named service. It is the key, prefixed with
+// the service name, upper cased, with characters other than letters
+// and digits replaced by underscores.
+func BindingEnvName(service, key string) string {
+	return notEnvName.ReplaceAllString(strings.ToUpper(service+"_"+key), "_")
+}
+
+// bindingEnv returns the environment variables for the keys of the
+// binding secret of the named service, ordered by name. Keys mapping to
+// the same variable are an EnvConflictError.
+func bindingEnv(service string, bindSecret *corev1.Secret) ([]corev1.EnvVar, error) {
+	keys := []string{}
+	for key := range bindSecret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	byName := map[string][]string{}
+	for _, key := range keys {
+		name := BindingEnvName(service, key)
+		byName[name] = append(byName[name], key)
+	}
+
+	result := []corev1.EnvVar{}
+	for _, key := range keys {
+		name := BindingEnvName(service, key)
+		if len(byName[name]) > 1 {
+			return nil, EnvConflictError{Name: name, Keys: byName[name]}
+		}
+		result = append(result, corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: bindSecret.Name,
+					},
+					Key: key,
+				},
+			},
+		})
+	}
+	return result, nil
+}
+
+// servicesEnv returns the environment variable holding the bindings of
+// the services bound with JSON
+func servicesEnv(appRef models.AppRef) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: ServicesEnvVar,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: ServicesSecret(appRef),
+				},
+				Key: ServicesEnvVar,
+			},
+		},
+	}
+}
+
+// UpdateServicesJSON adds the binding of the named service to the JSON
+// of the bindings of the application, or removes it, for a nil binding.
+// The JSON is kept in a secret owned by the application, which is
+// created on the first binding. It returns the number of bindings left
+// in the JSON.
+func UpdateServicesJSON(ctx context.Context, client k8s.Interface, appRef models.AppRef, owner metav1.OwnerReference, service string, binding map[string][]byte) (int, error) {
+	secrets := client.CoreV1().Secrets(appRef.Org)
+	count := 0
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secrets.Get(ctx, ServicesSecret(appRef), metav1.GetOptions{})
+		create := apierrors.IsNotFound(err)
+		switch {
+		case create && binding == nil:
+			count = 0
+			return nil
+		case create:
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ServicesSecret(appRef),
+					Namespace: appRef.Org,
+					Labels: map[string]string{
+						"app.kubernetes.io/name":       appRef.Name,
+						"app.kubernetes.io/part-of":    appRef.Org,
+						"app.kubernetes.io/managed-by": "epinio",
+						"app.kubernetes.io/component":  "application",
+					},
+					OwnerReferences: []metav1.OwnerReference{owner},
+				},
+			}
+		case err != nil:
+			return err
+		}
+
+		bindings, err := decodeServicesJSON(secret)
+		if err != nil {
+			return err
+		}
+
+		if binding == nil {
+			delete(bindings, service)
+		} else {
+			values := map[string]string{}
+			for key, value := range binding {
+				values[key] = string(value)
+			}
+			bindings[service] = values
+		}
+		count = len(bindings)
+
+		if err := encodeServicesJSON(secret, bindings); err != nil {
+			return err
+		}
+
+		if create {
+			_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+		} else {
+			_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		}
+		return err
+	})
+
+	return count, err
+}
+
+// ServicesSecret returns the name of the secret holding the JSON of the
+// bindings of the application
+func ServicesSecret(appRef models.AppRef) string {
+	return appRef.Name + "-services"
+}
+
+// RefreshServicesJSON rebuilds the JSON of the bindings of the
+// application from their current binding secrets, as found through the
+// volumes of its deployment. This way the credentials of a service
+// which changed since its binding reach the application on its next
+// deploy, or restart. Without JSON, or deployment, there is nothing to
+// do.
+func RefreshServicesJSON(ctx context.Context, client k8s.Interface, appRef models.AppRef) error {
+	secrets := client.CoreV1().Secrets(appRef.Org)
+
+	deployment, err := client.AppsV1().Deployments(appRef.Org).Get(ctx, appRef.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	bindSecrets := map[string]string{}
+	for _, volume := range deployment.Spec.Template.Spec.Volumes {
+		if volume.Secret != nil {
+			bindSecrets[volume.Name] = volume.Secret.SecretName
+		}
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secrets.Get(ctx, ServicesSecret(appRef), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		bindings, err := decodeServicesJSON(secret)
+		if err != nil {
+			return err
+		}
+
+		for service := range bindings {
+			name, ok := bindSecrets[service]
+			if !ok {
+				continue
+			}
+			bindSecret, err := secrets.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			values := map[string]string{}
+			for key, value := range bindSecret.Data {
+				values[key] = string(value)
+			}
+			bindings[service] = values
+		}
+
+		if err := encodeServicesJSON(secret, bindings); err != nil {
+			return err
+		}
+
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// checkBoundEnv returns an EnvConflictError if a variable of the
+// assignments is set by a binding of the application. Without
+// deployment there are no bindings.
+func checkBoundEnv(ctx context.Context, client k8s.Interface, appRef models.AppRef, assignments models.EnvVariableList) error {
+	deployment, err := client.AppsV1().Deployments(appRef.Org).Get(ctx, appRef.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, ev := range deployment.Spec.Template.Spec.Containers[0].Env {
+		if ev.ValueFrom == nil ||
+			ev.ValueFrom.SecretKeyRef == nil ||
+			ev.ValueFrom.SecretKeyRef.Name == EnvSecret(appRef) {
+			continue
+		}
+		for _, assignment := range assignments {
+			if assignment.Name == ev.Name {
+				return EnvConflictError{Name: ev.Name, Bound: true}
+			}
+		}
+	}
+
+	return nil
+}
+
+// servicesJSON returns the bindings in the JSON of the application,
+// keyed by service name. Without JSON there are none.
+func servicesJSON(ctx context.Context, client k8s.Interface, appRef models.AppRef) (map[string]map[string]string, error) {
+	secret, err := client.CoreV1().Secrets(appRef.Org).Get(ctx, ServicesSecret(appRef), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return map[string]map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeServicesJSON(secret)
+}
+
+func decodeServicesJSON(secret *corev1.Secret) (map[string]map[string]string, error) {
+	bindings := map[string]map[string]string{}
+	if data, ok := secret.Data[ServicesEnvVar]; ok {
+		if err := json.Unmarshal(data, &bindings); err != nil {
+			return nil, err
+		}
+	}
+	return bindings, nil
+}
+
+func encodeServicesJSON(secret *corev1.Secret, bindings map[string]map[string]string) error {
+	data, err := json.Marshal(bindings)
+	if err != nil {
+		return err
+	}
+	secret.Data = map[string][]byte{ServicesEnvVar: data}
+	return nil
+}
@@ -0,0 +1,123 @@
+package application_test
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Binding environment", func() {
+	var (
+		ctx    context.Context
+		client *fake.Clientset
+		appRef models.AppRef
+		owner  metav1.OwnerReference
+	)
+
+	bindings := func() map[string]map[string]string {
+		secret, err := client.CoreV1().Secrets("workspace").Get(ctx, application.ServicesSecret(appRef), metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		result := map[string]map[string]string{}
+		Expect(json.Unmarshal(secret.Data[application.ServicesEnvVar], &result)).To(Succeed())
+		return result
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = fake.NewSimpleClientset()
+		appRef = models.NewAppRef("app", "workspace")
+		owner = metav1.OwnerReference{APIVersion: "app.k8s.io/v1beta1", Kind: "App", Name: "app", UID: "uid"}
+	})
+
+	It("names the variables after the service and the key", func() {
+		Expect(application.BindingEnvName("mydb", "password")).To(Equal("MYDB_PASSWORD"))
+		Expect(application.BindingEnvName("my-db", "db.host-name")).To(Equal("MY_DB_DB_HOST_NAME"))
+	})
+
+	It("keeps the bindings in the JSON, until the last is removed", func() {
+		count, err := application.UpdateServicesJSON(ctx, client, appRef, owner, "mydb",
+			map[string][]byte{"user": []byte("admin"), "password": []byte("secret")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(1))
+
+		count, err = application.UpdateServicesJSON(ctx, client, appRef, owner, "cache",
+			map[string][]byte{"url": []byte("redis://cache")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(2))
+
+		Expect(bindings()).To(Equal(map[string]map[string]string{
+			"mydb":  {"user": "admin", "password": "secret"},
+			"cache": {"url": "redis://cache"},
+		}))
+
+		secret, err := client.CoreV1().Secrets("workspace").Get(ctx, application.ServicesSecret(appRef), metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.OwnerReferences).To(Equal([]metav1.OwnerReference{owner}))
+
+		count, err = application.UpdateServicesJSON(ctx, client, appRef, owner, "mydb", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(1))
+		Expect(bindings()).To(HaveKey("cache"))
+		Expect(bindings()).ToNot(HaveKey("mydb"))
+	})
+
+	It("creates no JSON when removing a binding", func() {
+		count, err := application.UpdateServicesJSON(ctx, client, appRef, owner, "mydb", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(BeZero())
+
+		_, err = client.CoreV1().Secrets("workspace").Get(ctx, application.ServicesSecret(appRef), metav1.GetOptions{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("refreshes the JSON with the current binding secrets", func() {
+		_, err := client.AppsV1().Deployments("workspace").Create(ctx, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "workspace"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Volumes: []corev1.Volume{{
+							Name: "mydb",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{SecretName: "mydb-binding"},
+							},
+						}},
+					},
+				},
+			},
+		}, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = client.CoreV1().Secrets("workspace").Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "mydb-binding", Namespace: "workspace"},
+			Data:       map[string][]byte{"password": []byte("rotated")},
+		}, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = application.UpdateServicesJSON(ctx, client, appRef, owner, "mydb",
+			map[string][]byte{"password": []byte("secret")})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(application.RefreshServicesJSON(ctx, client, appRef)).To(Succeed())
+		Expect(bindings()).To(Equal(map[string]map[string]string{
+			"mydb": {"password": "rotated"},
+		}))
+	})
+
+	It("refreshes nothing without JSON", func() {
+		Expect(application.RefreshServicesJSON(ctx, client, appRef)).To(Succeed())
+
+		_, err := client.CoreV1().Secrets("workspace").Get(ctx, application.ServicesSecret(appRef), metav1.GetOptions{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,19 @@
+package application
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+)
+
+// BuilderImageAnnotation is set on the resource of an application
+// staged with a chosen builder image. Its later stagings use the same
+// builder.
+const BuilderImageAnnotation = "epinio.suse.org/builder-image"
+
+// SetBuilderImage remembers the builder image for the stagings of the
+// application. An empty image goes back to the default builder.
+func SetBuilderImage(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, image string) error {
+	return annotate(ctx, cluster, appRef, BuilderImageAnnotation, image)
+}
@@ -0,0 +1,77 @@
+package application
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/names"
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// CacheSize is the storage requested for the build cache of an application
+const CacheSize = "1Gi"
+
+// CacheName returns the name of the PVC holding the build cache of the
+// application. It lives in the staging namespace, next to the
+// PipelineRuns using it.
+func CacheName(appRef models.AppRef) string {
+	return names.GenerateDNS1123SubDomainName(appRef.Org, appRef.Name, "cache")
+}
+
+// EnsureCache creates the build cache of the application, if it does
+// not exist yet.
+func EnsureCache(ctx context.Context, client k8s.Interface, appRef models.AppRef) error {
+	pvcs := client.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace)
+
+	pvc, err := pvcs.Get(ctx, CacheName(appRef), metav1.GetOptions{})
+	if err == nil {
+		if pvc.DeletionTimestamp != nil {
+			return errors.New("the build cache is still being cleared, try again later")
+		}
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	_, err = pvcs.Create(ctx, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CacheName(appRef),
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       appRef.Name,
+				"app.kubernetes.io/part-of":    appRef.Org,
+				"app.kubernetes.io/managed-by": "epinio",
+				"app.kubernetes.io/component":  "cache",
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse(CacheSize),
+			}},
+		},
+	}, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+
+	return err
+}
+
+// ClearCache removes the build cache of the application. The next
+// staging starts with an empty cache. A missing cache is not an error.
+func ClearCache(ctx context.Context, client k8s.Interface, appRef models.AppRef) error {
+	err := client.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace).
+		Delete(ctx, CacheName(appRef), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
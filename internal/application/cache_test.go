@@ -0,0 +1,52 @@
+package application_test
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		ctx    context.Context
+		client *fake.Clientset
+		appRef models.AppRef
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = fake.NewSimpleClientset()
+		appRef = models.NewAppRef("sample", "workspace")
+	})
+
+	It("creates the cache once", func() {
+		Expect(application.EnsureCache(ctx, client, appRef)).To(Succeed())
+		Expect(application.EnsureCache(ctx, client, appRef)).To(Succeed())
+
+		pvcs, err := client.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace).List(ctx, metav1.ListOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pvcs.Items).To(HaveLen(1))
+		Expect(pvcs.Items[0].Name).To(Equal(application.CacheName(appRef)))
+		Expect(pvcs.Items[0].Labels).To(HaveKeyWithValue("app.kubernetes.io/part-of", "workspace"))
+	})
+
+	It("clears the cache", func() {
+		Expect(application.EnsureCache(ctx, client, appRef)).To(Succeed())
+		Expect(application.ClearCache(ctx, client, appRef)).To(Succeed())
+
+		_, err := client.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace).
+			Get(ctx, application.CacheName(appRef), metav1.GetOptions{})
+		Expect(err).To(HaveOccurred())
+
+		// Clearing a missing cache is fine
+		Expect(application.ClearCache(ctx, client, appRef)).To(Succeed())
+	})
+})
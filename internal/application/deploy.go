@@ -0,0 +1,522 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// DefaultPort is the port the application container listens on
+	DefaultPort = int32(8080)
+)
+
+// DeployParam describes the workload of an application, as it is
+// rendered into the kubernetes resources running it. A Port of zero is
+// the DefaultPort. The HealthChecks become the probes of the
+// application container. The workers of a Stopped application get no
+// instances.
+type DeployParam struct {
+	models.AppRef
+	Owner        metav1.OwnerReference
+	StageID      string
+	Image        string
+	Instances    int32
+	Routes       []string
+	Port         int32
+	Processes    []models.Process
+	Environment  models.EnvVariableList
+	HealthChecks models.HealthChecks
+	Stopped      bool
+}
+
+// Deploy creates the Deployment, Service and Ingress of the
+// application, or updates them when they exist already. Bound
+// services, i.e. volumes of the existing deployment, are kept. The
+// non-web processes get a Deployment each, with the same bound
+// services. Deployments of processes not declared anymore are removed.
+func Deploy(ctx context.Context, client k8s.Interface, param DeployParam) error {
+	if param.Port == 0 {
+		param.Port = DefaultPort
+	}
+
+	if err := deployDeployment(ctx, client, param); err != nil {
+		return err
+	}
+	if err := deployService(ctx, client, param); err != nil {
+		return err
+	}
+	if err := deployIngress(ctx, client, param); err != nil {
+		return err
+	}
+	return deployWorkers(ctx, client, param)
+}
+
+// WorkerName returns the name of the Deployment running the named
+// process of the application
+func WorkerName(appRef models.AppRef, process string) string {
+	return fmt.Sprintf("%s-%s", appRef.Name, process)
+}
+
+// DeployedPort returns the port the deployed application listens on,
+// or zero if the application has no workload.
+func DeployedPort(ctx context.Context, client k8s.Interface, appRef models.AppRef) (int32, error) {
+	deployment, err := client.AppsV1().Deployments(appRef.Org).Get(ctx, appRef.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return deploymentPort(deployment), nil
+}
+
+func deploymentPort(deployment *appsv1.Deployment) int32 {
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) == 0 || len(containers[0].Ports) == 0 {
+		return 0
+	}
+	return containers[0].Ports[0].ContainerPort
+}
+
+func deployDeployment(ctx context.Context, client k8s.Interface, param DeployParam) error {
+	deployments := client.AppsV1().Deployments(param.Org)
+	desired := newDeployment(param)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := deployments.Get(ctx, param.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = deployments.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		mergeDeployment(current, desired, EnvSecret(param.AppRef))
+
+		// The selector of a deployment is immutable. A deployment
+		// made before its selector changed is replaced.
+		if !equality.Semantic.DeepEqual(current.Spec.Selector, desired.Spec.Selector) {
+			return replaceDeployment(ctx, client, current, desired.Spec.Selector)
+		}
+
+		_, err = deployments.Update(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// replaceDeployment deletes the deployment, and creates it again with
+// the selector. The pods of the deployment are replaced as well.
+func replaceDeployment(ctx context.Context, client k8s.Interface, deployment *appsv1.Deployment, selector *metav1.LabelSelector) error {
+	deployments := client.AppsV1().Deployments(deployment.Namespace)
+
+	err := deployments.Delete(ctx, deployment.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &deployment.UID},
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	replacement := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            deployment.Name,
+			Namespace:       deployment.Namespace,
+			Labels:          deployment.Labels,
+			Annotations:     deployment.Annotations,
+			OwnerReferences: deployment.OwnerReferences,
+		},
+		Spec: deployment.Spec,
+	}
+	replacement.Spec.Selector = selector
+
+	_, err = deployments.Create(ctx, replacement, metav1.CreateOptions{})
+	return err
+}
+
+func deployWorkers(ctx context.Context, client k8s.Interface, param DeployParam) error {
+	deployments := client.AppsV1().Deployments(param.Org)
+
+	// New workers get the services bound to the application, and its
+	// resources
+	web, err := deployments.Get(ctx, param.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	declared := map[string]bool{}
+	for _, process := range param.Processes {
+		desired := newWorker(param, process)
+		declared[desired.Name] = true
+
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			current, err := deployments.Get(ctx, desired.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				keepSettings(&web.Spec.Template.Spec, &desired.Spec.Template.Spec, EnvSecret(param.AppRef))
+				_, err = deployments.Create(ctx, desired, metav1.CreateOptions{})
+				return err
+			}
+			if err != nil {
+				return err
+			}
+
+			// The name may be taken by the workload of another application
+			if current.Labels["app.kubernetes.io/name"] != param.Name ||
+				current.Labels[models.EpinioProcessLabel] != process.Name {
+				return fmt.Errorf("deployment %s exists, and is not the %s process of the application", desired.Name, process.Name)
+			}
+
+			mergeDeployment(current, desired, EnvSecret(param.AppRef))
+
+			_, err = deployments.Update(ctx, current, metav1.UpdateOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	existing, err := deployments.List(ctx, metav1.ListOptions{
+		LabelSelector: workerSelector(param.AppRef),
+	})
+	if err != nil {
+		return err
+	}
+	for _, deployment := range existing.Items {
+		if declared[deployment.Name] {
+			continue
+		}
+		err := deployments.Delete(ctx, deployment.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deployService(ctx context.Context, client k8s.Interface, param DeployParam) error {
+	services := client.CoreV1().Services(param.Org)
+	desired := newService(param)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := services.Get(ctx, param.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = services.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		// The cluster IP is assigned by kubernetes and immutable, keep it.
+		current.Labels = desired.Labels
+		current.Annotations = desired.Annotations
+		current.OwnerReferences = desired.OwnerReferences
+		current.Spec.Ports = desired.Spec.Ports
+		current.Spec.Selector = desired.Spec.Selector
+
+		_, err = services.Update(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func deployIngress(ctx context.Context, client k8s.Interface, param DeployParam) error {
+	ingresses := client.NetworkingV1().Ingresses(param.Org)
+	desired := newIngress(param)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := ingresses.Get(ctx, param.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = ingresses.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		current.Labels = desired.Labels
+		current.Annotations = desired.Annotations
+		current.OwnerReferences = desired.OwnerReferences
+		current.Spec = desired.Spec
+
+		_, err = ingresses.Update(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// mergeDeployment changes the current deployment to match the desired
+// one. Volumes, mounts and environment variables not rendered by
+// Deploy are kept, as they come from bound services. The resources of
+// the container are kept as well.
+func mergeDeployment(current, desired *appsv1.Deployment, envSecretName string) {
+	current.Labels = desired.Labels
+	current.OwnerReferences = desired.OwnerReferences
+	current.Spec.Replicas = desired.Spec.Replicas
+
+	if current.Spec.Template.Annotations == nil {
+		current.Spec.Template.Annotations = map[string]string{}
+	}
+	for key, value := range desired.Spec.Template.Annotations {
+		current.Spec.Template.Annotations[key] = value
+	}
+	current.Spec.Template.Labels = desired.Spec.Template.Labels
+
+	spec := desired.Spec.Template.Spec
+	keepSettings(&current.Spec.Template.Spec, &spec, envSecretName)
+	current.Spec.Template.Spec = spec
+}
+
+// keepSettings copies the volumes, mounts and environment variables of
+// the bound services, and the resources of the container, from a pod
+// spec into the pod spec rendered by Deploy. A variable rendered by
+// Deploy, e.g. restored with a rollback, is not duplicated.
+func keepSettings(from, into *corev1.PodSpec, envSecretName string) {
+	into.Volumes = from.Volumes
+	if len(from.Containers) == 0 {
+		return
+	}
+
+	container := &into.Containers[0]
+	container.VolumeMounts = from.Containers[0].VolumeMounts
+	container.Resources = from.Containers[0].Resources
+
+	rendered := map[string]bool{}
+	for _, ev := range container.Env {
+		rendered[ev.Name] = true
+	}
+	for _, ev := range from.Containers[0].Env {
+		if ev.Name == "PORT" || rendered[ev.Name] {
+			continue
+		}
+		if ev.ValueFrom != nil &&
+			ev.ValueFrom.SecretKeyRef != nil &&
+			ev.ValueFrom.SecretKeyRef.Name == envSecretName {
+			continue
+		}
+		container.Env = append(container.Env, ev)
+	}
+}
+
+func newDeployment(param DeployParam) *appsv1.Deployment {
+	automountServiceAccountToken := false
+	replicas := param.Instances
+
+	environment := append([]corev1.EnvVar{
+		{Name: "PORT", Value: strconv.Itoa(int(param.Port))},
+	}, envSecretRefs(param)...)
+
+	templateLabels := appLabels(param.AppRef)
+	templateLabels[models.EpinioStageIDLabel] = param.StageID
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            param.Name,
+			Namespace:       param.Org,
+			Labels:          appLabels(param.AppRef),
+			OwnerReferences: []metav1.OwnerReference{param.Owner},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/name":      param.Name,
+					"app.kubernetes.io/component": "application",
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: templateLabels,
+					Annotations: map[string]string{
+						"app.kubernetes.io/name": param.Name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName:           param.Org,
+					AutomountServiceAccountToken: &automountServiceAccountToken,
+					Containers: []corev1.Container{
+						{
+							Name:  param.Name,
+							Image: param.Image,
+							Ports: []corev1.ContainerPort{
+								{ContainerPort: param.Port},
+							},
+							Env:            environment,
+							LivenessProbe:  probe(param.HealthChecks.Liveness, param.Port),
+							ReadinessProbe: probe(param.HealthChecks.Readiness, param.Port),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// newWorker returns the Deployment running a non-web process of the
+// application. It has the environment of the application, but no
+// port.
+func newWorker(param DeployParam, process models.Process) *appsv1.Deployment {
+	automountServiceAccountToken := false
+
+	replicas := int32(1)
+	if process.Instances != nil {
+		replicas = *process.Instances
+	}
+	if param.Stopped {
+		replicas = 0
+	}
+
+	command := process.Command
+	if len(command) == 0 {
+		// The launcher of the process type in a buildpacks image
+		command = []string{"/cnb/process/" + process.Name}
+	}
+
+	labels := workerLabels(param.AppRef, process.Name)
+	templateLabels := workerLabels(param.AppRef, process.Name)
+	templateLabels[models.EpinioStageIDLabel] = param.StageID
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            WorkerName(param.AppRef, process.Name),
+			Namespace:       param.Org,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{param.Owner},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/name":  param.Name,
+					models.EpinioProcessLabel: process.Name,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: templateLabels,
+					Annotations: map[string]string{
+						"app.kubernetes.io/name": param.Name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName:           param.Org,
+					AutomountServiceAccountToken: &automountServiceAccountToken,
+					Containers: []corev1.Container{
+						{
+							Name:    process.Name,
+							Image:   param.Image,
+							Command: command,
+							Env:     envSecretRefs(param),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// envSecretRefs returns the environment variables of the application,
+// as references into its environment secret
+func envSecretRefs(param DeployParam) []corev1.EnvVar {
+	environment := []corev1.EnvVar{}
+	for _, ev := range param.Environment {
+		environment = append(environment, corev1.EnvVar{
+			Name: ev.Name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: EnvSecret(param.AppRef),
+					},
+					Key: ev.Name,
+				},
+			},
+		})
+	}
+	return environment
+}
+
+func newService(param DeployParam) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            param.Name,
+			Namespace:       param.Org,
+			Labels:          appLabels(param.AppRef),
+			Annotations:     traefikAnnotations(),
+			OwnerReferences: []metav1.OwnerReference{param.Owner},
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{
+				{
+					Port:       param.Port,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt(int(param.Port)),
+				},
+			},
+			Selector: map[string]string{
+				"app.kubernetes.io/component": "application",
+				"app.kubernetes.io/name":      param.Name,
+			},
+		},
+	}
+}
+
+func newIngress(param DeployParam) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            param.Name,
+			Namespace:       param.Org,
+			Labels:          appLabels(param.AppRef),
+			Annotations:     traefikAnnotations(),
+			OwnerReferences: []metav1.OwnerReference{param.Owner},
+		},
+		Spec: ingressSpec(param.AppRef, param.Port, param.Routes),
+	}
+}
+
+// appLabels returns the labels identifying the kubernetes resources
+// of an application
+func appLabels(app models.AppRef) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       app.Name,
+		"app.kubernetes.io/part-of":    app.Org,
+		"app.kubernetes.io/component":  "application",
+		"app.kubernetes.io/managed-by": "epinio",
+	}
+}
+
+// workerLabels returns the labels identifying the kubernetes resources
+// of a non-web process of an application
+func workerLabels(app models.AppRef, process string) map[string]string {
+	labels := appLabels(app)
+	labels["app.kubernetes.io/component"] = "worker"
+	labels[models.EpinioProcessLabel] = process
+	return labels
+}
+
+// workerSelector returns the label selector for the Deployments of the
+// non-web processes of an application
+func workerSelector(app models.AppRef) string {
+	return fmt.Sprintf("app.kubernetes.io/component=worker,app.kubernetes.io/part-of=%s,app.kubernetes.io/name=%s",
+		app.Org, app.Name)
+}
+
+func traefikAnnotations() map[string]string {
+	return map[string]string{
+		"kubernetes.io/ingress.class":                      "traefik",
+		"traefik.ingress.kubernetes.io/router.entrypoints": "websecure",
+		"traefik.ingress.kubernetes.io/router.tls":         "true",
+	}
+}
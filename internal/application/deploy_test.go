@@ -0,0 +1,316 @@
+package application_test
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Deploy", func() {
+	var (
+		ctx    context.Context
+		client *fake.Clientset
+		param  application.DeployParam
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = fake.NewSimpleClientset()
+		param = application.DeployParam{
+			AppRef: models.NewAppRef("sample", "workspace"),
+			Owner: metav1.OwnerReference{
+				APIVersion: "app.k8s.io/v1beta1",
+				Kind:       "App",
+				Name:       "sample",
+				UID:        "1234",
+			},
+			StageID:   "stage1",
+			Image:     "registry.example.com/apps/sample-abc",
+			Instances: 2,
+			Routes:    []string{"sample.example.com"},
+			Environment: models.EnvVariableList{
+				{Name: "MODE", Value: "production"},
+			},
+		}
+	})
+
+	It("creates the deployment, service and ingress", func() {
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployment, err := client.AppsV1().Deployments("workspace").Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+		Expect(deployment.Labels).To(HaveKeyWithValue("app.kubernetes.io/component", "application"))
+		Expect(deployment.Labels).To(HaveKeyWithValue("app.kubernetes.io/part-of", "workspace"))
+		Expect(deployment.OwnerReferences).To(ConsistOf(param.Owner))
+		Expect(deployment.Spec.Template.Labels).To(HaveKeyWithValue(models.EpinioStageIDLabel, "stage1"))
+		Expect(deployment.Spec.Template.Spec.ServiceAccountName).To(Equal("workspace"))
+
+		container := deployment.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal(param.Image))
+		Expect(container.Ports[0].ContainerPort).To(Equal(application.DefaultPort))
+		Expect(container.Env).To(HaveLen(2))
+		Expect(container.Env[0]).To(Equal(corev1.EnvVar{Name: "PORT", Value: "8080"}))
+		Expect(container.Env[1].ValueFrom.SecretKeyRef.Name).To(Equal("sample-env"))
+		Expect(container.Env[1].ValueFrom.SecretKeyRef.Key).To(Equal("MODE"))
+
+		service, err := client.CoreV1().Services("workspace").Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(service.Spec.Ports[0].Port).To(Equal(application.DefaultPort))
+		Expect(service.Spec.Selector).To(HaveKeyWithValue("app.kubernetes.io/name", "sample"))
+
+		ingress, err := client.NetworkingV1().Ingresses("workspace").Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ingress.Spec.Rules[0].Host).To(Equal("sample.example.com"))
+		Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name).To(Equal("sample"))
+		Expect(ingress.Spec.TLS[0].Hosts).To(ConsistOf("sample.example.com"))
+		Expect(ingress.Spec.TLS[0].SecretName).To(Equal("sample.sample.example.com-tls"))
+	})
+
+	It("updates existing resources and keeps bound services and limits", func() {
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployments := client.AppsV1().Deployments("workspace")
+		deployment, err := deployments.Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		deployment.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "mydb"}}
+		deployment.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+			{Name: "mydb", MountPath: "/services/mydb"},
+		}
+		deployment.Spec.Template.Spec.Containers[0].Resources.Limits = corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("256Mi"),
+		}
+		_, err = deployments.Update(ctx, deployment, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		param.StageID = "stage2"
+		param.Image = "registry.example.com/apps/sample-def"
+		param.Instances = 1
+		param.Routes = []string{"other.example.com"}
+		param.Environment = models.EnvVariableList{}
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployment, err = deployments.Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+		Expect(deployment.Spec.Template.Labels).To(HaveKeyWithValue(models.EpinioStageIDLabel, "stage2"))
+		Expect(deployment.Spec.Template.Spec.Volumes).To(HaveLen(1))
+
+		container := deployment.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal(param.Image))
+		Expect(container.VolumeMounts).To(HaveLen(1))
+		Expect(container.Env).To(ConsistOf(corev1.EnvVar{Name: "PORT", Value: "8080"}))
+		Expect(container.Resources.Limits).To(HaveKeyWithValue(corev1.ResourceMemory, resource.MustParse("256Mi")))
+
+		ingress, err := client.NetworkingV1().Ingresses("workspace").Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ingress.Spec.Rules).To(HaveLen(1))
+		Expect(ingress.Spec.Rules[0].Host).To(Equal("other.example.com"))
+	})
+
+	It("uses the port of the application", func() {
+		param.Port = 3000
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployment, err := client.AppsV1().Deployments("workspace").Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		container := deployment.Spec.Template.Spec.Containers[0]
+		Expect(container.Ports[0].ContainerPort).To(Equal(int32(3000)))
+		Expect(container.Env[0]).To(Equal(corev1.EnvVar{Name: "PORT", Value: "3000"}))
+
+		service, err := client.CoreV1().Services("workspace").Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(service.Spec.Ports[0].Port).To(Equal(int32(3000)))
+		Expect(service.Spec.Ports[0].TargetPort.IntValue()).To(Equal(3000))
+
+		ingress, err := client.NetworkingV1().Ingresses("workspace").Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Port.Number).To(Equal(int32(3000)))
+
+		port, err := application.DeployedPort(ctx, client, param.AppRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(port).To(Equal(int32(3000)))
+	})
+
+	It("renders the health checks into probes", func() {
+		param.Port = 3000
+		param.HealthChecks = models.HealthChecks{
+			Liveness: &models.HealthCheck{
+				Type:             models.HealthCheckHTTP,
+				Path:             "/healthz",
+				InitialDelay:     5,
+				FailureThreshold: 3,
+			},
+			Readiness: &models.HealthCheck{
+				Type: models.HealthCheckTCP,
+				Port: 9000,
+			},
+		}
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployment, err := client.AppsV1().Deployments("workspace").Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		container := deployment.Spec.Template.Spec.Containers[0]
+		Expect(container.LivenessProbe.HTTPGet.Path).To(Equal("/healthz"))
+		Expect(container.LivenessProbe.HTTPGet.Port.IntValue()).To(Equal(3000))
+		Expect(container.LivenessProbe.InitialDelaySeconds).To(Equal(int32(5)))
+		Expect(container.LivenessProbe.FailureThreshold).To(Equal(int32(3)))
+		Expect(container.ReadinessProbe.TCPSocket.Port.IntValue()).To(Equal(9000))
+
+		checks, err := application.DeployedHealthChecks(ctx, client, param.AppRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(checks).To(Equal(param.HealthChecks))
+
+		By("removing a check")
+		param.HealthChecks.Readiness = &models.HealthCheck{Type: models.HealthCheckNone}
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		checks, err = application.DeployedHealthChecks(ctx, client, param.AppRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(checks.Liveness).ToNot(BeNil())
+		Expect(checks.Readiness).To(BeNil())
+	})
+
+	It("deploys the workers, and removes those not declared anymore", func() {
+		three := int32(3)
+		param.Processes = []models.Process{
+			{Name: "worker", Instances: &three},
+			{Name: "mailer", Command: []string{"bin/mailer", "--queue=mail"}},
+		}
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployments := client.AppsV1().Deployments("workspace")
+		worker, err := deployments.Get(ctx, "sample-worker", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*worker.Spec.Replicas).To(Equal(int32(3)))
+		Expect(worker.Labels).To(HaveKeyWithValue("app.kubernetes.io/component", "worker"))
+		Expect(worker.Labels).To(HaveKeyWithValue(models.EpinioProcessLabel, "worker"))
+		Expect(worker.OwnerReferences).To(ConsistOf(param.Owner))
+		Expect(worker.Spec.Template.Labels).To(HaveKeyWithValue(models.EpinioStageIDLabel, "stage1"))
+
+		container := worker.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal(param.Image))
+		Expect(container.Command).To(Equal([]string{"/cnb/process/worker"}))
+		Expect(container.Ports).To(BeEmpty())
+		Expect(container.Env).To(HaveLen(1))
+		Expect(container.Env[0].ValueFrom.SecretKeyRef.Key).To(Equal("MODE"))
+
+		mailer, err := deployments.Get(ctx, "sample-mailer", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*mailer.Spec.Replicas).To(Equal(int32(1)))
+		Expect(mailer.Spec.Template.Spec.Containers[0].Command).To(Equal([]string{"bin/mailer", "--queue=mail"}))
+
+		services, err := client.CoreV1().Services("workspace").List(ctx, metav1.ListOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(services.Items).To(HaveLen(1))
+
+		param.Processes = param.Processes[1:]
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		_, err = deployments.Get(ctx, "sample-worker", metav1.GetOptions{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		_, err = deployments.Get(ctx, "sample-mailer", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = deployments.Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("selects only the instances of the application, not those of its workers", func() {
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployment, err := client.AppsV1().Deployments("workspace").Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deployment.Spec.Selector.MatchLabels).To(HaveKeyWithValue("app.kubernetes.io/component", "application"))
+	})
+
+	It("replaces a deployment with the selector of earlier versions", func() {
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployments := client.AppsV1().Deployments("workspace")
+		deployment, err := deployments.Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		deployment.Spec.Selector.MatchLabels = map[string]string{"app.kubernetes.io/name": "sample"}
+		deployment.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "mydb"}}
+		_, err = deployments.Update(ctx, deployment, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployment, err = deployments.Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deployment.Spec.Selector.MatchLabels).To(HaveKeyWithValue("app.kubernetes.io/component", "application"))
+		Expect(deployment.Spec.Template.Spec.Volumes).To(HaveLen(1))
+	})
+
+	It("gives the workers the services bound to the application", func() {
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployments := client.AppsV1().Deployments("workspace")
+		deployment, err := deployments.Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		deployment.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "mydb"}}
+		deployment.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+			{Name: "mydb", MountPath: "/services/mydb"},
+		}
+		_, err = deployments.Update(ctx, deployment, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		By("creating a worker")
+		param.Processes = []models.Process{{Name: "worker"}}
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		worker, err := deployments.Get(ctx, "sample-worker", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(worker.Spec.Template.Spec.Volumes).To(HaveLen(1))
+		Expect(worker.Spec.Template.Spec.Containers[0].VolumeMounts).To(HaveLen(1))
+		Expect(worker.Spec.Template.Spec.Containers[0].Env).To(HaveLen(1))
+
+		By("updating the worker")
+		param.StageID = "stage2"
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		worker, err = deployments.Get(ctx, "sample-worker", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(worker.Spec.Template.Labels).To(HaveKeyWithValue(models.EpinioStageIDLabel, "stage2"))
+		Expect(worker.Spec.Template.Spec.Volumes).To(HaveLen(1))
+		Expect(worker.Spec.Template.Spec.Containers[0].VolumeMounts).To(HaveLen(1))
+	})
+
+	It("does not duplicate a variable of the application set by a binding", func() {
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployments := client.AppsV1().Deployments("workspace")
+		deployment, err := deployments.Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		deployment.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{
+			{Name: "PORT", Value: "8080"},
+			{Name: "MODE", ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "mydb-binding"},
+					Key:                  "mode",
+				},
+			}},
+		}
+		_, err = deployments.Update(ctx, deployment, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+
+		deployment, err = deployments.Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		env := deployment.Spec.Template.Spec.Containers[0].Env
+		Expect(env).To(HaveLen(2))
+		Expect(env[1].Name).To(Equal("MODE"))
+		Expect(env[1].ValueFrom.SecretKeyRef.Name).To(Equal("sample-env"))
+	})
+})
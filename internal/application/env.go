@@ -28,7 +28,14 @@ func Environment(ctx context.Context, cluster *kubernetes.Cluster, appRef models
 	return result, nil
 }
 
+// EnvironmentSet sets the variables in the environment of the
+// application. A variable set by a service binding is an
+// EnvConflictError.
 func EnvironmentSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, assignments models.EnvVariableList) error {
+	if err := checkBoundEnv(ctx, cluster.Kubectl, appRef, assignments); err != nil {
+		return err
+	}
+
 	return envUpdate(ctx, cluster, appRef, func(evSecret *v1.Secret) {
 		for _, ev := range assignments {
 			evSecret.Data[ev.Name] = []byte(ev.Value)
@@ -42,6 +49,28 @@ func EnvironmentUnset(ctx context.Context, cluster *kubernetes.Cluster, appRef m
 	})
 }
 
+// EnvironmentRestore replaces the environment of the application with
+// the given one, e.g. of a past release. Unlike EnvironmentSet it does
+// not restart the workload, as that is deployed with the environment
+// right after.
+func EnvironmentRestore(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, environment models.EnvVariableList) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		evSecret, err := envLoad(ctx, cluster, appRef)
+		if err != nil {
+			return err
+		}
+
+		evSecret.Data = make(map[string][]byte)
+		for _, ev := range environment {
+			evSecret.Data[ev.Name] = []byte(ev.Value)
+		}
+
+		_, err = cluster.Kubectl.CoreV1().Secrets(appRef.Org).Update(
+			ctx, evSecret, metav1.UpdateOptions{})
+		return err
+	})
+}
+
 func envNames(ev *v1.Secret) []string {
 	names := make([]string, len(ev.Data))
 	i := 0
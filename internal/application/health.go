@@ -0,0 +1,119 @@
+package application
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// DeployedHealthChecks returns the health checks of the deployed
+// application. They are empty if the application has no workload.
+func DeployedHealthChecks(ctx context.Context, client k8s.Interface, appRef models.AppRef) (models.HealthChecks, error) {
+	deployment, err := client.AppsV1().Deployments(appRef.Org).Get(ctx, appRef.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return models.HealthChecks{}, nil
+		}
+		return models.HealthChecks{}, err
+	}
+	return deploymentHealthChecks(deployment), nil
+}
+
+func deploymentHealthChecks(deployment *appsv1.Deployment) models.HealthChecks {
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return models.HealthChecks{}
+	}
+
+	port := deploymentPort(deployment)
+	return models.HealthChecks{
+		Liveness:  healthCheck(containers[0].LivenessProbe, port),
+		Readiness: healthCheck(containers[0].ReadinessProbe, port),
+	}
+}
+
+// probe renders the health check into the probe of the application
+// container, listening on port. There is no probe without a check.
+func probe(check *models.HealthCheck, port int32) *corev1.Probe {
+	if check == nil || check.Type == models.HealthCheckNone {
+		return nil
+	}
+
+	if check.Port != 0 {
+		port = check.Port
+	}
+
+	result := &corev1.Probe{
+		InitialDelaySeconds: check.InitialDelay,
+		PeriodSeconds:       check.Period,
+		TimeoutSeconds:      check.Timeout,
+		FailureThreshold:    check.FailureThreshold,
+	}
+
+	switch check.Type {
+	case models.HealthCheckHTTP:
+		path := check.Path
+		if path == "" {
+			path = "/"
+		}
+		result.HTTPGet = &corev1.HTTPGetAction{
+			Path: path,
+			Port: intstr.FromInt(int(port)),
+		}
+	case models.HealthCheckTCP:
+		result.TCPSocket = &corev1.TCPSocketAction{
+			Port: intstr.FromInt(int(port)),
+		}
+	case models.HealthCheckExec:
+		result.Exec = &corev1.ExecAction{
+			Command: check.Command,
+		}
+	}
+
+	return result
+}
+
+// healthCheck converts a probe of the application container back into
+// a health check. A probe on the port of the application is a check
+// without explicit port, so that it follows changes of that port.
+func healthCheck(probe *corev1.Probe, port int32) *models.HealthCheck {
+	if probe == nil {
+		return nil
+	}
+
+	check := &models.HealthCheck{
+		InitialDelay:     probe.InitialDelaySeconds,
+		Period:           probe.PeriodSeconds,
+		Timeout:          probe.TimeoutSeconds,
+		FailureThreshold: probe.FailureThreshold,
+	}
+
+	var probePort intstr.IntOrString
+	switch {
+	case probe.HTTPGet != nil:
+		check.Type = models.HealthCheckHTTP
+		check.Path = probe.HTTPGet.Path
+		probePort = probe.HTTPGet.Port
+	case probe.TCPSocket != nil:
+		check.Type = models.HealthCheckTCP
+		probePort = probe.TCPSocket.Port
+	case probe.Exec != nil:
+		check.Type = models.HealthCheckExec
+		check.Command = probe.Exec.Command
+	default:
+		return nil
+	}
+
+	if probePort.IntVal != port {
+		check.Port = probePort.IntVal
+	}
+
+	return check
+}
@@ -0,0 +1,89 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// Instances returns the instances of the application, of the web
+// process and of the workers, with the recent events of each.
+func Instances(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.AppInstanceList, error) {
+	instances, err := PodInstances(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range instances {
+		events, err := cluster.GetPodEvents(ctx, appRef.Org, instances[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		if events != "" {
+			instances[i].Events = strings.Split(events, "\n")
+		}
+	}
+
+	return instances, nil
+}
+
+// PodInstances returns the instances of the application, of the web
+// process and of the workers, as found in their pods
+func PodInstances(ctx context.Context, client k8s.Interface, appRef models.AppRef) (models.AppInstanceList, error) {
+	pods, err := client.CoreV1().Pods(appRef.Org).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/part-of=%s,app.kubernetes.io/name=%s", appRef.Org, appRef.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instances := models.AppInstanceList{}
+	for _, pod := range pods.Items {
+		instances = append(instances, podInstance(pod))
+	}
+	sort.Sort(instances)
+
+	return instances, nil
+}
+
+// podInstance describes the pod as instance of the application. The
+// state of the instance is that of the first container, the one
+// running the application.
+func podInstance(pod corev1.Pod) models.AppInstance {
+	instance := models.AppInstance{
+		Name:    pod.Name,
+		Worker:  pod.Labels[models.EpinioProcessLabel],
+		Phase:   string(pod.Status.Phase),
+		Node:    pod.Spec.NodeName,
+		Created: pod.CreationTimestamp.Time,
+	}
+
+	if len(pod.Status.ContainerStatuses) == 0 {
+		instance.Reason = pod.Status.Reason
+		return instance
+	}
+
+	status := pod.Status.ContainerStatuses[0]
+	instance.Ready = status.Ready
+	instance.Restarts = status.RestartCount
+
+	switch {
+	case status.State.Waiting != nil:
+		instance.Reason = status.State.Waiting.Reason
+	case status.State.Terminated != nil:
+		instance.Reason = status.State.Terminated.Reason
+	}
+	if status.LastTerminationState.Terminated != nil {
+		instance.LastTermination = status.LastTerminationState.Terminated.Reason
+	}
+
+	return instance
+}
@@ -0,0 +1,102 @@
+package application_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Instances", func() {
+	var (
+		ctx     context.Context
+		created time.Time
+	)
+
+	pod := func(name, app, worker string, age time.Duration, status corev1.PodStatus) *corev1.Pod {
+		labels := map[string]string{
+			"app.kubernetes.io/name":    app,
+			"app.kubernetes.io/part-of": "workspace",
+		}
+		if worker != "" {
+			labels[models.EpinioProcessLabel] = worker
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "workspace",
+				Labels:            labels,
+				CreationTimestamp: metav1.NewTime(created.Add(-age)),
+			},
+			Spec:   corev1.PodSpec{NodeName: "node1"},
+			Status: status,
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		created = time.Now().Truncate(time.Second)
+	})
+
+	It("describes the pods of the application and its workers", func() {
+		crashing := corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				RestartCount: 4,
+				State: corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+				},
+				LastTerminationState: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"},
+				},
+			}},
+		}
+		running := corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Ready: true,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}},
+		}
+
+		client := fake.NewSimpleClientset(
+			pod("sample-worker-1", "sample", "worker", time.Minute, running),
+			pod("sample-2", "sample", "", time.Minute, crashing),
+			pod("sample-1", "sample", "", time.Hour, running),
+			pod("other-1", "other", "", time.Hour, running),
+		)
+
+		instances, err := application.PodInstances(ctx, client, models.NewAppRef("sample", "workspace"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instances).To(HaveLen(3))
+
+		Expect(instances[0]).To(Equal(models.AppInstance{
+			Name:    "sample-1",
+			Phase:   "Running",
+			Ready:   true,
+			Node:    "node1",
+			Created: created.Add(-time.Hour),
+		}))
+		Expect(instances[1].Name).To(Equal("sample-2"))
+		Expect(instances[1].Ready).To(BeFalse())
+		Expect(instances[1].Restarts).To(Equal(int32(4)))
+		Expect(instances[1].Reason).To(Equal("CrashLoopBackOff"))
+		Expect(instances[1].LastTermination).To(Equal("OOMKilled"))
+		Expect(instances[2].Name).To(Equal("sample-worker-1"))
+		Expect(instances[2].Worker).To(Equal("worker"))
+	})
+
+	It("has no instances without workload", func() {
+		instances, err := application.PodInstances(ctx, fake.NewSimpleClientset(), models.NewAppRef("sample", "workspace"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instances).To(BeEmpty())
+	})
+})
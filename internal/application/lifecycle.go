@@ -0,0 +1,215 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// RestartedAtAnnotation is set on the pod template of the
+	// deployments of an application to restart its instances
+	RestartedAtAnnotation = "epinio.suse.org/restarted-at"
+	// StoppedInstancesAnnotation is set on the resource of a stopped
+	// application. It records the instances of each deployment of the
+	// application before the stop, for the start.
+	StoppedInstancesAnnotation = "epinio.suse.org/stopped-instances"
+)
+
+// Restart replaces the instances of the application and of its
+// workers, one after the other, like a new release would.
+func Restart(ctx context.Context, client k8s.Interface, appRef models.AppRef) error {
+	restartedAt := time.Now().Format(time.RFC3339)
+
+	return updateDeployments(ctx, client, appRef, func(deployment *appsv1.Deployment) {
+		if deployment.Spec.Template.Annotations == nil {
+			deployment.Spec.Template.Annotations = map[string]string{}
+		}
+		deployment.Spec.Template.Annotations[RestartedAtAnnotation] = restartedAt
+	})
+}
+
+// Stopped returns true if the application is stopped
+func Stopped(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (bool, error) {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return false, err
+	}
+	_, ok := app.GetAnnotations()[StoppedInstancesAnnotation]
+	return ok, nil
+}
+
+// Stop scales the application and its workers to zero. The instances
+// they had are remembered in the application resource first, so that
+// an application failing to stop part way is started again properly.
+func Stop(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) error {
+	instances, err := Replicas(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return err
+	}
+
+	recorded, err := json.Marshal(instances)
+	if err != nil {
+		return err
+	}
+
+	err = annotate(ctx, cluster, appRef, StoppedInstancesAnnotation, string(recorded))
+	if err != nil {
+		return err
+	}
+
+	_, err = ScaleToZero(ctx, cluster.Kubectl, appRef)
+	return err
+}
+
+// Start scales the stopped application and its workers back to the
+// instances they had before the stop.
+func Start(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) error {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return err
+	}
+
+	instances := map[string]int32{}
+	if recorded, ok := app.GetAnnotations()[StoppedInstancesAnnotation]; ok {
+		if err := json.Unmarshal([]byte(recorded), &instances); err != nil {
+			return err
+		}
+	}
+
+	err = ScaleTo(ctx, cluster.Kubectl, appRef, instances)
+	if err != nil {
+		return err
+	}
+
+	return annotate(ctx, cluster, appRef, StoppedInstancesAnnotation, "")
+}
+
+// Replicas returns the instances each of the deployments of the
+// application and of its workers has, as ScaleToZero does, without
+// changing them.
+func Replicas(ctx context.Context, client k8s.Interface, appRef models.AppRef) (map[string]int32, error) {
+	list, err := client.AppsV1().Deployments(appRef.Org).List(ctx, metav1.ListOptions{
+		LabelSelector: deploymentsSelector(appRef),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instances := map[string]int32{}
+	for _, deployment := range list.Items {
+		if deployment.Spec.Replicas != nil {
+			instances[deployment.Name] = *deployment.Spec.Replicas
+		}
+	}
+
+	return instances, nil
+}
+
+// ScaleToZero removes all instances of the application and of its
+// workers. It returns the instances each of their deployments had.
+func ScaleToZero(ctx context.Context, client k8s.Interface, appRef models.AppRef) (map[string]int32, error) {
+	instances := map[string]int32{}
+	zero := int32(0)
+
+	err := updateDeployments(ctx, client, appRef, func(deployment *appsv1.Deployment) {
+		if deployment.Spec.Replicas != nil {
+			instances[deployment.Name] = *deployment.Spec.Replicas
+		}
+		deployment.Spec.Replicas = &zero
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+// ScaleTo sets the instances of the deployments of the application and
+// of its workers, as returned by ScaleToZero. Deployments without
+// recorded instances get one.
+func ScaleTo(ctx context.Context, client k8s.Interface, appRef models.AppRef, instances map[string]int32) error {
+	return updateDeployments(ctx, client, appRef, func(deployment *appsv1.Deployment) {
+		replicas, ok := instances[deployment.Name]
+		if !ok {
+			replicas = 1
+		}
+		deployment.Spec.Replicas = &replicas
+	})
+}
+
+// updateDeployments applies the change to the deployments of the
+// application and of its workers
+func updateDeployments(ctx context.Context, client k8s.Interface, appRef models.AppRef, change func(*appsv1.Deployment)) error {
+	deployments := client.AppsV1().Deployments(appRef.Org)
+
+	list, err := deployments.List(ctx, metav1.ListOptions{
+		LabelSelector: deploymentsSelector(appRef),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range list.Items {
+		name := item.Name
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			deployment, err := deployments.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			change(deployment)
+
+			_, err = deployments.Update(ctx, deployment, metav1.UpdateOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deploymentsSelector selects the deployments of the application and of
+// its workers
+func deploymentsSelector(appRef models.AppRef) string {
+	return fmt.Sprintf("app.kubernetes.io/part-of=%s,app.kubernetes.io/name=%s", appRef.Org, appRef.Name)
+}
+
+// annotate sets the annotation of the application resource to the
+// value. An empty value removes the annotation.
+func annotate(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, annotation, value string) error {
+	client, err := cluster.ClientApp()
+	if err != nil {
+		return err
+	}
+
+	var patchValue interface{}
+	if value != "" {
+		patchValue = value
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				annotation: patchValue,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Namespace(appRef.Org).Patch(ctx, appRef.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,80 @@
+package application_test
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Lifecycle", func() {
+	var (
+		ctx    context.Context
+		client *fake.Clientset
+		param  application.DeployParam
+	)
+
+	replicas := func(name string) int32 {
+		deployment, err := client.AppsV1().Deployments("workspace").Get(ctx, name, metav1.GetOptions{})
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return *deployment.Spec.Replicas
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = fake.NewSimpleClientset()
+		three := int32(3)
+		param = application.DeployParam{
+			AppRef:    models.NewAppRef("sample", "workspace"),
+			StageID:   "stage1",
+			Image:     "registry.example.com/apps/sample-abc",
+			Instances: 2,
+			Routes:    []string{"sample.example.com"},
+			Processes: []models.Process{{Name: "worker", Instances: &three}},
+		}
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+	})
+
+	It("restarts the application and its workers", func() {
+		Expect(application.Restart(ctx, client, param.AppRef)).To(Succeed())
+
+		for _, name := range []string{"sample", "sample-worker"} {
+			deployment, err := client.AppsV1().Deployments("workspace").Get(ctx, name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployment.Spec.Template.Annotations).To(HaveKey(application.RestartedAtAnnotation))
+		}
+	})
+
+	It("returns the instances of the application and its workers", func() {
+		instances, err := application.Replicas(ctx, client, param.AppRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instances).To(Equal(map[string]int32{"sample": 2, "sample-worker": 3}))
+		Expect(replicas("sample")).To(Equal(int32(2)))
+	})
+
+	It("scales the application and its workers to zero, and back", func() {
+		instances, err := application.ScaleToZero(ctx, client, param.AppRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instances).To(Equal(map[string]int32{"sample": 2, "sample-worker": 3}))
+		Expect(replicas("sample")).To(BeZero())
+		Expect(replicas("sample-worker")).To(BeZero())
+
+		Expect(application.ScaleTo(ctx, client, param.AppRef, instances)).To(Succeed())
+		Expect(replicas("sample")).To(Equal(int32(2)))
+		Expect(replicas("sample-worker")).To(Equal(int32(3)))
+	})
+
+	It("deploys the workers of a stopped application without instances", func() {
+		param.Stopped = true
+		param.Instances = 0
+		Expect(application.Deploy(ctx, client, param)).To(Succeed())
+		Expect(replicas("sample")).To(BeZero())
+		Expect(replicas("sample-worker")).To(BeZero())
+	})
+})
@@ -0,0 +1,109 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/internal/api/v1/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("orgLogsSelector", func() {
+	pod := func(app, component string) labels.Set {
+		return labels.Set{
+			"app.kubernetes.io/managed-by": "epinio",
+			"app.kubernetes.io/part-of":    "workspace",
+			"app.kubernetes.io/name":       app,
+			"app.kubernetes.io/component":  component,
+		}
+	}
+
+	It("selects the instances, workers and stagings of the applications of the org", func() {
+		selector, err := orgLogsSelector("workspace", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(selector.Matches(pod("sample", "application"))).To(BeTrue())
+		Expect(selector.Matches(pod("sample", "worker"))).To(BeTrue())
+		Expect(selector.Matches(pod("other", "staging"))).To(BeTrue())
+		Expect(selector.Matches(pod("sample", "staging-logs"))).To(BeFalse())
+
+		other := pod("sample", "application")
+		other["app.kubernetes.io/part-of"] = "production"
+		Expect(selector.Matches(other)).To(BeFalse())
+	})
+
+	It("selects only the given applications", func() {
+		selector, err := orgLogsSelector("workspace", []string{"sample", "backend"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(selector.Matches(pod("sample", "worker"))).To(BeTrue())
+		Expect(selector.Matches(pod("backend", "application"))).To(BeTrue())
+		Expect(selector.Matches(pod("other", "application"))).To(BeFalse())
+	})
+})
+
+var _ = Describe("stagingLogsDue", func() {
+	var pr *v1beta1.PipelineRun
+
+	BeforeEach(func() {
+		pr = &v1beta1.PipelineRun{}
+	})
+
+	It("is not due for a running staging", func() {
+		Expect(stagingLogsDue(pr)).To(BeFalse())
+
+		pr.Status.MarkRunning("Running", "")
+		Expect(stagingLogsDue(pr)).To(BeFalse())
+	})
+
+	It("is due for a completed staging", func() {
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		Expect(stagingLogsDue(pr)).To(BeTrue())
+	})
+
+	It("is due for a finished staging without completion time", func() {
+		pr.Status.MarkFailed("Failed", "")
+		pr.Status.CompletionTime = nil
+		Expect(stagingLogsDue(pr)).To(BeTrue())
+	})
+
+	It("is not due for a staging whose logs are saved", func() {
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Annotations = map[string]string{LogsSavedAnnotation: "true"}
+		Expect(stagingLogsDue(pr)).To(BeFalse())
+	})
+})
+
+var _ = Describe("stagingPodsGone", func() {
+	It("is true when no pod of the staging run is left", func() {
+		ctx := context.Background()
+		client := fake.NewSimpleClientset(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "stage1-pod",
+				Namespace: deployments.TektonStagingNamespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": "epinio",
+					"app.kubernetes.io/component":  "staging",
+					models.EpinioStageIDLabel:      "stage1",
+				},
+			},
+		})
+
+		gone, err := stagingPodsGone(ctx, client, "stage1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(gone).To(BeFalse())
+
+		gone, err = stagingPodsGone(ctx, client, "stage2")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(gone).To(BeTrue())
+	})
+})
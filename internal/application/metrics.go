@@ -0,0 +1,334 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LinkerdProxyContainer is the name of the sidecar container
+	// linkerd injects into the pods it meshes
+	LinkerdProxyContainer = "linkerd-proxy"
+
+	// linkerdAdminPort is the port of the linkerd proxy serving its
+	// metrics
+	linkerdAdminPort = "4191"
+
+	// TrafficInterval is the time between the two samples of the
+	// metrics of a linkerd proxy the current traffic is computed from
+	TrafficInterval = 2 * time.Second
+)
+
+// Metrics returns the resource usage, and the traffic, of the instances
+// of the application, in the order of its instances. The usage is
+// taken from the metrics API of the cluster, if any, the traffic from
+// the linkerd proxies of the instances, if any. The proxies are sampled
+// twice, TrafficInterval apart, see RequestsBetween. Without traffic the
+// proxies are not asked.
+func Metrics(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, traffic bool) (models.AppMetrics, error) {
+	instances, err := PodInstances(ctx, cluster.Kubectl, appRef)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := fmt.Sprintf("app.kubernetes.io/part-of=%s,app.kubernetes.io/name=%s", appRef.Org, appRef.Name)
+
+	usage := map[string]models.ResourceUsage{}
+	data, err := cluster.Kubectl.CoreV1().RESTClient().Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/namespaces", appRef.Org, "pods").
+		Param("labelSelector", selector).
+		DoRaw(ctx)
+	switch {
+	case err == nil:
+		usage, err = UsageFromMetrics(data)
+		if err != nil {
+			return nil, err
+		}
+	case apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err):
+		// The cluster has no metrics API
+	default:
+		return nil, err
+	}
+
+	meshed := map[string]bool{}
+	if traffic {
+		pods, err := cluster.Kubectl.CoreV1().Pods(appRef.Org).List(ctx, metav1.ListOptions{
+			LabelSelector: selector,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, pod := range pods.Items {
+			meshed[pod.Name] = pod.Status.Phase == corev1.PodRunning && hasContainer(pod, LinkerdProxyContainer)
+		}
+	}
+
+	// An unreachable proxy, e.g. of a starting instance, is the same
+	// as none
+	sample := func() (map[string]*ProxySample, error) {
+		samples := map[string]*ProxySample{}
+		for _, instance := range instances {
+			if !meshed[instance.Name] {
+				continue
+			}
+			data, err := cluster.Kubectl.CoreV1().Pods(appRef.Org).
+				ProxyGet("http", instance.Name, linkerdAdminPort, "/metrics", nil).
+				DoRaw(ctx)
+			if err != nil {
+				continue
+			}
+			samples[instance.Name], err = ParseProxySample(data, time.Now())
+			if err != nil {
+				return nil, err
+			}
+		}
+		return samples, nil
+	}
+
+	first, second := map[string]*ProxySample{}, map[string]*ProxySample{}
+	if len(meshed) > 0 {
+		first, err = sample()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(first) > 0 {
+		select {
+		case <-time.After(TrafficInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		second, err = sample()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := models.AppMetrics{}
+	for _, instance := range instances {
+		metrics := models.InstanceMetrics{
+			Name:   instance.Name,
+			Worker: instance.Worker,
+		}
+		if u, ok := usage[instance.Name]; ok {
+			metrics.Usage = &u
+		}
+		if first[instance.Name] != nil && second[instance.Name] != nil {
+			metrics.Requests = RequestsBetween(first[instance.Name], second[instance.Name])
+		}
+
+		result = append(result, metrics)
+	}
+
+	return result, nil
+}
+
+// podMetricsList is the part of a PodMetricsList of the metrics API used
+// by UsageFromMetrics
+type podMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Containers []struct {
+			Name  string                       `json:"name"`
+			Usage map[string]resource.Quantity `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// UsageFromMetrics returns the usage of the pods in the PodMetricsList
+// returned by the metrics API, by pod name. The usage of the linkerd
+// proxy is not part of it.
+func UsageFromMetrics(data []byte) (map[string]models.ResourceUsage, error) {
+	var list podMetricsList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	result := map[string]models.ResourceUsage{}
+	for _, pod := range list.Items {
+		usage := models.ResourceUsage{}
+		for _, container := range pod.Containers {
+			if container.Name == LinkerdProxyContainer {
+				continue
+			}
+			if cpu, ok := container.Usage["cpu"]; ok {
+				usage.CPU += cpu.MilliValue()
+			}
+			if memory, ok := container.Usage["memory"]; ok {
+				usage.Memory += memory.Value()
+			}
+		}
+		result[pod.Metadata.Name] = usage
+	}
+
+	return result, nil
+}
+
+// ProxySample holds the counts of the inbound traffic of a linkerd proxy,
+// taken from its metrics at a point in time. The proxy counts from its
+// start on.
+type ProxySample struct {
+	Time      time.Time
+	Requests  float64
+	Responses float64
+	Successes float64
+
+	// Latencies are the counts of the responses up to each upper bound
+	// of the buckets, of all buckets merged, in milliseconds
+	Latencies map[float64]float64
+}
+
+// ParseProxySample returns the sample of the inbound traffic in the
+// metrics of a linkerd proxy, taken at the given time
+func ParseProxySample(data []byte, at time.Time) (*ProxySample, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ProxySample{Time: at, Latencies: map[float64]float64{}}
+
+	for _, m := range inboundMetrics(families["request_total"]) {
+		result.Requests += m.GetCounter().GetValue()
+	}
+
+	for _, m := range inboundMetrics(families["response_total"]) {
+		value := m.GetCounter().GetValue()
+		result.Responses += value
+		if labelValue(m, "classification") == "success" {
+			result.Successes += value
+		}
+	}
+
+	for _, m := range inboundMetrics(families["response_latency_ms"]) {
+		for _, b := range m.GetHistogram().Bucket {
+			result.Latencies[b.GetUpperBound()] += float64(b.GetCumulativeCount())
+		}
+	}
+
+	return result, nil
+}
+
+// RequestsBetween returns the traffic of a proxy from two samples of it.
+// The total is that of the later sample, i.e. since the start of the
+// proxy. The rate, success rate and latencies are those of the requests
+// between the samples. A proxy restarted in between counts from zero.
+func RequestsBetween(first, second *ProxySample) *models.RequestMetrics {
+	if second.Requests < first.Requests || second.Responses < first.Responses {
+		first = &ProxySample{Time: first.Time}
+	}
+
+	result := &models.RequestMetrics{Total: second.Requests}
+
+	if elapsed := second.Time.Sub(first.Time).Seconds(); elapsed > 0 {
+		result.Rate = (second.Requests - first.Requests) / elapsed
+	}
+
+	responses := second.Responses - first.Responses
+	if responses > 0 {
+		result.SuccessRate = (second.Successes - first.Successes) / responses
+	}
+
+	// The +Inf bucket counts all latencies
+	var total float64
+	buckets := []latencyBucket{}
+	for upper, count := range second.Latencies {
+		b := latencyBucket{upper: upper, count: count - first.Latencies[upper]}
+		if math.IsInf(upper, 1) {
+			total = b.count
+		}
+		buckets = append(buckets, b)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].upper < buckets[j].upper })
+
+	result.LatencyP50 = quantile(0.5, buckets, total)
+	result.LatencyP95 = quantile(0.95, buckets, total)
+	result.LatencyP99 = quantile(0.99, buckets, total)
+
+	return result
+}
+
+// latencyBucket is a bucket of a latency histogram, with the count of
+// the latencies up to its upper bound
+type latencyBucket struct {
+	upper float64
+	count float64
+}
+
+// quantile estimates the quantile of the latencies in the buckets, as
+// histogram_quantile of prometheus does, by interpolation within the
+// bucket holding it. A quantile beyond the largest finite bucket is
+// estimated as its upper bound.
+func quantile(q float64, buckets []latencyBucket, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * total
+	lower, below := 0.0, 0.0
+	for _, b := range buckets {
+		if math.IsInf(b.upper, 1) {
+			break
+		}
+		if b.count >= rank {
+			if b.count == below {
+				return b.upper
+			}
+			return lower + (b.upper-lower)*(rank-below)/(b.count-below)
+		}
+		lower, below = b.upper, b.count
+	}
+
+	return lower
+}
+
+// inboundMetrics returns the metrics of the family counting the
+// inbound traffic of the proxy
+func inboundMetrics(family *dto.MetricFamily) []*dto.Metric {
+	result := []*dto.Metric{}
+	if family == nil {
+		return result
+	}
+	for _, m := range family.Metric {
+		if labelValue(m, "direction") == "inbound" {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, label := range m.Label {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
+func hasContainer(pod corev1.Pod, name string) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package application_test
+
+import (
+	"math"
+	"time"
+
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+	It("sums the usage of the containers of a pod, without the linkerd proxy", func() {
+		data := []byte(`{
+  "kind": "PodMetricsList",
+  "items": [
+    {
+      "metadata": { "name": "app-1" },
+      "containers": [
+        { "name": "app", "usage": { "cpu": "250m", "memory": "64Mi" } },
+        { "name": "sidecar", "usage": { "cpu": "1500000n", "memory": "1Mi" } },
+        { "name": "linkerd-proxy", "usage": { "cpu": "5m", "memory": "10Mi" } }
+      ]
+    },
+    {
+      "metadata": { "name": "app-2" },
+      "containers": [
+        { "name": "app", "usage": { "cpu": "1", "memory": "128Mi" } }
+      ]
+    }
+  ]
+}`)
+
+		usage, err := application.UsageFromMetrics(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usage).To(HaveLen(2))
+		Expect(usage["app-1"].CPU).To(Equal(int64(252)))
+		Expect(usage["app-1"].Memory).To(Equal(int64(65 * 1024 * 1024)))
+		Expect(usage["app-2"].CPU).To(Equal(int64(1000)))
+		Expect(usage["app-2"].Memory).To(Equal(int64(128 * 1024 * 1024)))
+	})
+
+	It("takes the inbound traffic from the metrics of the linkerd proxy", func() {
+		data := []byte(`# HELP process_start_time_seconds Time that the process started.
+# TYPE process_start_time_seconds gauge
+process_start_time_seconds 900
+# HELP request_total Total count of HTTP requests.
+# TYPE request_total counter
+request_total{direction="inbound",authority="app.example.com"} 150
+request_total{direction="inbound",authority="other.example.com"} 50
+request_total{direction="outbound",authority="db"} 1000
+# HELP response_total Total count of HTTP responses.
+# TYPE response_total counter
+response_total{direction="inbound",classification="success",status_code="200"} 180
+response_total{direction="inbound",classification="failure",status_code="500"} 20
+response_total{direction="outbound",classification="failure",status_code="500"} 1000
+# HELP response_latency_ms Elapsed times between a request's headers being received and its response stream completing
+# TYPE response_latency_ms histogram
+response_latency_ms_bucket{direction="inbound",le="10"} 100
+response_latency_ms_bucket{direction="inbound",le="20"} 180
+response_latency_ms_bucket{direction="inbound",le="50"} 200
+response_latency_ms_bucket{direction="inbound",le="+Inf"} 200
+response_latency_ms_sum{direction="inbound"} 2500
+response_latency_ms_count{direction="inbound"} 200
+response_latency_ms_bucket{direction="outbound",le="10"} 0
+response_latency_ms_bucket{direction="outbound",le="20"} 0
+response_latency_ms_bucket{direction="outbound",le="50"} 0
+response_latency_ms_bucket{direction="outbound",le="+Inf"} 1000
+response_latency_ms_sum{direction="outbound"} 100000
+response_latency_ms_count{direction="outbound"} 1000
+`)
+
+		sample, err := application.ParseProxySample(data, time.Unix(1000, 0))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sample.Requests).To(Equal(200.0))
+		Expect(sample.Responses).To(Equal(200.0))
+		Expect(sample.Successes).To(Equal(180.0))
+		Expect(sample.Latencies).To(Equal(map[float64]float64{10: 100, 20: 180, 50: 200, math.Inf(1): 200}))
+	})
+
+	It("takes the traffic between two samples of the proxy", func() {
+		first := &application.ProxySample{
+			Time:      time.Unix(1000, 0),
+			Requests:  1000,
+			Responses: 1000,
+			Successes: 500,
+			Latencies: map[float64]float64{10: 1000, 20: 1000, 50: 1000, math.Inf(1): 1000},
+		}
+		second := &application.ProxySample{
+			Time:      time.Unix(1002, 0),
+			Requests:  1200,
+			Responses: 1200,
+			Successes: 680,
+			Latencies: map[float64]float64{10: 1100, 20: 1180, 50: 1200, math.Inf(1): 1200},
+		}
+
+		requests := application.RequestsBetween(first, second)
+		Expect(requests.Total).To(Equal(1200.0))
+		Expect(requests.Rate).To(Equal(100.0))
+		Expect(requests.SuccessRate).To(Equal(0.9))
+		Expect(requests.LatencyP50).To(Equal(10.0))
+		Expect(requests.LatencyP95).To(BeNumerically("~", 20+30*10.0/20, 0.001))
+		Expect(requests.LatencyP99).To(BeNumerically("~", 20+30*18.0/20, 0.001))
+	})
+
+	It("counts from zero for a proxy restarted between the samples", func() {
+		first := &application.ProxySample{Time: time.Unix(1000, 0), Requests: 1000, Responses: 1000}
+		second := &application.ProxySample{
+			Time:      time.Unix(1002, 0),
+			Requests:  10,
+			Responses: 10,
+			Successes: 10,
+			Latencies: map[float64]float64{10: 10, math.Inf(1): 10},
+		}
+
+		requests := application.RequestsBetween(first, second)
+		Expect(requests.Total).To(Equal(10.0))
+		Expect(requests.Rate).To(Equal(5.0))
+		Expect(requests.SuccessRate).To(Equal(1.0))
+	})
+
+	It("takes no traffic from a proxy which served none", func() {
+		sample, err := application.ParseProxySample([]byte(""), time.Now())
+		Expect(err).ToNot(HaveOccurred())
+
+		requests := application.RequestsBetween(sample, sample)
+		Expect(requests.Total).To(BeZero())
+		Expect(requests.Rate).To(BeZero())
+		Expect(requests.SuccessRate).To(BeZero())
+		Expect(requests.LatencyP99).To(BeZero())
+	})
+})
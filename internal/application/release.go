@@ -0,0 +1,101 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/names"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// MaxReleases is the number of releases kept in the history of an application
+	MaxReleases = 10
+
+	releaseKey = "release"
+)
+
+// RecordRelease saves the release into the history of the
+// application. The oldest releases beyond MaxReleases are removed.
+func RecordRelease(ctx context.Context, client k8s.Interface, appRef models.AppRef, owner metav1.OwnerReference, release models.Release) error {
+	data, err := json.Marshal(release)
+	if err != nil {
+		return err
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      releaseSecret(appRef, release.ID),
+			Namespace: appRef.Org,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       appRef.Name,
+				"app.kubernetes.io/part-of":    appRef.Org,
+				"app.kubernetes.io/managed-by": "epinio",
+				"app.kubernetes.io/component":  "release",
+				models.EpinioStageIDLabel:      release.ID,
+			},
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Data: map[string][]byte{
+			releaseKey: data,
+		},
+	}
+
+	secrets := client.CoreV1().Secrets(appRef.Org)
+
+	_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	releases, err := Releases(ctx, client, appRef)
+	if err != nil {
+		return err
+	}
+
+	for i := MaxReleases; i < len(releases); i++ {
+		err := secrets.Delete(ctx, releaseSecret(appRef, releases[i].ID), metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Releases returns the release history of the application, newest first
+func Releases(ctx context.Context, client k8s.Interface, appRef models.AppRef) (models.ReleaseList, error) {
+	secrets, err := client.CoreV1().Secrets(appRef.Org).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/name=%s,app.kubernetes.io/part-of=%s,app.kubernetes.io/component=release",
+			appRef.Name, appRef.Org),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := models.ReleaseList{}
+	for _, secret := range secrets.Items {
+		var release models.Release
+		if err := json.Unmarshal(secret.Data[releaseKey], &release); err != nil {
+			return nil, err
+		}
+		result = append(result, release)
+	}
+
+	sort.Sort(result)
+	return result, nil
+}
+
+func releaseSecret(appRef models.AppRef, id string) string {
+	return names.GenerateDNS1123SubDomainName(appRef.Name + "-release-" + id)
+}
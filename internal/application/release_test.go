@@ -0,0 +1,91 @@
+package application_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Releases", func() {
+	var (
+		ctx     context.Context
+		client  *fake.Clientset
+		appRef  models.AppRef
+		owner   metav1.OwnerReference
+		created time.Time
+	)
+
+	release := func(i int) models.Release {
+		return models.Release{
+			ID:          fmt.Sprintf("stage%d", i),
+			Image:       fmt.Sprintf("registry.example.com/apps/sample-%d", i),
+			Environment: models.EnvVariableList{{Name: "RELEASE", Value: fmt.Sprint(i)}},
+			Created:     created.Add(time.Duration(i) * time.Minute),
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = fake.NewSimpleClientset()
+		appRef = models.NewAppRef("sample", "workspace")
+		owner = metav1.OwnerReference{Name: "sample"}
+		created = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	It("returns the recorded releases, newest first", func() {
+		for i := 1; i <= 3; i++ {
+			Expect(application.RecordRelease(ctx, client, appRef, owner, release(i))).To(Succeed())
+		}
+
+		releases, err := application.Releases(ctx, client, appRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releases).To(HaveLen(3))
+		Expect(releases[0].ID).To(Equal("stage3"))
+		Expect(releases[2].ID).To(Equal("stage1"))
+		Expect(releases[0].Environment).To(Equal(models.EnvVariableList{{Name: "RELEASE", Value: "3"}}))
+	})
+
+	It("keeps the releases of other applications apart", func() {
+		Expect(application.RecordRelease(ctx, client, appRef, owner, release(1))).To(Succeed())
+		other := models.NewAppRef("other", "workspace")
+		Expect(application.RecordRelease(ctx, client, other, owner, release(2))).To(Succeed())
+
+		releases, err := application.Releases(ctx, client, appRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releases).To(HaveLen(1))
+		Expect(releases[0].ID).To(Equal("stage1"))
+	})
+
+	It("updates a release recorded again", func() {
+		Expect(application.RecordRelease(ctx, client, appRef, owner, release(1))).To(Succeed())
+		again := release(1)
+		again.Port = 3000
+		Expect(application.RecordRelease(ctx, client, appRef, owner, again)).To(Succeed())
+
+		releases, err := application.Releases(ctx, client, appRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releases).To(HaveLen(1))
+		Expect(releases[0].Port).To(Equal(int32(3000)))
+	})
+
+	It("keeps only the newest releases", func() {
+		for i := 1; i <= application.MaxReleases+2; i++ {
+			Expect(application.RecordRelease(ctx, client, appRef, owner, release(i))).To(Succeed())
+		}
+
+		releases, err := application.Releases(ctx, client, appRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releases).To(HaveLen(application.MaxReleases))
+		Expect(releases[0].ID).To(Equal(fmt.Sprintf("stage%d", application.MaxReleases+2)))
+		Expect(releases[application.MaxReleases-1].ID).To(Equal("stage3"))
+	})
+})
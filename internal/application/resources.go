@@ -0,0 +1,72 @@
+package application
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// DeployedResources returns the memory and cpu limits of the deployed
+// application, empty where they are not set, or if the application has
+// no workload.
+func DeployedResources(ctx context.Context, client k8s.Interface, appRef models.AppRef) (string, string, error) {
+	deployment, err := client.AppsV1().Deployments(appRef.Org).Get(ctx, appRef.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	memory, cpu := deploymentResources(deployment)
+	return memory, cpu, nil
+}
+
+// setResource requests and limits the named resource of the container
+// to the quantity. An empty quantity leaves the resource unchanged.
+func setResource(requirements *corev1.ResourceRequirements, name corev1.ResourceName, quantity string) error {
+	if quantity == "" {
+		return nil
+	}
+
+	q, err := resource.ParseQuantity(quantity)
+	if err != nil {
+		return err
+	}
+
+	if requirements.Limits == nil {
+		requirements.Limits = corev1.ResourceList{}
+	}
+	if requirements.Requests == nil {
+		requirements.Requests = corev1.ResourceList{}
+	}
+	requirements.Limits[name] = q
+	requirements.Requests[name] = q
+
+	return nil
+}
+
+// deploymentResources returns the memory and cpu limits of the
+// application container, empty where they are not set
+func deploymentResources(deployment *appsv1.Deployment) (string, string) {
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return "", ""
+	}
+
+	var memory, cpu string
+	limits := containers[0].Resources.Limits
+	if q, ok := limits[corev1.ResourceMemory]; ok {
+		memory = q.String()
+	}
+	if q, ok := limits[corev1.ResourceCPU]; ok {
+		cpu = q.String()
+	}
+	return memory, cpu
+}
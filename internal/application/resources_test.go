@@ -0,0 +1,43 @@
+package application
+
+import (
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var _ = Describe("Resources", func() {
+	table.DescribeTable("setResource",
+		func(current corev1.ResourceList, quantity string, expected corev1.ResourceList) {
+			requirements := corev1.ResourceRequirements{Limits: current, Requests: current.DeepCopy()}
+
+			Expect(setResource(&requirements, corev1.ResourceMemory, quantity)).To(Succeed())
+			Expect(requirements.Limits).To(Equal(expected))
+			Expect(requirements.Requests).To(Equal(expected))
+		},
+		table.Entry("keeps the resources for no quantity",
+			corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")}, "",
+			corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")}),
+		table.Entry("sets the resource of a container without resources",
+			nil, "256Mi",
+			corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")}),
+		table.Entry("replaces the resource",
+			corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")}, "256Mi",
+			corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")}),
+		table.Entry("keeps the other resources",
+			corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}, "256Mi",
+			corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+			}),
+	)
+
+	It("fails for a bad quantity", func() {
+		requirements := corev1.ResourceRequirements{}
+		Expect(setResource(&requirements, corev1.ResourceCPU, "fast")).ToNot(Succeed())
+		Expect(requirements.Limits).To(BeEmpty())
+	})
+})
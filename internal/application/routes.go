@@ -0,0 +1,126 @@
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/names"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// DeployedRoutes returns the routes of the deployed application, i.e.
+// the hosts of its ingress, or nil if the application has no workload.
+func DeployedRoutes(ctx context.Context, client k8s.Interface, appRef models.AppRef) ([]string, error) {
+	ingress, err := client.NetworkingV1().Ingresses(appRef.Org).Get(ctx, appRef.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	routes := []string{}
+	for _, rule := range ingress.Spec.Rules {
+		routes = append(routes, rule.Host)
+	}
+	return routes, nil
+}
+
+// SetRoutes replaces the routes of the deployed application. The
+// certificates of the routes are not handled here.
+func SetRoutes(ctx context.Context, client k8s.Interface, appRef models.AppRef, routes []string) error {
+	ingresses := client.NetworkingV1().Ingresses(appRef.Org)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ingress, err := ingresses.Get(ctx, appRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		// Keep the port the application listens on
+		port := DefaultPort
+		if len(ingress.Spec.Rules) > 0 {
+			rule := ingress.Spec.Rules[0]
+			if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 && rule.HTTP.Paths[0].Backend.Service != nil {
+				port = rule.HTTP.Paths[0].Backend.Service.Port.Number
+			}
+		}
+
+		ingress.Spec = ingressSpec(appRef, port, routes)
+
+		_, err = ingresses.Update(ctx, ingress, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// RouteUser returns the application using the route, if any
+func RouteUser(ctx context.Context, client k8s.Interface, route string) (*models.AppRef, error) {
+	ingresses, err := client.NetworkingV1().Ingresses("").List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/component=application,app.kubernetes.io/managed-by=epinio",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ingress := range ingresses.Items {
+		for _, rule := range ingress.Spec.Rules {
+			if rule.Host == route {
+				appRef := models.NewAppRef(ingress.Name, ingress.Namespace)
+				return &appRef, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// CertName returns the name of the certificate for the route of the
+// application. The certificate stores its key and cert into a secret
+// of the same name, with suffix "-tls".
+func CertName(appRef models.AppRef, route string) string {
+	return names.TruncateMD5(fmt.Sprintf("%s.%s", appRef.Name, route),
+		validation.DNS1123SubdomainMaxLength-len("-tls"))
+}
+
+// ingressSpec returns the rules routing each of the hosts to the
+// service of the application, with a certificate per host.
+func ingressSpec(appRef models.AppRef, port int32, routes []string) networkingv1.IngressSpec {
+	pathType := networkingv1.PathTypeImplementationSpecific
+
+	spec := networkingv1.IngressSpec{}
+	for _, route := range routes {
+		spec.Rules = append(spec.Rules, networkingv1.IngressRule{
+			Host: route,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{
+						{
+							Path:     "/",
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: appRef.Name,
+									Port: networkingv1.ServiceBackendPort{
+										Number: port,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+		spec.TLS = append(spec.TLS, networkingv1.IngressTLS{
+			Hosts:      []string{route},
+			SecretName: CertName(appRef, route) + "-tls",
+		})
+	}
+	return spec
+}
@@ -0,0 +1,77 @@
+package application_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Routes", func() {
+	var (
+		ctx    context.Context
+		client *fake.Clientset
+		appRef models.AppRef
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = fake.NewSimpleClientset()
+		appRef = models.NewAppRef("sample", "workspace")
+
+		Expect(application.Deploy(ctx, client, application.DeployParam{
+			AppRef:    appRef,
+			StageID:   "stage1",
+			Image:     "registry.example.com/apps/sample-abc",
+			Instances: 1,
+			Routes:    []string{"sample.example.com"},
+			Port:      3000,
+		})).To(Succeed())
+	})
+
+	It("returns the routes of the deployed application", func() {
+		routes, err := application.DeployedRoutes(ctx, client, appRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(routes).To(Equal([]string{"sample.example.com"}))
+
+		routes, err = application.DeployedRoutes(ctx, client, models.NewAppRef("other", "workspace"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(routes).To(BeNil())
+	})
+
+	It("replaces the routes, keeping the port", func() {
+		Expect(application.SetRoutes(ctx, client, appRef, []string{"sample.example.com", "www.example.org"})).To(Succeed())
+
+		ingress, err := client.NetworkingV1().Ingresses("workspace").Get(ctx, "sample", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ingress.Spec.Rules).To(HaveLen(2))
+		Expect(ingress.Spec.Rules[1].Host).To(Equal("www.example.org"))
+		Expect(ingress.Spec.Rules[1].HTTP.Paths[0].Backend.Service.Port.Number).To(Equal(int32(3000)))
+		Expect(ingress.Spec.TLS).To(HaveLen(2))
+		Expect(ingress.Spec.TLS[1].Hosts).To(ConsistOf("www.example.org"))
+		Expect(ingress.Spec.TLS[1].SecretName).To(Equal(application.CertName(appRef, "www.example.org") + "-tls"))
+	})
+
+	It("finds the application using a route", func() {
+		user, err := application.RouteUser(ctx, client, "sample.example.com")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user).To(Equal(&appRef))
+
+		user, err = application.RouteUser(ctx, client, "www.example.org")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user).To(BeNil())
+	})
+
+	It("keeps certificate names within bounds", func() {
+		long := strings.Repeat("a", 60) + "." + strings.Repeat("b", 60) + "." +
+			strings.Repeat("c", 60) + "." + strings.Repeat("d", 60) + ".example.com"
+		Expect(len(application.CertName(appRef, long) + "-tls")).To(BeNumerically("<=", 253))
+	})
+})
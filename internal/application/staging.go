@@ -0,0 +1,217 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
+	tekton "github.com/tektoncd/pipeline/pkg/client/clientset/versioned/typed/pipeline/v1beta1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// CurrentStaging returns the ID of the staging run of the application
+// which is still in progress, or the empty string if there is none.
+func CurrentStaging(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (string, error) {
+	cs, err := versioned.NewForConfig(cluster.RestConfig)
+	if err != nil {
+		return "", err
+	}
+
+	l, err := cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/name=%s,app.kubernetes.io/part-of=%s", appRef.Name, appRef.Org),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// assume that completed pipelineruns are from the past and have a CompletionTime
+	for _, pr := range l.Items {
+		if pr.Status.CompletionTime == nil {
+			return pr.Name, nil
+		}
+	}
+
+	return "", nil
+}
+
+// StagingsInProgress returns the number of staging runs still in
+// progress, by org
+func StagingsInProgress(ctx context.Context, cluster *kubernetes.Cluster) (map[string]int, error) {
+	cs, err := versioned.NewForConfig(cluster.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]int{}
+	for _, pr := range l.Items {
+		if pr.Status.CompletionTime == nil {
+			result[pr.Labels["app.kubernetes.io/part-of"]]++
+		}
+	}
+
+	return result, nil
+}
+
+// StagingStatus returns the progress of the identified staging run
+func StagingStatus(ctx context.Context, cluster *kubernetes.Cluster, stageID string) (*models.StagingStatus, error) {
+	cs, err := versioned.NewForConfig(cluster.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace).Get(ctx, stageID, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	status := NewStagingStatus(pr)
+	return &status, nil
+}
+
+// NewStagingStatus converts the state of the PipelineRun into the
+// progress of the staging run, per task of the pipeline.
+func NewStagingStatus(pr *v1beta1.PipelineRun) models.StagingStatus {
+	status := models.StagingStatus{
+		ID: pr.Name,
+		App: models.NewAppRef(pr.Labels["app.kubernetes.io/name"],
+			pr.Labels["app.kubernetes.io/part-of"]),
+		StartTime:      timeOf(pr.Status.StartTime),
+		CompletionTime: timeOf(pr.Status.CompletionTime),
+	}
+	succeeded := pr.Status.GetCondition("Succeeded")
+	status.State = stagingState(pr.Status.StartTime, succeeded.IsTrue(), succeeded.IsFalse(), succeeded.GetReason())
+	if succeeded.IsFalse() {
+		status.Reason = succeeded.GetReason()
+		status.Message = succeeded.GetMessage()
+	}
+
+	// Tasks not run yet, and thus without TaskRun, are pending
+	taskRuns := map[string]*v1beta1.TaskRunStatus{}
+	for _, tr := range pr.Status.TaskRuns {
+		taskRuns[tr.PipelineTaskName] = tr.Status
+	}
+
+	if pr.Status.PipelineSpec == nil {
+		return status
+	}
+
+	tasks := append([]v1beta1.PipelineTask{}, pr.Status.PipelineSpec.Tasks...)
+	tasks = append(tasks, pr.Status.PipelineSpec.Finally...)
+	for _, task := range tasks {
+		taskStatus := models.StagingTaskStatus{
+			Name:  task.Name,
+			State: models.StagingPending,
+		}
+		if tr, ok := taskRuns[task.Name]; ok && tr != nil {
+			succeeded := tr.GetCondition("Succeeded")
+			taskStatus.State = stagingState(tr.StartTime, succeeded.IsTrue(), succeeded.IsFalse(), succeeded.GetReason())
+			if succeeded.IsFalse() {
+				taskStatus.Reason = succeeded.GetReason()
+				taskStatus.Message = succeeded.GetMessage()
+			}
+			taskStatus.StartTime = timeOf(tr.StartTime)
+			taskStatus.CompletionTime = timeOf(tr.CompletionTime)
+		}
+		status.Tasks = append(status.Tasks, taskStatus)
+	}
+
+	return status
+}
+
+// stagingState derives the state of a run, pipeline or task, from its
+// start and its Succeeded condition.
+func stagingState(start *metav1.Time, succeeded, failed bool, reason string) string {
+	switch {
+	case succeeded:
+		return models.StagingSucceeded
+	case failed && (reason == "PipelineRunCancelled" || reason == string(v1beta1.TaskRunReasonCancelled)):
+		return models.StagingCancelled
+	case failed:
+		return models.StagingFailed
+	case start == nil:
+		return models.StagingPending
+	default:
+		return models.StagingRunning
+	}
+}
+
+// timeOf converts an optional kube time into a plain time
+func timeOf(t *metav1.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	result := t.Time
+	return &result
+}
+
+// CancelStaging stops the identified staging run, and removes the
+// volumes holding its workspaces.
+func CancelStaging(ctx context.Context, cluster *kubernetes.Cluster, stageID string) error {
+	cs, err := versioned.NewForConfig(cluster.RestConfig)
+	if err != nil {
+		return err
+	}
+	client := cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace)
+
+	return CancelRun(ctx, client, cluster.Kubectl, stageID)
+}
+
+// CancelRun is CancelStaging, with the clients for the staging runs
+// and their volumes.
+func CancelRun(ctx context.Context, client tekton.PipelineRunInterface, kubectl k8s.Interface, stageID string) error {
+	var pr *v1beta1.PipelineRun
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		pr, err = client.Get(ctx, stageID, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if pr.IsCancelled() {
+			return nil
+		}
+
+		pr.Spec.Status = v1beta1.PipelineRunSpecStatusCancelled
+		pr, err = client.Update(ctx, pr, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The workspace volumes are created by tekton from the claim
+	// templates, and are owned by the run. They are normally only
+	// removed together with the run.
+	pvcs := kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace)
+	l, err := pvcs.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, pvc := range l.Items {
+		for _, owner := range pvc.OwnerReferences {
+			if owner.UID != pr.UID {
+				continue
+			}
+			err := pvcs.Delete(ctx, pvc.Name, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package application_test
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	tektonfake "github.com/tektoncd/pipeline/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+const stagingRunYaml = `
+metadata:
+  name: stage1
+  labels:
+    app.kubernetes.io/name: sample
+    app.kubernetes.io/part-of: workspace
+status:
+  startTime: "2021-06-01T10:00:00Z"
+  conditions:
+  - type: Succeeded
+    status: Unknown
+    reason: Running
+  pipelineSpec:
+    tasks:
+    - name: clone
+    - name: stage
+    - name: clean
+  taskRuns:
+    stage1-clone-abc:
+      pipelineTaskName: clone
+      status:
+        startTime: "2021-06-01T10:00:00Z"
+        completionTime: "2021-06-01T10:00:10Z"
+        conditions:
+        - type: Succeeded
+          status: "True"
+          reason: Succeeded
+    stage1-stage-def:
+      pipelineTaskName: stage
+      status:
+        startTime: "2021-06-01T10:00:10Z"
+        conditions:
+        - type: Succeeded
+          status: Unknown
+          reason: Running
+`
+
+var _ = Describe("StagingStatus", func() {
+	var pr *v1beta1.PipelineRun
+
+	BeforeEach(func() {
+		pr = &v1beta1.PipelineRun{}
+		Expect(yaml.Unmarshal([]byte(stagingRunYaml), pr)).To(Succeed())
+	})
+
+	It("reports the progress of each task", func() {
+		status := application.NewStagingStatus(pr)
+
+		Expect(status.ID).To(Equal("stage1"))
+		Expect(status.App).To(Equal(models.NewAppRef("sample", "workspace")))
+		Expect(status.State).To(Equal(models.StagingRunning))
+		Expect(status.Done()).To(BeFalse())
+		Expect(status.StartTime).ToNot(BeNil())
+		Expect(status.CompletionTime).To(BeNil())
+
+		Expect(status.Tasks).To(HaveLen(3))
+		Expect(status.Tasks[0].Name).To(Equal("clone"))
+		Expect(status.Tasks[0].State).To(Equal(models.StagingSucceeded))
+		Expect(status.Tasks[0].CompletionTime).ToNot(BeNil())
+		Expect(status.Tasks[1].Name).To(Equal("stage"))
+		Expect(status.Tasks[1].State).To(Equal(models.StagingRunning))
+		Expect(status.Tasks[2].Name).To(Equal("clean"))
+		Expect(status.Tasks[2].State).To(Equal(models.StagingPending))
+	})
+
+	It("reports the reason of a failure", func() {
+		pr.Status.Conditions[0].Status = "False"
+		pr.Status.Conditions[0].Reason = "Failed"
+		pr.Status.Conditions[0].Message = "Tasks Completed: 2 (Failed: 1, Cancelled 0), Skipped: 1"
+		stage := pr.Status.TaskRuns["stage1-stage-def"].Status
+		stage.Conditions[0].Status = "False"
+		stage.Conditions[0].Reason = "Failed"
+		stage.Conditions[0].Message = "step-create exited with code 1"
+
+		status := application.NewStagingStatus(pr)
+
+		Expect(status.State).To(Equal(models.StagingFailed))
+		Expect(status.Done()).To(BeTrue())
+		Expect(status.Reason).To(Equal("Failed"))
+		Expect(status.Tasks[1].State).To(Equal(models.StagingFailed))
+		Expect(status.Tasks[1].Message).To(Equal("step-create exited with code 1"))
+		Expect(status.Tasks[0].Reason).To(BeEmpty())
+	})
+
+	It("reports a cancelled run", func() {
+		pr.Status.Conditions[0].Status = "False"
+		pr.Status.Conditions[0].Reason = "PipelineRunCancelled"
+
+		status := application.NewStagingStatus(pr)
+
+		Expect(status.State).To(Equal(models.StagingCancelled))
+		Expect(status.Done()).To(BeTrue())
+	})
+})
+
+var _ = Describe("CancelRun", func() {
+	var (
+		ctx     context.Context
+		tekton  *tektonfake.Clientset
+		kubectl *fake.Clientset
+	)
+
+	pvc := func(name, owner string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       deployments.TektonStagingNamespace,
+				OwnerReferences: []metav1.OwnerReference{{Name: owner, UID: types.UID(owner + "-uid")}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		pr := &v1beta1.PipelineRun{}
+		Expect(yaml.Unmarshal([]byte(stagingRunYaml), pr)).To(Succeed())
+		pr.Namespace = deployments.TektonStagingNamespace
+		pr.UID = "stage1-uid"
+		tekton = tektonfake.NewSimpleClientset(pr)
+
+		kubectl = fake.NewSimpleClientset(pvc("pvc-stage1", "stage1"), pvc("pvc-stage0", "stage0"))
+	})
+
+	It("cancels the run, and removes its volumes only", func() {
+		runs := tekton.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace)
+		Expect(application.CancelRun(ctx, runs, kubectl, "stage1")).To(Succeed())
+
+		pr, err := runs.Get(ctx, "stage1", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pr.Spec.Status).To(Equal(v1beta1.PipelineRunSpecStatus(v1beta1.PipelineRunSpecStatusCancelled)))
+
+		pvcs, err := kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace).List(ctx, metav1.ListOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pvcs.Items).To(HaveLen(1))
+		Expect(pvcs.Items[0].Name).To(Equal("pvc-stage0"))
+	})
+
+	It("leaves a cancelled run as is", func() {
+		runs := tekton.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace)
+		Expect(application.CancelRun(ctx, runs, kubectl, "stage1")).To(Succeed())
+		Expect(application.CancelRun(ctx, runs, kubectl, "stage1")).To(Succeed())
+	})
+
+	It("fails for an unknown run", func() {
+		runs := tekton.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace)
+		Expect(application.CancelRun(ctx, runs, kubectl, "stage2")).ToNot(Succeed())
+	})
+})
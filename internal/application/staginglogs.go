@@ -0,0 +1,424 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/kubernetes/tailer"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/names"
+	"github.com/go-logr/logr"
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// MaxStagingLogSize is the most bytes of log lines kept for a
+	// staging run. The oldest lines beyond it are dropped, to fit the
+	// lines into a config map.
+	MaxStagingLogSize = 900 * 1024
+
+	// LogsSavedAnnotation marks a staging run whose logs are saved
+	LogsSavedAnnotation = "epinio.suse.org/logs-saved"
+
+	stagingLogsKey = "logs"
+)
+
+// SaveStagingLogs keeps, every interval until the context is done, the
+// logs of the completed staging runs beyond the life of their
+// PipelineRuns. The logs are taken from the pods of the runs soon after
+// their completion, while the pods still exist.
+func SaveStagingLogs(ctx context.Context, logger logr.Logger, interval time.Duration) {
+	ctx = context.WithValue(ctx, tracelog.CtxLoggerKey{}, logger)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := saveStagingLogs(ctx); err != nil {
+				logger.Error(err, "saving staging logs")
+			}
+		}
+	}
+}
+
+// saveStagingLogs keeps the logs of the completed staging runs whose
+// logs are not kept yet. A run is marked as saved once its lines are
+// stored. A run without log lines is tried again on the next round,
+// until its pods are gone.
+func saveStagingLogs(ctx context.Context) error {
+	log := tracelog.Logger(ctx)
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return err
+	}
+	cs, err := versioned.NewForConfig(cluster.RestConfig)
+	if err != nil {
+		return err
+	}
+	client := cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace)
+
+	runs, err := client.List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/managed-by=epinio,app.kubernetes.io/component=staging",
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range runs.Items {
+		pr := &runs.Items[i]
+		if !stagingLogsDue(pr) {
+			continue
+		}
+
+		appRef := models.NewAppRef(pr.Labels["app.kubernetes.io/name"], pr.Labels["app.kubernetes.io/part-of"])
+
+		app, err := Get(ctx, cluster, appRef)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				log.Error(err, "getting application of staging run", "uid", pr.Name)
+			}
+			continue
+		}
+		owner := metav1.OwnerReference{
+			APIVersion: app.GetAPIVersion(),
+			Kind:       app.GetKind(),
+			Name:       app.GetName(),
+			UID:        app.GetUID(),
+		}
+
+		lines, err := collectLogs(ctx, cluster, appRef, pr.Name)
+		if err != nil {
+			log.Error(err, "collecting staging logs", "uid", pr.Name)
+			continue
+		}
+		if len(lines) > 0 {
+			err = StoreStagingLogs(ctx, cluster.Kubectl, appRef, owner, pr.Name, lines)
+			if err != nil {
+				log.Error(err, "storing staging logs", "uid", pr.Name)
+				continue
+			}
+		} else {
+			gone, err := stagingPodsGone(ctx, cluster.Kubectl, pr.Name)
+			if err != nil {
+				log.Error(err, "checking staging pods", "uid", pr.Name)
+				continue
+			}
+			if !gone {
+				continue
+			}
+			log.Info("staging logs lost, the pods of the run are gone", "uid", pr.Name)
+		}
+
+		// A conflicting change of the run saves its logs again on
+		// the next round, replacing those saved now
+		if pr.Annotations == nil {
+			pr.Annotations = map[string]string{}
+		}
+		pr.Annotations[LogsSavedAnnotation] = "true"
+		_, err = client.Update(ctx, pr, metav1.UpdateOptions{})
+		if err != nil && !apierrors.IsConflict(err) {
+			log.Error(err, "marking staging logs as saved", "uid", pr.Name)
+		}
+	}
+
+	return nil
+}
+
+// stagingLogsDue returns true if the logs of the staging run are to be
+// saved, i.e. the run is done, with or without a completion time, and
+// its logs are not saved yet.
+func stagingLogsDue(pr *v1beta1.PipelineRun) bool {
+	if _, ok := pr.Annotations[LogsSavedAnnotation]; ok {
+		return false
+	}
+	if pr.Status.CompletionTime != nil {
+		return true
+	}
+	succeeded := pr.Status.GetCondition("Succeeded")
+	return succeeded != nil && !succeeded.IsUnknown()
+}
+
+// stagingPodsGone returns true if no pod of the staging run is left
+func stagingPodsGone(ctx context.Context, client k8s.Interface, stageID string) (bool, error) {
+	pods, err := client.CoreV1().Pods(deployments.TektonStagingNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/managed-by=epinio,app.kubernetes.io/component=staging,%s=%s",
+			models.EpinioStageIDLabel, stageID),
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(pods.Items) == 0, nil
+}
+
+// collectLogs returns all the log lines of the staging run, with their
+// timestamps, oldest first
+func collectLogs(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, stageID string) ([]tailer.ContainerLogLine, error) {
+	logChan := make(chan tailer.ContainerLogLine)
+	lines := []tailer.ContainerLogLine{}
+
+	done := make(chan struct{})
+	go func() {
+		for line := range logChan {
+			lines = append(lines, line)
+		}
+		close(done)
+	}()
+
+	// Without a since of its own the logs reach only as far back as
+	// the log history of the server
+	options := models.LogOptions{Timestamps: true, Since: math.MaxInt64}
+
+	var wg sync.WaitGroup
+	err := Logs(ctx, logChan, &wg, cluster, false, appRef.Name, stageID, appRef.Org, options)
+	wg.Wait()
+	close(logChan)
+	<-done
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Timestamp.Before(lines[j].Timestamp)
+	})
+	return lines, nil
+}
+
+// StoreStagingLogs saves the log lines of the staging run, oldest first,
+// into a config map owned by the application. The oldest lines beyond
+// MaxStagingLogSize are dropped. See pruneStagingLogs for the saved logs
+// of other runs which are dropped.
+func StoreStagingLogs(ctx context.Context, client k8s.Interface, appRef models.AppRef, owner metav1.OwnerReference, stageID string, lines []tailer.ContainerLogLine) error {
+	encoded := []string{}
+	size := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		b, err := json.Marshal(lines[i])
+		if err != nil {
+			return err
+		}
+		if size+len(b)+1 > MaxStagingLogSize {
+			break
+		}
+		size += len(b) + 1
+		encoded = append(encoded, string(b))
+	}
+
+	var data strings.Builder
+	for i := len(encoded) - 1; i >= 0; i-- {
+		data.WriteString(encoded[i])
+		data.WriteString("\n")
+	}
+
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      stagingLogsConfigMap(appRef, stageID),
+			Namespace: appRef.Org,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       appRef.Name,
+				"app.kubernetes.io/part-of":    appRef.Org,
+				"app.kubernetes.io/managed-by": "epinio",
+				"app.kubernetes.io/component":  "staging-logs",
+				models.EpinioStageIDLabel:      stageID,
+			},
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Data: map[string]string{
+			stagingLogsKey: data.String(),
+		},
+	}
+
+	configMaps := client.CoreV1().ConfigMaps(appRef.Org)
+
+	_, err := configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	return pruneStagingLogs(ctx, client, appRef, stageID)
+}
+
+// pruneStagingLogs drops the saved logs of the staging runs of the
+// application which are not used by its release history. The logs of the
+// releases in the history are kept, and for rollbacks those of the release
+// rolled back to. So are the logs of up to MaxReleases runs newer than the
+// newest release, i.e. runs which failed or are not deployed yet. The
+// given run counts among them when it is not released.
+func pruneStagingLogs(ctx context.Context, client k8s.Interface, appRef models.AppRef, stageID string) error {
+	releases, err := Releases(ctx, client, appRef)
+	if err != nil {
+		return err
+	}
+
+	keep := map[string]bool{}
+	for _, release := range releases {
+		keep[release.ID] = true
+		if release.RollbackOf != "" {
+			keep[release.RollbackOf] = true
+		}
+	}
+	var newest time.Time
+	if len(releases) > 0 {
+		newest = releases[0].Created
+	}
+
+	configMaps := client.CoreV1().ConfigMaps(appRef.Org)
+
+	saved, err := configMaps.List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/name=%s,app.kubernetes.io/part-of=%s,app.kubernetes.io/component=staging-logs",
+			appRef.Name, appRef.Org),
+	})
+	if err != nil {
+		return err
+	}
+
+	items := saved.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[j].CreationTimestamp.Before(&items[i].CreationTimestamp)
+	})
+	unreleased := 0
+	if !keep[stageID] {
+		unreleased++
+	}
+	for _, item := range items {
+		id := item.Labels[models.EpinioStageIDLabel]
+		if keep[id] || id == stageID {
+			continue
+		}
+		if unreleased < MaxReleases && (newest.IsZero() || item.CreationTimestamp.After(newest)) {
+			unreleased++
+			continue
+		}
+		err := configMaps.Delete(ctx, item.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SavedStagingLogs returns the saved log lines of the staging run in the
+// org, oldest first. The result is nil when they are not saved.
+func SavedStagingLogs(ctx context.Context, client k8s.Interface, org, stageID string) ([]tailer.ContainerLogLine, error) {
+	saved, err := client.CoreV1().ConfigMaps(org).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/component=staging-logs,%s=%s", models.EpinioStageIDLabel, stageID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(saved.Items) == 0 {
+		return nil, nil
+	}
+
+	lines := []tailer.ContainerLogLine{}
+	decoder := json.NewDecoder(strings.NewReader(saved.Items[0].Data[stagingLogsKey]))
+	for decoder.More() {
+		var line tailer.ContainerLogLine
+		if err := decoder.Decode(&line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// ReplayLogs writes the saved log lines to the logChan, selected by the
+// options, as Logs does for live ones. The options reach into the past
+// only as far as their since. Their instance is not supported.
+func ReplayLogs(ctx context.Context, logChan chan tailer.ContainerLogLine, lines []tailer.ContainerLogLine, options models.LogOptions) error {
+	include, exclude, err := options.Filters()
+	if err != nil {
+		return err
+	}
+
+	var cutoff time.Time
+	if options.Since > 0 {
+		cutoff = time.Now().Add(-options.Since)
+	}
+
+	selected := []tailer.ContainerLogLine{}
+OUTER:
+	for _, line := range lines {
+		if line.Timestamp.Before(cutoff) {
+			continue
+		}
+		for _, rex := range exclude {
+			if rex.MatchString(line.Message) {
+				continue OUTER
+			}
+		}
+		if len(include) != 0 {
+			matches := false
+			for _, rin := range include {
+				if rin.MatchString(line.Message) {
+					matches = true
+					break
+				}
+			}
+			if !matches {
+				continue OUTER
+			}
+		}
+		selected = append(selected, line)
+	}
+
+	// The tail counts per container, from the end
+	if options.Tail != nil {
+		counts := map[string]int64{}
+		keep := make([]bool, len(selected))
+		for i := len(selected) - 1; i >= 0; i-- {
+			container := selected[i].PodName + "/" + selected[i].ContainerName
+			if counts[container] < *options.Tail {
+				counts[container]++
+				keep[i] = true
+			}
+		}
+		tail := []tailer.ContainerLogLine{}
+		for i, line := range selected {
+			if keep[i] {
+				tail = append(tail, line)
+			}
+		}
+		selected = tail
+	}
+
+	for _, line := range selected {
+		if !options.Timestamps {
+			line.Timestamp = time.Time{}
+		}
+		select {
+		case logChan <- line:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func stagingLogsConfigMap(appRef models.AppRef, stageID string) string {
+	return names.GenerateDNS1123SubDomainName(appRef.Name + "-staging-" + stageID)
+}
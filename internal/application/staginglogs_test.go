@@ -0,0 +1,170 @@
+package application_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes/tailer"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Staging logs", func() {
+	var (
+		ctx    context.Context
+		client *fake.Clientset
+		appRef models.AppRef
+		owner  metav1.OwnerReference
+		now    time.Time
+	)
+
+	line := func(container, message string, age time.Duration) tailer.ContainerLogLine {
+		return tailer.ContainerLogLine{
+			Message:       message,
+			ContainerName: container,
+			PodName:       "stage-pod",
+			Namespace:     "tekton-staging",
+			AppName:       appRef.Name,
+			Timestamp:     now.Add(-age),
+		}
+	}
+
+	replay := func(lines []tailer.ContainerLogLine, options models.LogOptions) []tailer.ContainerLogLine {
+		logChan := make(chan tailer.ContainerLogLine)
+		result := []tailer.ContainerLogLine{}
+		done := make(chan struct{})
+		go func() {
+			for l := range logChan {
+				result = append(result, l)
+			}
+			close(done)
+		}()
+		Expect(application.ReplayLogs(ctx, logChan, lines, options)).To(Succeed())
+		close(logChan)
+		<-done
+		return result
+	}
+
+	messages := func(lines []tailer.ContainerLogLine) []string {
+		result := []string{}
+		for _, l := range lines {
+			result = append(result, l.Message)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = fake.NewSimpleClientset()
+		appRef = models.NewAppRef("app", "workspace")
+		owner = metav1.OwnerReference{APIVersion: "app.k8s.io/v1beta1", Kind: "App", Name: "app", UID: "uid"}
+		now = time.Now().UTC().Truncate(time.Second)
+	})
+
+	It("keeps the lines of a staging run", func() {
+		lines := []tailer.ContainerLogLine{
+			line("step-build", "building", 2*time.Minute),
+			line("step-export", "exporting", time.Minute),
+		}
+		Expect(application.StoreStagingLogs(ctx, client, appRef, owner, "stage1", lines)).To(Succeed())
+
+		saved, err := application.SavedStagingLogs(ctx, client, "workspace", "stage1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(saved).To(HaveLen(2))
+		Expect(saved[0].Message).To(Equal("building"))
+		Expect(saved[1].ContainerName).To(Equal("step-export"))
+		Expect(saved[1].Timestamp.Equal(now.Add(-time.Minute))).To(BeTrue())
+
+		saved, err = application.SavedStagingLogs(ctx, client, "workspace", "stage2")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(saved).To(BeNil())
+	})
+
+	It("drops the oldest lines beyond the size limit", func() {
+		big := strings.Repeat("x", 100*1024)
+		lines := []tailer.ContainerLogLine{}
+		for i := 0; i < 12; i++ {
+			lines = append(lines, line("step-build", fmt.Sprintf("%02d%s", i, big), time.Duration(12-i)*time.Minute))
+		}
+		Expect(application.StoreStagingLogs(ctx, client, appRef, owner, "stage1", lines)).To(Succeed())
+
+		saved, err := application.SavedStagingLogs(ctx, client, "workspace", "stage1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(saved)).To(BeNumerically("<", 12))
+		Expect(saved[len(saved)-1].Message).To(HavePrefix("11"))
+	})
+
+	It("keeps the logs of the newest unreleased staging runs only", func() {
+		for i := 0; i < application.MaxReleases+2; i++ {
+			stageID := fmt.Sprintf("stage%d", i)
+			Expect(application.StoreStagingLogs(ctx, client, appRef, owner, stageID, nil)).To(Succeed())
+		}
+
+		configMaps, err := client.CoreV1().ConfigMaps("workspace").List(ctx, metav1.ListOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configMaps.Items).To(HaveLen(application.MaxReleases))
+	})
+
+	It("keeps the logs of the releases in the history and of their rollback targets", func() {
+		record := func(id, rollbackOf string, age time.Duration) {
+			Expect(application.RecordRelease(ctx, client, appRef, owner, models.Release{
+				ID:         id,
+				RollbackOf: rollbackOf,
+				Created:    now.Add(-age),
+			})).To(Succeed())
+		}
+
+		Expect(application.StoreStagingLogs(ctx, client, appRef, owner, "target", nil)).To(Succeed())
+		Expect(application.StoreStagingLogs(ctx, client, appRef, owner, "dropped", nil)).To(Succeed())
+		record("rollback", "target", time.Minute)
+		for i := 0; i < application.MaxReleases-1; i++ {
+			stageID := fmt.Sprintf("stage%d", i)
+			record(stageID, "", time.Duration(i+2)*time.Minute)
+			Expect(application.StoreStagingLogs(ctx, client, appRef, owner, stageID, nil)).To(Succeed())
+		}
+
+		for _, stageID := range []string{"target", "stage0", "stage8"} {
+			saved, err := application.SavedStagingLogs(ctx, client, "workspace", stageID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(saved).ToNot(BeNil(), stageID)
+		}
+		saved, err := application.SavedStagingLogs(ctx, client, "workspace", "dropped")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(saved).To(BeNil())
+	})
+
+	It("replays the lines selected by the options", func() {
+		lines := []tailer.ContainerLogLine{
+			line("step-build", "old build", time.Hour),
+			line("step-build", "build 1", 3*time.Minute),
+			line("step-build", "build 2", 2*time.Minute),
+			line("step-export", "export", time.Minute),
+			line("step-build", "noise", time.Second),
+		}
+
+		Expect(messages(replay(lines, models.LogOptions{}))).To(Equal(
+			[]string{"old build", "build 1", "build 2", "export", "noise"}))
+
+		tail := int64(1)
+		Expect(messages(replay(lines, models.LogOptions{
+			Since:   10 * time.Minute,
+			Tail:    &tail,
+			Exclude: []string{"noise"},
+		}))).To(Equal([]string{"build 2", "export"}))
+
+		Expect(messages(replay(lines, models.LogOptions{
+			Include: []string{"^build", "export"},
+		}))).To(Equal([]string{"build 1", "build 2", "export"}))
+
+		Expect(replay(lines, models.LogOptions{})[0].Timestamp.IsZero()).To(BeTrue())
+		Expect(replay(lines, models.LogOptions{Timestamps: true})[0].Timestamp.IsZero()).To(BeFalse())
+	})
+})
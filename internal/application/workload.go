@@ -31,12 +31,35 @@ func NewWorkload(cluster *kubernetes.Cluster, app models.AppRef) *Workload {
 	return &Workload{cluster: cluster, app: app}
 }
 
-// EnvironmentChange imports the current environment into the deployment
+// EnvironmentChange imports the current environment into the
+// deployment, and into the deployments of the non-web processes
 func (a *Workload) EnvironmentChange(ctx context.Context, varNames []string) error {
+	err := a.environmentChange(ctx, a.app.Name, varNames)
+	if err != nil {
+		return err
+	}
+
+	workers, err := a.workers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, worker := range workers {
+		err := a.environmentChange(ctx, worker.Name, varNames)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// environmentChange imports the current environment into the named deployment
+func (a *Workload) environmentChange(ctx context.Context, name string, varNames []string) error {
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		// Retrieve the latest version of Deployment before attempting update
 		// RetryOnConflict uses exponential backoff to avoid exhausting the apiserver
-		deployment, err := a.deployment(ctx)
+		deployment, err := a.cluster.Kubectl.AppsV1().Deployments(a.app.Org).Get(
+			ctx, name, metav1.GetOptions{})
 		if err != nil {
 			return err
 		}
@@ -132,6 +155,43 @@ func (a *Workload) Scale(ctx context.Context, instances int32) error {
 	})
 }
 
+// SetResources changes the memory and cpu of the application
+// instances, and of the instances of its workers. They are both
+// requested and limited to these values. An empty value keeps the
+// current setting.
+func (a *Workload) SetResources(ctx context.Context, memory, cpu string) error {
+	names, err := a.deploymentNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	deployments := a.cluster.Kubectl.AppsV1().Deployments(a.app.Org)
+	for _, name := range names {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			deployment, err := deployments.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			container := &deployment.Spec.Template.Spec.Containers[0]
+			if err := setResource(&container.Resources, corev1.ResourceMemory, memory); err != nil {
+				return err
+			}
+			if err := setResource(&container.Resources, corev1.ResourceCPU, cpu); err != nil {
+				return err
+			}
+
+			_, err = deployments.Update(ctx, deployment, metav1.UpdateOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // UnbindAll dissolves all bindings from the application.
 func (a *Workload) UnbindAll(ctx context.Context, cluster *kubernetes.Cluster, svcs []string) error {
 
@@ -152,63 +212,113 @@ func (a *Workload) UnbindAll(ctx context.Context, cluster *kubernetes.Cluster, s
 	return nil
 }
 
-// Unbind dissolves the binding of the service to the application.
+// Unbind dissolves the binding of the service to the application, and
+// its workers. The environment variables of the binding are removed
+// with it.
 func (a *Workload) Unbind(ctx context.Context, service interfaces.Service) error {
-	for {
-		deployment, err := a.deployment(ctx)
+	bindings, err := servicesJSON(ctx, a.cluster.Kubectl, a.app)
+	if err != nil {
+		return err
+	}
+	_, inJSON := bindings[service.Name()]
+	lastJSON := inJSON && len(bindings) == 1
+
+	names, err := a.deploymentNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	deployments := a.cluster.Kubectl.AppsV1().Deployments(a.app.Org)
+	for i, name := range names {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			deployment, err := deployments.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			if !unbindSpec(&deployment.Spec.Template.Spec, a.app, service.Name(), lastJSON) {
+				// The workers follow the application
+				if i == 0 {
+					return errors.New("service is not bound to the application")
+				}
+				return nil
+			}
+
+			_, err = deployments.Update(ctx, deployment, metav1.UpdateOptions{})
+			return err
+		})
 		if err != nil {
 			return err
 		}
+	}
 
-		volumes := deployment.Spec.Template.Spec.Volumes
-		newVolumes := []corev1.Volume{}
-		found := false
-		for _, volume := range volumes {
-			if volume.Name == service.Name() {
-				found = true
-			} else {
-				newVolumes = append(newVolumes, volume)
-			}
+	if inJSON {
+		_, err := UpdateServicesJSON(ctx, a.cluster.Kubectl, a.app, metav1.OwnerReference{}, service.Name(), nil)
+		if err != nil {
+			return err
 		}
-		if !found {
-			return errors.New("service is not bound to the application")
+	}
+	if lastJSON {
+		err := a.cluster.Kubectl.CoreV1().Secrets(a.app.Org).Delete(ctx, ServicesSecret(a.app), metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
 		}
+	}
 
-		// TODO: Iterate over containers and find the one matching the app name
-		volumeMounts := deployment.Spec.Template.Spec.Containers[0].VolumeMounts
-		newVolumeMounts := []corev1.VolumeMount{}
-		found = false
-		for _, mount := range volumeMounts {
-			if mount.Name == service.Name() {
-				found = true
-			} else {
-				newVolumeMounts = append(newVolumeMounts, mount)
+	// delete binding - DeleteBinding(a.Name)
+	return service.DeleteBinding(ctx, a.app.Name, a.app.Org)
+}
+
+// unbindSpec removes the volume, mount and environment variables of the
+// binding of the service from the pod spec, and the JSON of the
+// bindings too, for the last binding in it. It returns false if the
+// service is not bound.
+func unbindSpec(spec *corev1.PodSpec, appRef models.AppRef, service string, lastJSON bool) bool {
+	newVolumes := []corev1.Volume{}
+	bindSecretName := ""
+	found := false
+	for _, volume := range spec.Volumes {
+		if volume.Name == service {
+			found = true
+			if volume.Secret != nil {
+				bindSecretName = volume.Secret.SecretName
 			}
+		} else {
+			newVolumes = append(newVolumes, volume)
 		}
-		if !found {
-			return errors.New("service is not bound to the application")
-		}
+	}
+	if !found {
+		return false
+	}
 
-		deployment.Spec.Template.Spec.Volumes = newVolumes
-		deployment.Spec.Template.Spec.Containers[0].VolumeMounts = newVolumeMounts
+	// TODO: Iterate over containers and find the one matching the app name
+	container := &spec.Containers[0]
 
-		_, err = a.cluster.Kubectl.AppsV1().Deployments(a.app.Org).Update(
-			ctx,
-			deployment,
-			metav1.UpdateOptions{},
-		)
-		if err == nil {
-			break
+	newEnvironment := []corev1.EnvVar{}
+	for _, ev := range container.Env {
+		if ev.ValueFrom != nil &&
+			ev.ValueFrom.SecretKeyRef != nil &&
+			ev.ValueFrom.SecretKeyRef.Name == bindSecretName {
+			continue
 		}
-		if !apierrors.IsConflict(err) {
-			return err
+		if lastJSON && isServicesEnv(ev, appRef) {
+			continue
 		}
+		newEnvironment = append(newEnvironment, ev)
+	}
 
-		// Found a conflict. Try again from the beginning.
+	newVolumeMounts := []corev1.VolumeMount{}
+	for _, mount := range container.VolumeMounts {
+		if mount.Name != service {
+			newVolumeMounts = append(newVolumeMounts, mount)
+		}
 	}
 
-	// delete binding - DeleteBinding(a.Name)
-	return service.DeleteBinding(ctx, a.app.Name, a.app.Org)
+	spec.Volumes = newVolumes
+	container.VolumeMounts = newVolumeMounts
+	container.Env = newEnvironment
+
+	return true
 }
 
 func (a *Workload) deployment(ctx context.Context) (*appsv1.Deployment, error) {
@@ -217,63 +327,179 @@ func (a *Workload) deployment(ctx context.Context) (*appsv1.Deployment, error) {
 	)
 }
 
-// Bind creates a binding of the service to the application.
-func (a *Workload) Bind(ctx context.Context, service interfaces.Service) error {
+// deploymentNames returns the names of the deployment of the
+// application, first, and of the deployments of its workers
+func (a *Workload) deploymentNames(ctx context.Context) ([]string, error) {
+	workers, err := a.workers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{a.app.Name}
+	for _, worker := range workers {
+		names = append(names, worker.Name)
+	}
+	return names, nil
+}
+
+// workers returns the deployments of the non-web processes of the application
+func (a *Workload) workers(ctx context.Context) ([]appsv1.Deployment, error) {
+	l, err := a.cluster.Kubectl.AppsV1().Deployments(a.app.Org).List(ctx, metav1.ListOptions{
+		LabelSelector: workerSelector(a.app),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l.Items, nil
+}
+
+// Bind creates a binding of the service to the application, and its
+// workers. The binding is mounted as files at /services/NAME, and, as
+// selected by the options, passed as environment variables, one per
+// key, and as part of the JSON of all bindings in ServicesEnvVar.
+func (a *Workload) Bind(ctx context.Context, service interfaces.Service, options models.BindOptions) error {
 	bindSecret, err := service.GetBinding(ctx, a.app.Name)
 	if err != nil {
 		return err
 	}
 
-	for {
-		deployment, err := a.deployment(ctx)
+	environment := []corev1.EnvVar{}
+	if options.Env {
+		environment, err = bindingEnv(service.Name(), bindSecret)
 		if err != nil {
 			return err
 		}
+	}
+
+	var owner metav1.OwnerReference
+	if options.JSON {
+		app, err := Get(ctx, a.cluster, a.app)
+		if err != nil {
+			return err
+		}
+		owner = metav1.OwnerReference{
+			APIVersion: app.GetAPIVersion(),
+			Kind:       app.GetKind(),
+			Name:       app.GetName(),
+			UID:        app.GetUID(),
+		}
+	}
 
-		volumes := deployment.Spec.Template.Spec.Volumes
+	names, err := a.deploymentNames(ctx)
+	if err != nil {
+		return err
+	}
 
-		for _, volume := range volumes {
-			if volume.Name == service.Name() {
-				return errors.New("service already bound")
+	deployments := a.cluster.Kubectl.AppsV1().Deployments(a.app.Org)
+	for i, name := range names {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			deployment, err := deployments.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
 			}
-		}
 
-		volumes = append(volumes, corev1.Volume{
-			Name: service.Name(),
-			VolumeSource: corev1.VolumeSource{
-				Secret: &corev1.SecretVolumeSource{
-					SecretName: bindSecret.Name,
-				},
-			},
-		})
-		// TODO: Iterate over containers and find the one matching the app name
-		volumeMounts := deployment.Spec.Template.Spec.Containers[0].VolumeMounts
-		volumeMounts = append(volumeMounts, corev1.VolumeMount{
-			Name:      service.Name(),
-			ReadOnly:  true,
-			MountPath: fmt.Sprintf("/services/%s", service.Name()),
+			bound, err := bindSpec(&deployment.Spec.Template.Spec, a.app, service.Name(), bindSecret.Name, environment, options.JSON)
+			if err != nil {
+				return err
+			}
+			if !bound {
+				// The workers follow the application
+				if i == 0 {
+					return errors.New("service already bound")
+				}
+				return nil
+			}
+
+			_, err = deployments.Update(ctx, deployment, metav1.UpdateOptions{})
+			return err
 		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The JSON is changed only with the workload bound. Until then the
+	// instances wait for it.
+	if options.JSON {
+		_, err = UpdateServicesJSON(ctx, a.cluster.Kubectl, a.app, owner, service.Name(), bindSecret.Data)
+		if err != nil {
+			return err
+		}
+	}
 
-		deployment.Spec.Template.Spec.Volumes = volumes
-		deployment.Spec.Template.Spec.Containers[0].VolumeMounts = volumeMounts
+	return nil
+}
 
-		_, err = a.cluster.Kubectl.AppsV1().Deployments(a.app.Org).Update(
-			ctx,
-			deployment,
-			metav1.UpdateOptions{},
-		)
+// bindSpec adds the volume and mount of the binding of the service to
+// the pod spec, with the environment variables of the binding, and the
+// JSON of the bindings, if asked for. It returns false if the service
+// is bound already, and an EnvConflictError if a variable is already
+// set.
+func bindSpec(spec *corev1.PodSpec, appRef models.AppRef, service, bindSecretName string, environment []corev1.EnvVar, withJSON bool) (bool, error) {
+	for _, volume := range spec.Volumes {
+		if volume.Name == service {
+			return false, nil
+		}
+	}
+
+	// TODO: Iterate over containers and find the one matching the app name
+	container := &spec.Containers[0]
 
-		if err == nil {
-			break
+	added := append([]corev1.EnvVar{}, environment...)
+	if withJSON && !hasServicesEnv(container.Env, appRef) {
+		added = append(added, servicesEnv(appRef))
+	}
+	for _, ev := range added {
+		if hasEnv(container.Env, ev.Name) {
+			return false, EnvConflictError{Name: ev.Name}
 		}
-		if !apierrors.IsConflict(err) {
-			return err
+	}
+
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: service,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: bindSecretName,
+			},
+		},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      service,
+		ReadOnly:  true,
+		MountPath: fmt.Sprintf("/services/%s", service),
+	})
+	container.Env = append(container.Env, added...)
+
+	return true, nil
+}
+
+// hasEnv returns true if the named environment variable is set
+func hasEnv(environment []corev1.EnvVar, name string) bool {
+	for _, ev := range environment {
+		if ev.Name == name {
+			return true
 		}
+	}
+	return false
+}
 
-		// Found a conflict. Try again from the beginning.
+// hasServicesEnv returns true if the JSON of the bindings of the
+// application is set
+func hasServicesEnv(environment []corev1.EnvVar, appRef models.AppRef) bool {
+	for _, ev := range environment {
+		if isServicesEnv(ev, appRef) {
+			return true
+		}
 	}
+	return false
+}
 
-	return nil
+// isServicesEnv returns true if the environment variable is the JSON of
+// the bindings of the application
+func isServicesEnv(ev corev1.EnvVar, appRef models.AppRef) bool {
+	return ev.Name == ServicesEnvVar &&
+		ev.ValueFrom != nil &&
+		ev.ValueFrom.SecretKeyRef != nil &&
+		ev.ValueFrom.SecretKeyRef.Name == ServicesSecret(appRef)
 }
 
 // Complete fills all fields of a workload with values from the cluster
@@ -284,7 +510,7 @@ func (a *Workload) Complete(ctx context.Context) (*models.App, error) {
 
 	// Query application deployment for stageID and status (ready vs desired replicas)
 
-	deploymentSelector := fmt.Sprintf("app.kubernetes.io/part-of=%s,app.kubernetes.io/name=%s", a.app.Org, a.app.Name)
+	deploymentSelector := fmt.Sprintf("app.kubernetes.io/component=application,app.kubernetes.io/part-of=%s,app.kubernetes.io/name=%s", a.app.Org, a.app.Name)
 
 	deploymentListOptions := metav1.ListOptions{
 		LabelSelector: deploymentSelector,
@@ -301,17 +527,46 @@ func (a *Workload) Complete(ctx context.Context) (*models.App, error) {
 			deployments.Items[0].Status.ReadyReplicas,
 			deployments.Items[0].Status.Replicas)
 
+		if deployments.Items[0].Spec.Replicas != nil {
+			app.Instances = *deployments.Items[0].Spec.Replicas
+		}
+
 		app.StageID = deployments.Items[0].
 			Spec.Template.ObjectMeta.Labels["epinio.suse.org/stage-id"]
 
+		app.Port = deploymentPort(&deployments.Items[0])
+		app.HealthChecks = deploymentHealthChecks(&deployments.Items[0])
+		app.Memory, app.CPU = deploymentResources(&deployments.Items[0])
+
 		app.Active = true
 	}
 
+	workers, err := a.workers(ctx)
+	if err != nil {
+		app.Workers = []string{err.Error()}
+	} else {
+		for _, worker := range workers {
+			app.Workers = append(app.Workers, worker.Labels[models.EpinioProcessLabel])
+		}
+	}
+
 	app.Routes, err = a.cluster.ListIngressRoutes(ctx, app.Organization, app.Name)
 	if err != nil {
 		app.Routes = []string{err.Error()}
 	}
 
+	// Without access to the autoscaler the app is shown as not autoscaled
+	autoscaling, err := Autoscaler(ctx, a.cluster.Kubectl, a.app)
+	if err == nil {
+		app.Autoscaling = autoscaling
+	}
+
+	// Without access to the application resource the app is shown as running
+	stopped, err := Stopped(ctx, a.cluster, a.app)
+	if err == nil {
+		app.Stopped = stopped
+	}
+
 	app.BoundServices = []string{}
 	bound, err := a.Services(ctx)
 	if err != nil {
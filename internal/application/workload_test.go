@@ -0,0 +1,165 @@
+package application
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Workload bindings", func() {
+	var (
+		appRef     models.AppRef
+		bindSecret *corev1.Secret
+		spec       *corev1.PodSpec
+	)
+
+	secretRef := func(name, secret, key string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+					Key:                  key,
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		appRef = models.NewAppRef("app", "workspace")
+		bindSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "mydb-binding"},
+			Data: map[string][]byte{
+				"user":     []byte("admin"),
+				"password": []byte("secret"),
+			},
+		}
+		spec = &corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "app",
+				Env: []corev1.EnvVar{
+					{Name: "PORT", Value: "8080"},
+					secretRef("MODE", EnvSecret(appRef), "MODE"),
+				},
+			}},
+		}
+	})
+
+	Describe("bindingEnv", func() {
+		It("refers to the keys of the binding secret, by name", func() {
+			env, err := bindingEnv("mydb", bindSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(env).To(Equal([]corev1.EnvVar{
+				secretRef("MYDB_PASSWORD", "mydb-binding", "password"),
+				secretRef("MYDB_USER", "mydb-binding", "user"),
+			}))
+		})
+
+		It("refuses keys mapping to the same variable", func() {
+			bindSecret.Data["db.host"] = []byte("a")
+			bindSecret.Data["db_host"] = []byte("b")
+
+			_, err := bindingEnv("svc", bindSecret)
+			Expect(err).To(Equal(EnvConflictError{Name: "SVC_DB_HOST", Keys: []string{"db.host", "db_host"}}))
+			Expect(err.Error()).To(Equal("keys 'db.host', 'db_host' of the binding all map to the environment variable 'SVC_DB_HOST'"))
+		})
+	})
+
+	Describe("bindSpec and unbindSpec", func() {
+		It("binds the service with its variables and the JSON, and unbinds it", func() {
+			env, err := bindingEnv("mydb", bindSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			bound, err := bindSpec(spec, appRef, "mydb", "mydb-binding", env, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bound).To(BeTrue())
+			Expect(spec.Volumes).To(HaveLen(1))
+			Expect(spec.Volumes[0].Secret.SecretName).To(Equal("mydb-binding"))
+			Expect(spec.Containers[0].VolumeMounts).To(ConsistOf(corev1.VolumeMount{
+				Name: "mydb", ReadOnly: true, MountPath: "/services/mydb",
+			}))
+			Expect(spec.Containers[0].Env).To(HaveLen(5))
+			Expect(hasServicesEnv(spec.Containers[0].Env, appRef)).To(BeTrue())
+
+			By("leaving a bound service alone")
+			bound, err = bindSpec(spec, appRef, "mydb", "mydb-binding", env, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bound).To(BeFalse())
+			Expect(spec.Volumes).To(HaveLen(1))
+
+			By("unbinding")
+			Expect(unbindSpec(spec, appRef, "mydb", true)).To(BeTrue())
+			Expect(spec.Volumes).To(BeEmpty())
+			Expect(spec.Containers[0].VolumeMounts).To(BeEmpty())
+			Expect(spec.Containers[0].Env).To(Equal([]corev1.EnvVar{
+				{Name: "PORT", Value: "8080"},
+				secretRef("MODE", EnvSecret(appRef), "MODE"),
+			}))
+
+			Expect(unbindSpec(spec, appRef, "mydb", true)).To(BeFalse())
+		})
+
+		It("keeps the JSON for the other bindings in it", func() {
+			bound, err := bindSpec(spec, appRef, "mydb", "mydb-binding", nil, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bound).To(BeTrue())
+
+			Expect(unbindSpec(spec, appRef, "mydb", false)).To(BeTrue())
+			Expect(hasServicesEnv(spec.Containers[0].Env, appRef)).To(BeTrue())
+		})
+
+		It("refuses a variable set already, and changes nothing", func() {
+			spec.Containers[0].Env = append(spec.Containers[0].Env, corev1.EnvVar{Name: "MYDB_USER", Value: "me"})
+			env, err := bindingEnv("mydb", bindSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = bindSpec(spec, appRef, "mydb", "mydb-binding", env, false)
+			Expect(err).To(Equal(EnvConflictError{Name: "MYDB_USER"}))
+			Expect(spec.Volumes).To(BeEmpty())
+			Expect(spec.Containers[0].Env).To(HaveLen(3))
+		})
+	})
+
+	Describe("checkBoundEnv", func() {
+		var (
+			ctx    context.Context
+			client *fake.Clientset
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			client = fake.NewSimpleClientset()
+		})
+
+		It("accepts anything without deployment", func() {
+			Expect(checkBoundEnv(ctx, client, appRef, models.EnvVariableList{{Name: "MYDB_USER"}})).To(Succeed())
+		})
+
+		It("refuses the variables of a binding only", func() {
+			spec.Containers[0].Env = append(spec.Containers[0].Env, secretRef("MYDB_USER", "mydb-binding", "user"))
+			_, err := client.AppsV1().Deployments("workspace").Create(ctx, &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "workspace"},
+				Spec: appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{Spec: *spec},
+				},
+			}, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(checkBoundEnv(ctx, client, appRef, models.EnvVariableList{
+				{Name: "MODE"}, {Name: "PORT"}, {Name: "OTHER"},
+			})).To(Succeed())
+
+			err = checkBoundEnv(ctx, client, appRef, models.EnvVariableList{{Name: "MYDB_USER", Value: "me"}})
+			Expect(err).To(Equal(EnvConflictError{Name: "MYDB_USER", Bound: true}))
+			Expect(err.Error()).To(Equal("environment variable 'MYDB_USER' is set by a service binding"))
+		})
+	})
+})
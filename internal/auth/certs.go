@@ -44,11 +44,13 @@ func ExtendLocalTrust(certs string) {
 }
 
 // CertParam describes the cert-manager certificate CRD. It's passed to
-// CreateCertificate to create the cert-manager certificate CR.
+// CreateCertificate to create the cert-manager certificate CR. The
+// certificate is for Host, or for Name.Domain when no Host is given.
 type CertParam struct {
 	Name      string
 	Namespace string
 	Domain    string
+	Host      string
 	Issuer    string
 }
 
@@ -83,6 +85,29 @@ func CreateCertificate(
 	return nil
 }
 
+// DeleteCertificate removes the named certificate resource, and the
+// secret holding the issued certificate. A missing certificate is not
+// an error.
+func DeleteCertificate(ctx context.Context, cluster *kubernetes.Cluster, namespace, name string) error {
+	client, err := cluster.ClientCertificate()
+	if err != nil {
+		return err
+	}
+
+	err = client.Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	// cert-manager keeps the secret of a deleted certificate
+	err = cluster.Kubectl.CoreV1().Secrets(namespace).Delete(ctx, name+"-tls", metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
 func newCertificate(cert CertParam) (*unstructured.Unstructured, error) {
 	// Notes:
 	// - spec.CommonName is length-limited.
@@ -99,7 +124,11 @@ func newCertificate(cert CertParam) (*unstructured.Unstructured, error) {
 	//   full string as means of keeping the text unique across
 	//   apps.
 
-	cn := names.TruncateMD5(fmt.Sprintf("%s.%s", cert.Name, cert.Domain), 64)
+	host := cert.Host
+	if host == "" {
+		host = fmt.Sprintf("%s.%s", cert.Name, cert.Domain)
+	}
+	cn := names.TruncateMD5(host, 64)
 	data := fmt.Sprintf(`{
 		"apiVersion": "cert-manager.io/v1alpha2",
 		"kind": "Certificate",
@@ -110,14 +139,14 @@ func newCertificate(cert CertParam) (*unstructured.Unstructured, error) {
 			"commonName" : "%[2]s",
 			"secretName" : "%[1]s-tls",
 			"dnsNames": [
-				"%[1]s.%[3]s"
+				"%[3]s"
 			],
 			"issuerRef" : {
 				"name" : "%[4]s",
 				"kind" : "ClusterIssuer"
 			}
 		}
-        }`, cert.Name, cn, cert.Domain, cert.Issuer)
+        }`, cert.Name, cn, host, cert.Issuer)
 
 	decoderUnstructured := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
 	obj := &unstructured.Unstructured{}
@@ -1,8 +1,13 @@
 package cli
 
 import (
-	v1 "github.com/epinio/epinio/internal/api/v1"
+	"os"
+	"path/filepath"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
 	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/epinio/epinio/internal/manifest"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -23,20 +28,45 @@ var CmdApp = &cobra.Command{
 func init() {
 	flags := CmdAppLogs.Flags()
 	flags.Bool("follow", false, "follow the logs of the application")
-	flags.Bool("staging", false, "show the staging logs of the application")
+	flags.String("staging", "", "show the staging logs of the application, of the last staging run, or of the given one, as --staging=STAGE_ID")
+	flags.Lookup("staging").NoOptDefVal = lastStaging
+	flags.String("instance", "", "show only the logs of this instance, by name or index, as listed by \"epinio app show\"")
+	logFlags(flags) // See logs.go for implementation
 
 	updateFlags := CmdAppUpdate.Flags()
 	updateFlags.Int32P("instances", "i", 1, "The number of instances the application should have")
-	err := cobra.MarkFlagRequired(updateFlags, "instances")
-	if err != nil {
-		panic(err)
-	}
+	updateFlags.String("memory", "", "The memory of each instance, e.g. 256Mi")
+	updateFlags.String("cpu", "", "The cpu of each instance, e.g. 500m")
+
+	autoscaleFlags := CmdAppAutoscale.Flags()
+	autoscaleFlags.Int32("min", application.DefaultAutoscaleMin, "The least number of instances")
+	autoscaleFlags.Int32("max", 0, "The largest number of instances")
+	autoscaleFlags.Int32("cpu-percent", application.DefaultAutoscaleCPUPercent, "The cpu usage to aim for, in percent of the cpu of an instance")
+	autoscaleFlags.Bool("off", false, "Turn the autoscaling off, keeping the current instances")
+
+	CmdAppExec.Flags().String("instance", "", "Name or index of the instance to run the command in, as listed by \"epinio app show\". Defaults to the first ready instance")
 
+	CmdAppPortForward.Flags().String("instance", "", "Name or index of the instance to forward to, as listed by \"epinio app show\". Defaults to the first ready instance")
+	CmdAppPortForward.Flags().String("address", "localhost", "Address to listen on for the local ports")
+
+	CmdApp.AddCommand(CmdAppAutoscale)
+	CmdApp.AddCommand(CmdAppCache) // See cache.go for implementation
 	CmdApp.AddCommand(CmdAppCreate)
 	CmdApp.AddCommand(CmdAppEnv) // See env.go for implementation
+	CmdApp.AddCommand(CmdAppExec)
+	CmdApp.AddCommand(CmdAppExportManifest)
 	CmdApp.AddCommand(CmdAppList)
 	CmdApp.AddCommand(CmdAppLogs)
+	CmdApp.AddCommand(CmdAppPortForward)
+	CmdApp.AddCommand(CmdAppReleases)
+	CmdApp.AddCommand(CmdAppRestart)
+	CmdApp.AddCommand(CmdAppRollback)
+	CmdApp.AddCommand(CmdAppRoute) // See routes.go for implementation
 	CmdApp.AddCommand(CmdAppShow)
+	CmdApp.AddCommand(CmdAppStage) // See stage.go for implementation
+	CmdApp.AddCommand(CmdAppStart)
+	CmdApp.AddCommand(CmdAppStop)
+	CmdApp.AddCommand(CmdAppTop)
 	CmdApp.AddCommand(CmdAppUpdate)
 	CmdApp.AddCommand(CmdDeleteApp)
 	CmdApp.AddCommand(CmdPush) // See push.go for implementation
@@ -138,6 +168,93 @@ var CmdAppShow = &cobra.Command{
 	},
 }
 
+// CmdAppTop implements the epinio `apps top` command
+var CmdAppTop = &cobra.Command{
+	Use:   "top NAME",
+	Short: "Show the resource usage and traffic of the named application",
+	Long: `Show the cpu and memory usage of the instances of the named application, and, where linkerd meshes them, their requests.
+The usage needs a metrics API in the cluster, like the one of the metrics-server.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppTop(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error showing app metrics")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppExportManifest implements the epinio `apps export-manifest` command
+var CmdAppExportManifest = &cobra.Command{
+	Use:   "export-manifest NAME [PATH]",
+	Short: "Save the configuration of the named application as manifest",
+	Long:  "Save the configuration of the named application as manifest file, by default " + manifest.FileName + " in the current working directory",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		path := manifest.FileName
+		if len(args) == 2 {
+			path = args[1]
+		}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			path = filepath.Join(path, manifest.FileName)
+		}
+
+		err = client.AppExportManifest(args[0], path)
+		if err != nil {
+			return errors.Wrap(err, "error exporting manifest")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// lastStaging is the value of the --staging option of `apps logs` given
+// without a stage ID
+const lastStaging = "last"
+
 // CmdAppLogs implements the epinio `apps logs` command
 var CmdAppLogs = &cobra.Command{
 	Use:   "logs NAME",
@@ -156,22 +273,36 @@ var CmdAppLogs = &cobra.Command{
 			return errors.Wrap(err, "error reading option --follow")
 		}
 
-		staging, err := cmd.Flags().GetBool("staging")
+		stageID, err := cmd.Flags().GetString("staging")
 		if err != nil {
 			return errors.Wrap(err, "error reading option --staging")
 		}
 
-		stageID, err := client.AppStageID(args[0])
-		if err != nil {
-			return errors.Wrap(err, "error checking app")
+		if stageID == lastStaging {
+			stageID, err = client.AppStageID(args[0])
+			if err != nil {
+				return errors.Wrap(err, "error checking app")
+			}
 		}
-		if staging {
+		if stageID != "" {
 			follow = false
-		} else {
-			stageID = ""
 		}
 
-		err = client.AppLogs(args[0], stageID, follow, nil)
+		options, err := logOptions(cmd)
+		if err != nil {
+			return err
+		}
+		options.Instance, err = cmd.Flags().GetString("instance")
+		if err != nil {
+			return errors.Wrap(err, "error reading option --instance")
+		}
+
+		output, err := logOutput(cmd, follow)
+		if err != nil {
+			return err
+		}
+
+		err = client.AppLogs(args[0], stageID, follow, options, output, nil)
 		if err != nil {
 			return errors.Wrap(err, "error streaming application logs")
 		}
@@ -180,12 +311,372 @@ var CmdAppLogs = &cobra.Command{
 	},
 }
 
+// CmdAppReleases implements the epinio `apps releases` command
+var CmdAppReleases = &cobra.Command{
+	Use:   "releases NAME",
+	Short: "List the release history of the named application",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppReleases(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error listing releases")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppAutoscale implements the epinio `apps autoscale` command
+var CmdAppAutoscale = &cobra.Command{
+	Use:   "autoscale NAME",
+	Short: "Autoscale the named application",
+	Long: `Scale the instances of the named application between --min and --max, keeping their cpu usage near --cpu-percent.
+The usage is relative to the cpu of an instance, as set by "epinio app update --cpu", or the default of the org.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		off, err := cmd.Flags().GetBool("off")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --off")
+		}
+		if off {
+			err = client.AppAutoscaleOff(args[0])
+			if err != nil {
+				return errors.Wrap(err, "error turning off app autoscaling")
+			}
+			return nil
+		}
+
+		var req models.AutoscaleRequest
+		min, err := cmd.Flags().GetInt32("min")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --min")
+		}
+		req.Min = &min
+		req.Max, err = cmd.Flags().GetInt32("max")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --max")
+		}
+		req.CPUPercent, err = cmd.Flags().GetInt32("cpu-percent")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --cpu-percent")
+		}
+		if req.Max < 1 || min < 1 || min > req.Max || req.CPUPercent < 1 {
+			cmd.SilenceUsage = false
+			return errors.New("expected 0 < --min <= --max, and --cpu-percent greater than zero")
+		}
+
+		err = client.AppAutoscale(args[0], req)
+		if err != nil {
+			return errors.Wrap(err, "error autoscaling app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppExec implements the epinio `apps exec` command
+var CmdAppExec = &cobra.Command{
+	Use:   "exec NAME [-- COMMAND [ARG...]]",
+	Short: "Run a command in an instance of the named application",
+	Long: `Run a command in an instance of the named application, by default a shell.
+The command is connected to the terminal, and gets one of its own when stdin and stdout are a terminal.
+The exit code of the command becomes the exit code of epinio.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("requires the name of the application")
+		}
+		if dash := cmd.ArgsLenAtDash(); dash > 1 || (dash < 0 && len(args) > 1) {
+			return errors.New("the command has to follow --")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		instance, err := cmd.Flags().GetString("instance")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --instance")
+		}
+
+		code, err := client.AppExec(args[0], instance, args[1:])
+		if err != nil {
+			return errors.Wrap(err, "error running command in app")
+		}
+		if code != 0 {
+			os.Exit(code)
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppPortForward implements the epinio `apps port-forward` command
+var CmdAppPortForward = &cobra.Command{
+	Use:   "port-forward NAME [LOCAL:]REMOTE...",
+	Short: "Forward local ports to an instance of the named application",
+	Long: `Forward local ports to the ports of an instance of the named application, through the Epinio API, until interrupted.
+A single port is used for both sides. A local port of 0 picks a free one.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		instance, err := cmd.Flags().GetString("instance")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --instance")
+		}
+		address, err := cmd.Flags().GetString("address")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --address")
+		}
+
+		err = client.AppPortForward(cmd.Context(), args[0], instance, address, args[1:])
+		if err != nil {
+			return errors.Wrap(err, "error forwarding ports")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppRestart implements the epinio `apps restart` command
+var CmdAppRestart = &cobra.Command{
+	Use:   "restart NAME",
+	Short: "Restart the named application",
+	Long:  "Replace the instances of the named application and of its workers one by one, keeping the application available",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppRestart(cmd.Context(), args[0])
+		if err != nil {
+			return errors.Wrap(err, "error restarting app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppRollback implements the epinio `apps rollback` command
+var CmdAppRollback = &cobra.Command{
+	Use:   "rollback NAME [RELEASE]",
+	Short: "Deploy a previous release of the named application",
+	Long:  "Deploy a previous release of the named application again, without staging. Defaults to the release before the active one",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		release := ""
+		if len(args) == 2 {
+			release = args[1]
+		}
+
+		err = client.AppRollback(cmd.Context(), args[0], release)
+		if err != nil {
+			return errors.Wrap(err, "error rolling back the app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppStart implements the epinio `apps start` command
+var CmdAppStart = &cobra.Command{
+	Use:   "start NAME",
+	Short: "Start the named application",
+	Long:  "Bring the instances of the stopped application and of its workers back, as many as they had before the stop",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppStart(cmd.Context(), args[0])
+		if err != nil {
+			return errors.Wrap(err, "error starting app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppStop implements the epinio `apps stop` command
+var CmdAppStop = &cobra.Command{
+	Use:   "stop NAME",
+	Short: "Stop the named application",
+	Long:  "Remove all instances of the named application and of its workers. The application keeps its configuration, and \"epinio app start\" brings the instances back",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppStop(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error stopping app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
 // CmdAppUpdate is used by the epinio `apps update` command to scale
 // a single app
 var CmdAppUpdate = &cobra.Command{
 	Use:   "update NAME",
 	Short: "Update the named application",
-	Long:  "Update the running application's attributes (e.g. instances)",
+	Long:  "Update the running application's attributes (e.g. instances, memory and cpu)",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
@@ -200,11 +691,20 @@ var CmdAppUpdate = &cobra.Command{
 		if err != nil {
 			return errors.Wrap(err, "trouble with instances")
 		}
-		if i == nil {
-			d := v1.DefaultInstances
-			i = &d
+		memory, err := cmd.Flags().GetString("memory")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --memory")
+		}
+		cpu, err := cmd.Flags().GetString("cpu")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --cpu")
 		}
-		err = client.AppUpdate(args[0], *i)
+		if i == nil && memory == "" && cpu == "" {
+			cmd.SilenceUsage = false
+			return errors.New("nothing to update, expected --instances, --memory or --cpu")
+		}
+
+		err = client.AppUpdate(args[0], i, memory, cpu)
 		if err != nil {
 			return errors.Wrap(err, "error updating the app")
 		}
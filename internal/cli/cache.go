@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdAppCache implements the `epinio app cache` command ensemble
+var CmdAppCache = &cobra.Command{
+	Use:           "cache",
+	Short:         "Epinio application build cache",
+	Long:          `Manage the build cache of epinio applications`,
+	Args:          cobra.ExactArgs(0),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	CmdAppCache.AddCommand(CmdAppCacheClear)
+}
+
+// CmdAppCacheClear implements the `epinio app cache clear` command
+var CmdAppCacheClear = &cobra.Command{
+	Use:   "clear NAME",
+	Short: "Clear application build cache",
+	Long:  "Remove the build cache of the named application. The next push stages it from scratch",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppCacheClear(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error clearing the build cache")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
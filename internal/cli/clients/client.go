@@ -11,32 +11,32 @@ import (
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/avast/retry-go"
 	"github.com/epinio/epinio/deployments"
 	"github.com/epinio/epinio/helpers/kubernetes"
-	"github.com/epinio/epinio/helpers/kubernetes/tailer"
 	"github.com/epinio/epinio/helpers/termui"
 	"github.com/epinio/epinio/helpers/tracelog"
 	api "github.com/epinio/epinio/internal/api/v1"
 	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/epinio/epinio/internal/application"
 	"github.com/epinio/epinio/internal/cli/config"
-	"github.com/epinio/epinio/internal/cli/logprinter"
-	"github.com/epinio/epinio/internal/domain"
 	"github.com/epinio/epinio/internal/duration"
+	"github.com/epinio/epinio/internal/manifest"
 	"github.com/epinio/epinio/internal/services"
 
 	"github.com/go-logr/logr"
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
+	kubeduration "k8s.io/apimachinery/pkg/util/duration"
 	"k8s.io/apimachinery/pkg/util/validation"
 )
 
@@ -51,9 +51,22 @@ type EpinioClient struct {
 	wsServerURL string
 }
 
+// PushParams holds the optional settings of a push, i.e. everything
+// beyond name and sources.
 type PushParams struct {
-	Instances *int32
-	Services  []string
+	Instances        *int32
+	Services         []string
+	Routes           []string
+	ContainerImage   string
+	BuildStrategy    string
+	BuilderImage     string
+	Buildpacks       []string
+	ProcessType      string
+	Port             int32
+	Workers          []models.Process
+	HealthChecks     models.HealthChecks
+	Environment      models.EnvVariableList
+	BuildEnvironment models.EnvVariableList
 }
 
 func NewEpinioClient(ctx context.Context, flags *pflag.FlagSet) (*EpinioClient, error) {
@@ -208,6 +221,140 @@ func (c *EpinioClient) EnvUnset(ctx context.Context, appName, envName string) er
 	return nil
 }
 
+// AppStageCancel stops the staging of the named application which is
+// in progress
+func (c *EpinioClient) AppStageCancel(appName string) error {
+	log := c.Log.WithName("AppStageCancel").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Cancelling the staging of application")
+
+	jsonResponse, err := c.get(api.Routes.Path("AppShow", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+	var app models.App
+	if err := json.Unmarshal(jsonResponse, &app); err != nil {
+		return err
+	}
+	if app.Staging == "" {
+		return errors.New("application is not staging")
+	}
+	stageID := app.Staging
+
+	err = c.stagingCancel(c.Config.Org, stageID)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().WithStringValue("Staging ID", stageID).Msg("Staging cancelled.")
+
+	return nil
+}
+
+// AppRoutes lists the routes of the named application
+func (c *EpinioClient) AppRoutes(appName string) error {
+	log := c.Log.WithName("AppRoutes").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Show application routes")
+
+	jsonResponse, err := c.get(api.Routes.Path("AppRoutes", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+	var routes []string
+	if err := json.Unmarshal(jsonResponse, &routes); err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Route")
+	for _, route := range routes {
+		msg = msg.WithTableRow(fmt.Sprintf("https://%s", route))
+	}
+	msg.Msg("Routes:")
+
+	return nil
+}
+
+// AppRouteAdd makes the named application reachable under the route
+func (c *EpinioClient) AppRouteAdd(appName, route string) error {
+	log := c.Log.WithName("AppRouteAdd").WithValues("Organization", c.Config.Org, "Application", appName, "Route", route)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Route", route).
+		Msg("Adding route to application")
+
+	js, err := json.Marshal(models.RouteRequest{Route: route})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.post(api.Routes.Path("AppRouteAdd", c.Config.Org, appName), string(js))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().WithStringValue("Route", fmt.Sprintf("https://%s", route)).Msg("Route added.")
+
+	return nil
+}
+
+// AppRouteRemove removes the route from the named application
+func (c *EpinioClient) AppRouteRemove(appName, route string) error {
+	log := c.Log.WithName("AppRouteRemove").WithValues("Organization", c.Config.Org, "Application", appName, "Route", route)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Route", route).
+		Msg("Removing route from application")
+
+	_, err := c.delete(api.Routes.Path("AppRouteDelete", c.Config.Org, appName, route))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Route removed.")
+
+	return nil
+}
+
+// AppCacheClear removes the build cache of the named application
+func (c *EpinioClient) AppCacheClear(appName string) error {
+	log := c.Log.WithName("AppCacheClear").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Clearing the build cache of application")
+
+	_, err := c.delete(api.Routes.Path("AppCacheClear", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Build cache cleared.")
+
+	return nil
+}
+
 // EnvMatching retrieves all environment variables in the cluster, for
 // the specified application, and the given prefix
 func (c *EpinioClient) EnvMatching(ctx context.Context, appName, prefix string) []string {
@@ -470,8 +617,9 @@ func (c *EpinioClient) ServiceMatching(ctx context.Context, prefix string) []str
 }
 
 // BindService attaches a service specified by name to the named application,
-// both in the targeted organization.
-func (c *EpinioClient) BindService(serviceName, appName string) error {
+// both in the targeted organization. The options select whether the binding
+// is passed as environment variables too.
+func (c *EpinioClient) BindService(serviceName, appName string, options models.BindOptions) error {
 	log := c.Log.WithName("Bind Service To Application").
 		WithValues("Name", serviceName, "Application", appName, "Organization", c.Config.Org)
 	log.Info("start")
@@ -484,7 +632,8 @@ func (c *EpinioClient) BindService(serviceName, appName string) error {
 		Msg("Bind Service")
 
 	request := models.BindRequest{
-		Names: []string{serviceName},
+		Names:       []string{serviceName},
+		BindOptions: options,
 	}
 
 	js, err := json.Marshal(request)
@@ -786,10 +935,17 @@ func (c *EpinioClient) Info() error {
 	giteaVersion := "unavailable"
 
 	epinioVersion := "unavailable"
+	builders := "unavailable"
 	if jsonResponse, err := c.get(api.Routes.Path("Info")); err == nil {
-		v := struct{ Version string }{}
+		v := models.InfoResponse{}
 		if err := json.Unmarshal(jsonResponse, &v); err == nil {
 			epinioVersion = v.Version
+			if len(v.BuilderImages) > 0 {
+				builders = v.BuilderImages[0] + " (default)"
+				if len(v.BuilderImages) > 1 {
+					builders += ", " + strings.Join(v.BuilderImages[1:], ", ")
+				}
+			}
 		}
 	}
 
@@ -798,6 +954,7 @@ func (c *EpinioClient) Info() error {
 		WithStringValue("Kubernetes Version", kubeVersion).
 		WithStringValue("Gitea Version", giteaVersion).
 		WithStringValue("Epinio Version", epinioVersion).
+		WithStringValue("Builder Images", builders).
 		Msg("Epinio Environment")
 
 	return nil
@@ -863,7 +1020,7 @@ func (c *EpinioClient) Apps() error {
 	for _, app := range apps {
 		msg = msg.WithTableRow(
 			app.Name,
-			app.Status,
+			appStatus(app),
 			strings.Join(app.Routes, ", "),
 			strings.Join(app.BoundServices, ", "))
 	}
@@ -896,18 +1053,201 @@ func (c *EpinioClient) AppShow(appName string) error {
 		return err
 	}
 
-	c.ui.Success().
+	msg := c.ui.Success().
 		WithTable("Key", "Value").
-		WithTableRow("Status", app.Status).
+		WithTableRow("Status", appStatus(app)).
 		WithTableRow("StageId", app.StageID).
 		WithTableRow("Routes", strings.Join(app.Routes, ", ")).
 		WithTableRow("Services", strings.Join(app.BoundServices, ", ")).
-		WithTableRow("Environment", `See it by running the command "epinio app env list `+appName+`"`).
-		Msg("Details:")
+		WithTableRow("Environment", `See it by running the command "epinio app env list `+appName+`"`)
+	if app.Port != 0 {
+		msg = msg.WithTableRow("Port", strconv.Itoa(int(app.Port)))
+	}
+	if len(app.Workers) > 0 {
+		msg = msg.WithTableRow("Workers", strings.Join(app.Workers, ", "))
+	}
+	if app.Autoscaling != nil {
+		msg = msg.WithTableRow("Autoscaling", fmt.Sprintf("%d - %d instances at %d%% cpu, current %d, desired %d",
+			app.Autoscaling.Min, app.Autoscaling.Max, app.Autoscaling.CPUPercent,
+			app.Autoscaling.CurrentReplicas, app.Autoscaling.DesiredReplicas))
+	}
+	msg = msg.WithTableRow("Memory", resourceOrDefault(app.Memory)).
+		WithTableRow("CPU", resourceOrDefault(app.CPU))
+	if app.HealthChecks.Liveness != nil {
+		msg = msg.WithTableRow("Liveness", app.HealthChecks.Liveness.String())
+	}
+	if app.HealthChecks.Readiness != nil {
+		msg = msg.WithTableRow("Readiness", app.HealthChecks.Readiness.String())
+	}
+	if app.Staging != "" {
+		msg = msg.WithTableRow("Staging", app.Staging)
+	}
+	msg.Msg("Details:")
+
+	jsonResponse, err = c.get(api.Routes.Path("AppInstances", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+	var instances models.AppInstanceList
+	if err := json.Unmarshal(jsonResponse, &instances); err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		return nil
+	}
+
+	// The usage of the instances is shown when the cluster has a
+	// metrics API. Failing to get it does not fail the command.
+	usage := map[string]*models.ResourceUsage{}
+	metrics, err := c.appMetrics(appName, false)
+	if err != nil {
+		details.Info("metrics", "error", err.Error())
+	}
+	for _, instance := range metrics {
+		usage[instance.Name] = instance.Usage
+	}
+
+	headers := []string{"Instance", "Worker", "Phase", "Ready", "Restarts", "Reason", "Last Termination", "Node", "Age"}
+	if metrics.HasUsage() {
+		headers = append(headers, "CPU", "Memory")
+	}
+
+	msg = c.ui.Success().WithTable(headers...)
+	hasEvents := false
+	for _, instance := range instances {
+		row := []string{
+			instance.Name,
+			instance.Worker,
+			instance.Phase,
+			strconv.FormatBool(instance.Ready),
+			strconv.Itoa(int(instance.Restarts)),
+			instance.Reason,
+			instance.LastTermination,
+			instance.Node,
+			kubeduration.HumanDuration(time.Since(instance.Created)),
+		}
+		if metrics.HasUsage() {
+			row = append(row, usageColumns(usage[instance.Name])...)
+		}
+		msg = msg.WithTableRow(row...)
+		hasEvents = hasEvents || len(instance.Events) > 0
+	}
+	msg.Msg("Instances:")
+
+	if hasEvents {
+		msg = c.ui.Success().WithTable("Instance", "Event")
+		for _, instance := range instances {
+			for _, event := range instance.Events {
+				msg = msg.WithTableRow(instance.Name, event)
+			}
+		}
+		msg.Msg("Events:")
+	}
 
 	return nil
 }
 
+// AppExportManifest saves the configuration of the named app, in the
+// targeted org, as an application manifest into the file at path
+func (c *EpinioClient) AppExportManifest(appName, path string) error {
+	log := c.Log.WithName("Apps").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Manifest", path).
+		Msg("Export application manifest")
+
+	details.Info("show application")
+
+	jsonResponse, err := c.get(api.Routes.Path("AppShow", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+	var app models.App
+	if err := json.Unmarshal(jsonResponse, &app); err != nil {
+		return err
+	}
+
+	details.Info("list environment")
+
+	jsonResponse, err = c.get(api.Routes.Path("EnvList", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+	var environment models.EnvVariableList
+	if err := json.Unmarshal(jsonResponse, &environment); err != nil {
+		return err
+	}
+
+	details.Info("list releases")
+
+	jsonResponse, err = c.get(api.Routes.Path("AppReleases", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+	var releases models.ReleaseList
+	if err := json.Unmarshal(jsonResponse, &releases); err != nil {
+		return err
+	}
+
+	m := exportManifest(app, environment, releases)
+
+	details.Info("write manifest", "path", path)
+
+	if err := m.Write(path); err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Manifest exported")
+
+	return nil
+}
+
+// exportManifest returns the manifest of the app, with its environment.
+// The workers and the builder settings are those of its active
+// release, if any.
+func exportManifest(app models.App, environment models.EnvVariableList, releases models.ReleaseList) manifest.Manifest {
+	m := manifest.Manifest{
+		Name:     app.Name,
+		Services: app.BoundServices,
+		Routes:   app.Routes,
+	}
+	if app.Active {
+		instances := app.Instances
+		m.Instances = &instances
+		m.Port = app.Port
+	}
+	if app.HealthChecks.Liveness != nil || app.HealthChecks.Readiness != nil {
+		healthChecks := app.HealthChecks
+		m.HealthChecks = &healthChecks
+	}
+	if len(environment) > 0 {
+		m.Environment = map[string]string{}
+		for _, ev := range environment {
+			m.Environment[ev.Name] = ev.Value
+		}
+	}
+
+	for _, release := range releases {
+		if !release.Active {
+			continue
+		}
+		m.Workers = release.Processes
+		m.BuilderImage = release.BuilderImage
+		m.Buildpacks = release.Buildpacks
+		m.ProcessType = release.ProcessType
+		if m.Port == 0 {
+			m.Port = release.Port
+		}
+	}
+
+	return m
+}
+
 // AppStageID returns the stage id of the named app, in the targeted org
 func (c *EpinioClient) AppStageID(appName string) (string, error) {
 	log := c.Log.WithName("Apps").WithValues("Organization", c.Config.Org, "Application", appName)
@@ -931,7 +1271,7 @@ func (c *EpinioClient) AppStageID(appName string) (string, error) {
 }
 
 // AppUpdate updates the specified running application's attributes (e.g. instances)
-func (c *EpinioClient) AppUpdate(appName string, instances int32) error {
+func (c *EpinioClient) AppUpdate(appName string, instances *int32, memory, cpu string) error {
 	log := c.Log.WithName("Apps").WithValues("Organization", c.Config.Org, "Application", appName)
 	log.Info("start")
 	defer log.Info("return")
@@ -946,6 +1286,8 @@ func (c *EpinioClient) AppUpdate(appName string, instances int32) error {
 
 	data, err := json.Marshal(models.UpdateAppRequest{
 		Instances: instances,
+		Memory:    memory,
+		CPU:       cpu,
 	})
 	if err != nil {
 		return err
@@ -961,33 +1303,152 @@ func (c *EpinioClient) AppUpdate(appName string, instances int32) error {
 	return nil
 }
 
-// AppLogs streams the logs of all the application instances, in the targeted org
-// If stageID is an empty string, runtime application logs are streamed. If stageID
-// is set, then the matching staging logs are streamed.
-// There are 2 ways of stopping this method:
-// 1. The websocket connection closes.
-// 2. Something is sent to the interrupt channel
-// The interrupt channel is used by the caller when printing of logs should
-// be stopped.
-// To make sure everything is properly stopped (both the main thread and the
-// go routine) no matter what caused the stop (number 1 or 2 above):
-// - The go routines closes the connection on interrupt. This causes the main
-//   loop to stop as well.
-// - The main thread sends a signal to the `done` channel when it returns. This
-//   causes the go routine to stop.
-// - The main thread waits for the go routine to stop before finally returning (by
-//   calling `wg.Wait()`.
-// This is what happens when `interrupt` receives something:
-// 1. The go routine closes the connection
-// 2. The loop in the main thread is stopped because the connection was closed
-// 3. The main thread sends to the `done` chan (as a "defer" function), and then
-//    calls wg.Wait() to wait for the go routine to exit.
-// 4. The go routine receives the `done` message, calls wg.Done() and returns
-// 5. The main thread returns
-// When the connection is closed (e.g. from the server side), the process is the
-// same but starts from #2 above.
-func (c *EpinioClient) AppLogs(appName, stageID string, follow bool, interrupt chan bool) error {
-	log := c.Log.WithName("Apps").WithValues("Organization", c.Config.Org, "Application", appName)
+// AppAutoscale turns on the autoscaling of the named app, in the targeted org
+func (c *EpinioClient) AppAutoscale(appName string, req models.AutoscaleRequest) error {
+	log := c.Log.WithName("AppAutoscale").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Instances", fmt.Sprintf("%d - %d", *req.Min, req.Max)).
+		Msg("Autoscale application")
+
+	js, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.post(api.Routes.Path("AppAutoscale", c.Config.Org, appName), string(js))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Application is autoscaled.")
+
+	return nil
+}
+
+// AppAutoscaleOff turns off the autoscaling of the named app, in the targeted org
+func (c *EpinioClient) AppAutoscaleOff(appName string) error {
+	log := c.Log.WithName("AppAutoscaleOff").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Stop autoscaling application")
+
+	_, err := c.delete(api.Routes.Path("AppAutoscaleDelete", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Application is not autoscaled anymore.")
+
+	return nil
+}
+
+// AppReleases displays the release history of the named app, in the targeted org
+func (c *EpinioClient) AppReleases(appName string) error {
+	log := c.Log.WithName("AppReleases").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Show application releases")
+
+	jsonResponse, err := c.get(api.Routes.Path("AppReleases", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+	var releases models.ReleaseList
+	if err := json.Unmarshal(jsonResponse, &releases); err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Release", "Created", "Revision", "User", "Rollback Of", "Active")
+
+	for _, release := range releases {
+		revision := ""
+		if release.Git != nil {
+			revision = release.Git.Revision
+		}
+		active := ""
+		if release.Active {
+			active = "*"
+		}
+		msg = msg.WithTableRow(
+			release.ID,
+			release.Created.Local().Format(time.RFC3339),
+			revision,
+			release.User,
+			release.RollbackOf,
+			active)
+	}
+
+	msg.Msg("Releases:")
+
+	return nil
+}
+
+// AppRollback deploys a past release of the named app, in the targeted
+// org, again. Without a release the app goes back to the release
+// before the active one.
+func (c *EpinioClient) AppRollback(ctx context.Context, appName, release string) error {
+	log := c.Log.WithName("AppRollback").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	msg := c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName)
+	if release != "" {
+		msg = msg.WithStringValue("Release", release)
+	}
+	msg.Msg("Rollback application")
+
+	js, err := json.Marshal(models.RollbackRequest{Release: release})
+	if err != nil {
+		return err
+	}
+
+	details.Info("rollback")
+	b, err := c.post(api.Routes.Path("AppRollback", c.Config.Org, appName), string(js))
+	if err != nil {
+		return err
+	}
+
+	var response models.RollbackResponse
+	if err := json.Unmarshal(b, &response); err != nil {
+		return err
+	}
+
+	appRef := models.NewAppRef(appName, c.Config.Org)
+	details.Info("wait for app", "StageID", response.Release.ID)
+	err = c.waitForApp(ctx, appRef, response.Release.ID)
+	if err != nil {
+		return errors.Wrap(err, "waiting for app failed")
+	}
+
+	c.ui.Success().
+		WithStringValue("Release", response.Release.ID).
+		WithStringValue("Rollback Of", response.Release.RollbackOf).
+		WithStringValue("Image", response.Release.Image).
+		Msg("Rolled back.")
+
+	return nil
+}
+
+// AppRestart restarts the instances of the named app, in the targeted
+// org, one by one
+func (c *EpinioClient) AppRestart(ctx context.Context, appName string) error {
+	log := c.Log.WithName("AppRestart").WithValues("Organization", c.Config.Org, "Application", appName)
 	log.Info("start")
 	defer log.Info("return")
 	details := log.V(1) // NOTE: Increment of level, not absolute.
@@ -995,16 +1456,95 @@ func (c *EpinioClient) AppLogs(appName, stageID string, follow bool, interrupt c
 	c.ui.Note().
 		WithStringValue("Organization", c.Config.Org).
 		WithStringValue("Application", appName).
-		Msg("Streaming application logs")
+		Msg("Restart application")
 
-	details.Info("application logs")
+	details.Info("restart")
+	_, err := c.post(api.Routes.Path("AppRestart", c.Config.Org, appName), "")
+	if err != nil {
+		return err
+	}
 
-	var urlArgs = []string{}
-	urlArgs = append(urlArgs, fmt.Sprintf("follow=%t", follow))
-	urlArgs = append(urlArgs, fmt.Sprintf("stage_id=%s", stageID))
+	details.Info("wait for app")
+	err = c.waitForApp(ctx, models.NewAppRef(appName, c.Config.Org), "")
+	if err != nil {
+		return errors.Wrap(err, "waiting for app failed")
+	}
 
-	headers := http.Header{
-		"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", c.Config.User, c.Config.Password)))},
+	c.ui.Success().Msg("Application restarted.")
+
+	return nil
+}
+
+// AppStop removes all instances of the named app, in the targeted org
+func (c *EpinioClient) AppStop(appName string) error {
+	log := c.Log.WithName("AppStop").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Stop application")
+
+	_, err := c.post(api.Routes.Path("AppStop", c.Config.Org, appName), "")
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Application stopped.")
+
+	return nil
+}
+
+// AppStart brings the instances of the named and stopped app, in the
+// targeted org, back
+func (c *EpinioClient) AppStart(ctx context.Context, appName string) error {
+	log := c.Log.WithName("AppStart").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Start application")
+
+	details.Info("start")
+	_, err := c.post(api.Routes.Path("AppStart", c.Config.Org, appName), "")
+	if err != nil {
+		return err
+	}
+
+	details.Info("wait for app")
+	err = c.waitForApp(ctx, models.NewAppRef(appName, c.Config.Org), "")
+	if err != nil {
+		return errors.Wrap(err, "waiting for app failed")
+	}
+
+	c.ui.Success().Msg("Application started.")
+
+	return nil
+}
+
+// AppLogs streams the logs of all the application instances, in the targeted org
+// If stageID is an empty string, runtime application logs are streamed. If stageID
+// is set, then the matching staging logs are streamed.
+// The printing of logs is stopped when the websocket connection closes, or
+// something is sent to the interrupt channel, see streamLogs.
+// The options select the lines, see models.LogOptions, and the output how they
+// are printed, see LogOutput.
+func (c *EpinioClient) AppLogs(appName, stageID string, follow bool, options models.LogOptions, output LogOutput, interrupt chan bool) error {
+	log := c.Log.WithName("Apps").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	options = output.logOptions(options)
+
+	query := logQuery(follow, options)
+	query.Set("stage_id", stageID)
+	if options.Instance != "" {
+		query.Set("instance", options.Instance)
 	}
 
 	var endpoint string
@@ -1013,67 +1553,19 @@ func (c *EpinioClient) AppLogs(appName, stageID string, follow bool, interrupt c
 	} else {
 		endpoint = api.Routes.Path("StagingLogs", c.Config.Org, stageID)
 	}
-	webSocketConn, resp, err := websocket.DefaultDialer.Dial(
-		fmt.Sprintf("%s/%s?%s", c.wsServerURL, endpoint, strings.Join(urlArgs, "&")), headers)
-	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("Failed to connect to websockets endpoint. Response was = %+v\nThe error is", resp))
-	}
-
-	done := make(chan bool)
-	// When we get an interrupt, we close the websocket connection and we
-	// we don't want to return an error in this case.
-	connectionClosedByUs := false
-
-	var wg sync.WaitGroup
-	wg.Add(1)
-	defer wg.Wait()
-	go func() { // Closes the connection on "interrupt" or just stops on "done"
-		defer wg.Done()
-		for {
-			select {
-			case <-done: // Used by the other loop stop stop this go routine
-				return
-			case <-interrupt:
-				// Used by the caller of this method to stop everything. We simply close
-				// the connection here. This will make the loop below to stop and send us
-				// a signal on "done" above. That will stop this go routine too.
-				webSocketConn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Time{})
-				connectionClosedByUs = true
-				webSocketConn.Close()
-			}
-		}
-	}()
 
-	defer func() {
-		done <- true // Stop the go routine when we return
-	}()
+	note := c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName)
 
-	var logLine tailer.ContainerLogLine
-	printer := logprinter.LogPrinter{Tmpl: logprinter.DefaultSingleNamespaceTemplate()}
-	for {
-		_, message, err := webSocketConn.ReadMessage()
-		if err != nil {
-			if connectionClosedByUs {
-				return nil
-			}
-			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
-				webSocketConn.Close()
-				return nil
-			}
-			return err
-		}
-		err = json.Unmarshal(message, &logLine)
-		if err != nil {
-			return err
-		}
+	details.Info("application logs")
 
-		printer.Print(logprinter.Log{
-			Message:       logLine.Message,
-			Namespace:     logLine.Namespace,
-			PodName:       logLine.PodName,
-			ContainerName: logLine.ContainerName,
-		}, c.ui.ProgressNote().Compact())
-	}
+	return c.streamLogs(logStream{
+		endpoint: endpoint,
+		query:    query,
+		note:     note,
+		title:    "Streaming application logs",
+	}, options, output, interrupt)
 }
 
 // CreateOrg creates an Org in gitea
@@ -1147,6 +1639,59 @@ func (c *EpinioClient) DeleteOrg(org string) error {
 	return nil
 }
 
+// OrgLimits shows the resource defaults and maximums of the org
+func (c *EpinioClient) OrgLimits(org string) error {
+	log := c.Log.WithName("OrgLimits").WithValues("Organization", org)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", org).
+		Msg("Show organization limits")
+
+	jsonResponse, err := c.get(api.Routes.Path("OrgLimits", org))
+	if err != nil {
+		return err
+	}
+	var limits models.OrgLimits
+	if err := json.Unmarshal(jsonResponse, &limits); err != nil {
+		return err
+	}
+
+	c.ui.Success().
+		WithTable("Resource", "Default", "Maximum").
+		WithTableRow("memory", limits.DefaultMemory, limits.MaxMemory).
+		WithTableRow("cpu", limits.DefaultCPU, limits.MaxCPU).
+		Msg("Limits:")
+
+	return nil
+}
+
+// OrgLimitsSet replaces the resource defaults and maximums of the org
+func (c *EpinioClient) OrgLimitsSet(org string, limits models.OrgLimits) error {
+	log := c.Log.WithName("OrgLimitsSet").WithValues("Organization", org)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", org).
+		Msg("Setting organization limits")
+
+	js, err := json.Marshal(limits)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.post(api.Routes.Path("OrgLimitsSet", org), string(js))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Limits set.")
+
+	return nil
+}
+
 // Delete removes the named application from the cluster
 func (c *EpinioClient) Delete(ctx context.Context, appname string) error {
 	log := c.Log.WithName("Delete").WithValues("Application", appname)
@@ -1256,10 +1801,11 @@ func (c *EpinioClient) Orgs() error {
 
 // Push pushes an app
 // * validate
-// * upload
+// * upload (skipped for container images)
 // * stage
 // * (tail logs)
-// * wait for pipelinerun
+// * wait for pipelinerun (skipped for container images)
+// * deploy
 // * wait for app
 func (c *EpinioClient) Push(ctx context.Context, name, rev, source string, params PushParams) error {
 	appRef := models.AppRef{Name: name, Org: c.Config.Org}
@@ -1276,6 +1822,9 @@ func (c *EpinioClient) Push(ctx context.Context, name, rev, source string, param
 	if rev != "" {
 		sourceToShow = fmt.Sprintf("%s @ %s", sourceToShow, rev)
 	}
+	if params.ContainerImage != "" {
+		sourceToShow = params.ContainerImage
+	}
 
 	msg := c.ui.Note().
 		WithStringValue("Name", appRef.Name).
@@ -1288,6 +1837,28 @@ func (c *EpinioClient) Push(ctx context.Context, name, rev, source string, param
 		sort.Strings(services)
 		msg = msg.WithStringValue("Services:", strings.Join(services, ", "))
 	}
+	if params.BuildStrategy != "" {
+		msg = msg.WithStringValue("Build Strategy", params.BuildStrategy)
+	}
+	if params.BuilderImage != "" {
+		msg = msg.WithStringValue("Builder Image", params.BuilderImage)
+	}
+	if len(params.Buildpacks) > 0 {
+		msg = msg.WithStringValue("Buildpacks", strings.Join(params.Buildpacks, ", "))
+	}
+	if params.ProcessType != "" {
+		msg = msg.WithStringValue("Process Type", params.ProcessType)
+	}
+	if params.Port != 0 {
+		msg = msg.WithStringValue("Port", strconv.Itoa(int(params.Port)))
+	}
+	if len(params.Workers) > 0 {
+		workers := []string{}
+		for _, worker := range params.Workers {
+			workers = append(workers, worker.Name)
+		}
+		msg = msg.WithStringValue("Workers", strings.Join(workers, ", "))
+	}
 
 	msg.Msg("About to push an application with given name and sources into the specified organization")
 
@@ -1322,9 +1893,27 @@ func (c *EpinioClient) Push(ctx context.Context, name, rev, source string, param
 		return err
 	}
 
+	if len(params.Environment) > 0 {
+		c.ui.Normal().Msg("Setting the application environment ...")
+
+		js, err := json.Marshal(params.Environment)
+		if err != nil {
+			return err
+		}
+
+		details.Info("set environment")
+		_, err = c.post(api.Routes.Path("EnvSet", appRef.Org, appRef.Name), string(js))
+		if err != nil {
+			return err
+		}
+	}
+
 	var gitRef *models.GitRef
 
-	if rev == "" {
+	switch {
+	case params.ContainerImage != "":
+		// A prebuilt image. There are no sources to upload.
+	case rev == "":
 		c.ui.Normal().Msg("Collecting the application sources ...")
 
 		tmpDir, tarball, err := collectSources(log, source)
@@ -1347,7 +1936,7 @@ func (c *EpinioClient) Push(ctx context.Context, name, rev, source string, param
 		log.V(3).Info("upload response", "response", upload)
 
 		gitRef = upload.Git
-	} else {
+	default:
 		gitRef = &models.GitRef{
 			URL:      source,
 			Revision: rev,
@@ -1356,47 +1945,56 @@ func (c *EpinioClient) Push(ctx context.Context, name, rev, source string, param
 
 	c.ui.Normal().Msg("Staging application ...")
 
-	route, err := appDefaultRoute(ctx, appRef.Name)
-	if err != nil {
-		return errors.Wrap(err, "unable to determine default app route")
+	// The settings are given to the staging as well. This lets the
+	// server deploy the staged application, should the push stop
+	// before doing so.
+	settings := models.DeploySettings{
+		Instances:    params.Instances,
+		Routes:       params.Routes,
+		Port:         params.Port,
+		Processes:    params.Workers,
+		HealthChecks: params.HealthChecks,
 	}
 	req := models.StageRequest{
-		App:       appRef,
-		Instances: params.Instances,
-		Git:       gitRef,
-		Route:     route,
-	}
-	details.Info("staging code", "Git", gitRef.Revision)
+		App:              appRef,
+		Git:              gitRef,
+		ContainerImage:   params.ContainerImage,
+		BuildStrategy:    params.BuildStrategy,
+		BuilderImage:     params.BuilderImage,
+		Buildpacks:       params.Buildpacks,
+		ProcessType:      params.ProcessType,
+		BuildEnvironment: params.BuildEnvironment,
+		DeploySettings:   settings,
+	}
+	details.Info("staging code", "Git", gitRef, "ContainerImage", params.ContainerImage)
 	stage, err := c.stageCode(req)
 	if err != nil {
 		return err
 	}
 	log.V(3).Info("stage response", "response", stage)
 
-	details.Info("start tailing logs", "StageID", stage.Stage.ID)
-
-	// Buffered because the go routine may no longer be listening when we try
-	// to stop it. Stopping it should be a fire and forget. We have wg to wait
-	// for the routine to be gone.
-	stopChan := make(chan bool, 1)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	defer wg.Wait()
-	go func() {
-		defer wg.Done()
-		err := c.AppLogs(appRef.Name, stage.Stage.ID, true, stopChan)
+	// A prebuilt image comes back without staging run to wait for
+	if stage.Stage.ID != "" {
+		err = c.followStaging(ctx, appRef, stage.Stage.ID)
 		if err != nil {
-			c.ui.Problem().Msg(fmt.Sprintf("failed to tail logs: %s", err.Error()))
+			return err
 		}
-	}()
+	}
+
+	c.ui.Normal().Msg("Deploying application ...")
 
-	details.Info("wait for pipelinerun", "StageID", stage.Stage.ID)
-	err = c.waitForPipelineRun(ctx, appRef, stage.Stage.ID)
+	deployRequest := models.DeployRequest{
+		App:            appRef,
+		Stage:          stage.Stage,
+		Image:          stage.Image,
+		DeploySettings: settings,
+	}
+	details.Info("deploying code", "StageID", stage.Stage.ID)
+	deployResponse, err := c.deployCode(deployRequest)
 	if err != nil {
-		stopChan <- true // Stop the printing go routine
-		return errors.Wrap(err, "waiting for staging failed")
+		return err
 	}
-	stopChan <- true // Stop the printing go routine
+	log.V(3).Info("deploy response", "response", deployResponse)
 
 	details.Info("wait for app", "StageID", stage.Stage.ID)
 	err = c.waitForApp(ctx, appRef, stage.Stage.ID)
@@ -1450,20 +2048,12 @@ func (c *EpinioClient) Push(ctx context.Context, name, rev, source string, param
 	c.ui.Success().
 		WithStringValue("Name", appRef.Name).
 		WithStringValue("Organization", appRef.Org).
-		WithStringValue("Route", fmt.Sprintf("https://%s", route)).
+		WithStringValue("Routes", httpsURLs(deployResponse.Routes)).
 		Msg("App is online.")
 
 	return nil
 }
 
-func appDefaultRoute(ctx context.Context, name string) (string, error) {
-	mainDomain, err := domain.MainDomain(ctx)
-	if err != nil {
-		return "", err
-	}
-	return fmt.Sprintf("%s.%s", name, mainDomain), nil
-}
-
 // Target targets an org in gitea
 func (c *EpinioClient) Target(org string) error {
 	log := c.Log.WithName("Target").WithValues("Organization", org)
@@ -1672,6 +2262,54 @@ func (c *EpinioClient) curlWithCustomErrorHandling(endpoint, method, requestBody
 	return bodyBytes, nil
 }
 
+// appStatus returns the ready and desired instances of the application,
+// noting when it is stopped
+func appStatus(app models.App) string {
+	if app.Stopped {
+		return app.Status + " (stopped)"
+	}
+	return app.Status
+}
+
+// wsConnect opens a websocket connection to the endpoint of the API
+// server, with the credentials of the user
+func (c *EpinioClient) wsConnect(endpoint string, query url.Values) (*websocket.Conn, error) {
+	headers := http.Header{
+		"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", c.Config.User, c.Config.Password)))},
+	}
+
+	conn, resp, err := websocket.DefaultDialer.Dial(
+		fmt.Sprintf("%s/%s?%s", c.wsServerURL, endpoint, query.Encode()), headers)
+	if err != nil {
+		if resp != nil {
+			defer resp.Body.Close()
+			bodyBytes, _ := ioutil.ReadAll(resp.Body)
+			return nil, errors.New(fmt.Sprintf("%s: %s", http.StatusText(resp.StatusCode), string(bodyBytes)))
+		}
+		return nil, errors.Wrap(err, "Failed to connect to websockets endpoint")
+	}
+
+	return conn, nil
+}
+
+// resourceOrDefault returns the quantity of a resource of an
+// application, or a note that the default of the org applies
+func resourceOrDefault(quantity string) string {
+	if quantity == "" {
+		return "org default"
+	}
+	return quantity
+}
+
+// httpsURLs returns the routes as comma-separated list of https URLs
+func httpsURLs(routes []string) string {
+	urls := []string{}
+	for _, route := range routes {
+		urls = append(urls, fmt.Sprintf("https://%s", route))
+	}
+	return strings.Join(urls, ", ")
+}
+
 func uniqueStrings(stringSlice []string) []string {
 	keys := make(map[string]bool)
 	list := []string{}
@@ -0,0 +1,153 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// AppExec runs the command in an instance of the named app, in the
+// targeted org, connected to stdin, stdout and stderr. An empty
+// instance selects the first ready one, an empty command a shell. When
+// stdin and stdout are a terminal the command gets one too. It returns
+// the exit code of the command.
+func (c *EpinioClient) AppExec(appName, instance string, command []string) (int, error) {
+	log := c.Log.WithName("AppExec").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	// No notes are printed, so that the output of the command can be
+	// processed further.
+
+	stdinFd := int(os.Stdin.Fd())
+	tty := terminal.IsTerminal(stdinFd) && terminal.IsTerminal(int(os.Stdout.Fd()))
+
+	query := url.Values{}
+	query.Set("tty", fmt.Sprintf("%t", tty))
+	if instance != "" {
+		query.Set("instance", instance)
+	}
+	for _, arg := range command {
+		query.Add("command", arg)
+	}
+
+	details.Info("connect", "tty", tty, "command", command)
+	conn, err := c.wsConnect(api.Routes.Path("AppExec", c.Config.Org, appName), query)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	out := &execConn{conn: conn}
+	done := make(chan struct{})
+	defer close(done)
+
+	if tty {
+		state, err := terminal.MakeRaw(stdinFd)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to set up the terminal")
+		}
+		defer func() {
+			_ = terminal.Restore(stdinFd, state)
+		}()
+
+		// The size is polled, as windows has no signal for
+		// changes of it.
+		go func() {
+			var last models.TerminalSize
+			for {
+				width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+				if err == nil && (uint16(width) != last.Width || uint16(height) != last.Height) {
+					last = models.TerminalSize{Width: uint16(width), Height: uint16(height)}
+					if size, err := json.Marshal(last); err == nil {
+						_ = out.send(models.ExecResize, size)
+					}
+				}
+
+				select {
+				case <-done:
+					return
+				case <-time.After(250 * time.Millisecond):
+				}
+			}
+		}()
+	}
+
+	// Relay stdin until its end, which is sent as an empty message
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				if out.send(models.ExecStdin, buf[:n]) != nil {
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					_ = out.send(models.ExecStdin, nil)
+				}
+				return
+			}
+		}
+	}()
+
+	var response *models.ExecResponse
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				break
+			}
+			return 0, err
+		}
+		if len(message) == 0 {
+			continue
+		}
+
+		switch message[0] {
+		case models.ExecStdout:
+			_, _ = os.Stdout.Write(message[1:])
+		case models.ExecStderr:
+			_, _ = os.Stderr.Write(message[1:])
+		case models.ExecResult:
+			response = &models.ExecResponse{}
+			if err := json.Unmarshal(message[1:], response); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if response == nil {
+		return 0, errors.New("connection closed without result of the command")
+	}
+	if response.Error != "" {
+		return response.ExitCode, errors.New(response.Error)
+	}
+
+	return response.ExitCode, nil
+}
+
+// execConn serializes the messages sent over the websocket connection
+type execConn struct {
+	conn *websocket.Conn
+	lock sync.Mutex
+}
+
+func (c *execConn) send(stream byte, data []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.conn.WriteMessage(websocket.BinaryMessage, append([]byte{stream}, data...))
+}
@@ -0,0 +1,311 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes/tailer"
+	"github.com/epinio/epinio/helpers/termui"
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/cli/logprinter"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+// LogOutput selects how AppLogs prints the log lines. The format is
+// empty for the default, "json" for one JSON object per line, or
+// "template=TEMPLATE" for a template of the user, which is given each
+// tailer.ContainerLogLine. With a file the lines are written to it,
+// instead of the terminal.
+type LogOutput struct {
+	Format string
+	File   string
+}
+
+// Machine reports whether the format is meant for processing by other
+// tools, and not for people
+func (o LogOutput) Machine() bool {
+	return o.Format != ""
+}
+
+// logSink prints the log lines as chosen by a LogOutput
+type logSink struct {
+	ui         *termui.UI
+	out        io.Writer // Writer of the lines, the ui when nil
+	printer    logprinter.LogPrinter
+	json       bool
+	user       bool
+	timestamps bool
+	lines      int
+}
+
+// newLogSink returns the sink of the lines, writing to the writer, if
+// any. Timestamps, and the applications, are printed before the lines
+// of the default format.
+func newLogSink(ui *termui.UI, output LogOutput, out io.Writer, timestamps, withApp bool) (*logSink, error) {
+	sink := &logSink{ui: ui, out: out, timestamps: timestamps}
+
+	switch {
+	case output.Format == "":
+		sink.printer = logprinter.LogPrinter{Tmpl: logprinter.LogTemplate(out == nil, withApp)}
+	case output.Format == "json":
+		sink.json = true
+	case strings.HasPrefix(output.Format, "template="):
+		tmpl, err := logprinter.UserTemplate(strings.TrimPrefix(output.Format, "template="))
+		if err != nil {
+			return nil, err
+		}
+		sink.printer = logprinter.LogPrinter{Tmpl: tmpl}
+		sink.user = true
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown output format '%s', expected json or template=TEMPLATE", output.Format))
+	}
+
+	return sink, nil
+}
+
+// print prints the log line
+func (s *logSink) print(line tailer.ContainerLogLine) error {
+	s.lines++
+
+	if s.json {
+		b, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		_, err = s.writer().Write(append(b, '\n'))
+		return err
+	}
+
+	if s.user {
+		return s.printer.Write(s.writer(), line)
+	}
+
+	// Lines replayed from before timestamps were kept have none
+	text := line.Message
+	if s.timestamps && !line.Timestamp.IsZero() {
+		text = line.Timestamp.Local().Format(time.RFC3339Nano) + " " + text
+	}
+	log := logprinter.Log{
+		Message:       text,
+		Namespace:     line.Namespace,
+		PodName:       line.PodName,
+		ContainerName: line.ContainerName,
+		AppName:       line.AppName,
+	}
+
+	if s.out == nil {
+		s.printer.Print(log, s.ui.ProgressNote().Compact())
+		return nil
+	}
+	return s.printer.Write(s.out, log)
+}
+
+// writer returns the writer of the lines of machine formats
+func (s *logSink) writer() io.Writer {
+	if s.out == nil {
+		return os.Stdout
+	}
+	return s.out
+}
+
+// logOptions returns the options adjusted to the output. JSON lines
+// always carry their timestamp.
+func (o LogOutput) logOptions(options models.LogOptions) models.LogOptions {
+	if o.Format == "json" {
+		options.Timestamps = true
+	}
+	return options
+}
+
+// logQuery returns the query parameters of the logs endpoints, for the
+// options, except the instance
+func logQuery(follow bool, options models.LogOptions) url.Values {
+	query := url.Values{}
+	query.Set("follow", fmt.Sprintf("%t", follow))
+	if options.Since > 0 {
+		query.Set("since", options.Since.String())
+	}
+	if options.Tail != nil {
+		query.Set("tail", strconv.FormatInt(*options.Tail, 10))
+	}
+	if options.Timestamps {
+		query.Set("timestamps", "true")
+	}
+	for _, include := range options.Include {
+		query.Add("include", include)
+	}
+	for _, exclude := range options.Exclude {
+		query.Add("exclude", exclude)
+	}
+	return query
+}
+
+// OrgLogs streams the logs of the applications in the targeted org, and of
+// their stagings, with the name of the application on each line. Without apps
+// the logs of all applications are streamed. The options and the output are
+// those of AppLogs, except for the instance.
+func (c *EpinioClient) OrgLogs(apps []string, follow bool, options models.LogOptions, output LogOutput) error {
+	log := c.Log.WithName("OrgLogs").WithValues("Organization", c.Config.Org, "Applications", apps)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	options = output.logOptions(options)
+
+	query := logQuery(follow, options)
+	for _, app := range apps {
+		query.Add("app", app)
+	}
+
+	note := c.ui.Note().
+		WithStringValue("Organization", c.Config.Org)
+	if len(apps) > 0 {
+		note = note.WithStringValue("Applications", strings.Join(apps, ", "))
+	}
+
+	details.Info("org logs")
+
+	return c.streamLogs(logStream{
+		endpoint: api.Routes.Path("OrgLogs", c.Config.Org),
+		query:    query,
+		note:     note,
+		title:    "Streaming organization logs",
+		withApp:  true,
+	}, options, output, nil)
+}
+
+// logStream is a stream of log lines printed by streamLogs
+type logStream struct {
+	endpoint string
+	query    url.Values
+	note     *termui.Message // Shown with the title before the lines
+	title    string
+	withApp  bool // Print the name of the application of the lines
+}
+
+// streamLogs prints the log lines sent by the logs endpoint of the
+// stream as chosen by the output. The note of the stream is not shown
+// when machine readable lines are printed to stdout.
+// There are 2 ways of stopping this method:
+// 1. The websocket connection closes.
+// 2. Something is sent to the interrupt channel
+// The interrupt channel is used by the caller when printing of logs should
+// be stopped.
+// To make sure everything is properly stopped (both the main thread and the
+// go routine) no matter what caused the stop (number 1 or 2 above):
+//   - The go routines closes the connection on interrupt. This causes the main
+//     loop to stop as well.
+//   - The main thread sends a signal to the `done` channel when it returns. This
+//     causes the go routine to stop.
+//   - The main thread waits for the go routine to stop before finally returning (by
+//     calling `wg.Wait()`.
+//
+// This is what happens when `interrupt` receives something:
+//  1. The go routine closes the connection
+//  2. The loop in the main thread is stopped because the connection was closed
+//  3. The main thread sends to the `done` chan (as a "defer" function), and then
+//     calls wg.Wait() to wait for the go routine to exit.
+//  4. The go routine receives the `done` message, calls wg.Done() and returns
+//  5. The main thread returns
+//
+// When the connection is closed (e.g. from the server side), the process is the
+// same but starts from #2 above.
+func (c *EpinioClient) streamLogs(stream logStream, options models.LogOptions, output LogOutput, interrupt chan bool) error {
+	var out io.Writer
+	if output.File != "" {
+		file, err := os.Create(output.File)
+		if err != nil {
+			return errors.Wrap(err, "failed to create the log file")
+		}
+		defer file.Close()
+		out = file
+	}
+
+	sink, err := newLogSink(c.ui, output, out, options.Timestamps, stream.withApp)
+	if err != nil {
+		return err
+	}
+
+	// Machine formats on stdout are not mixed with notes
+	if output.File != "" || !output.Machine() {
+		stream.note.Msg(stream.title)
+	}
+
+	// finish reports the saved logs
+	finish := func() error {
+		if output.File != "" {
+			c.ui.Success().
+				WithStringValue("File", output.File).
+				WithIntValue("Lines", sink.lines).
+				Msg("Logs saved")
+		}
+		return nil
+	}
+
+	webSocketConn, err := c.wsConnect(stream.endpoint, stream.query)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan bool)
+	// When we get an interrupt, we close the websocket connection and we
+	// we don't want to return an error in this case.
+	connectionClosedByUs := false
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	defer wg.Wait()
+	go func() { // Closes the connection on "interrupt" or just stops on "done"
+		defer wg.Done()
+		for {
+			select {
+			case <-done: // Used by the other loop stop stop this go routine
+				return
+			case <-interrupt:
+				// Used by the caller of this method to stop everything. We simply close
+				// the connection here. This will make the loop below to stop and send us
+				// a signal on "done" above. That will stop this go routine too.
+				webSocketConn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Time{})
+				connectionClosedByUs = true
+				webSocketConn.Close()
+			}
+		}
+	}()
+
+	defer func() {
+		done <- true // Stop the go routine when we return
+	}()
+
+	for {
+		_, message, err := webSocketConn.ReadMessage()
+		if err != nil {
+			if connectionClosedByUs {
+				return finish()
+			}
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				webSocketConn.Close()
+				return finish()
+			}
+			return err
+		}
+		var logLine tailer.ContainerLogLine
+		err = json.Unmarshal(message, &logLine)
+		if err != nil {
+			return err
+		}
+
+		err = sink.print(logLine)
+		if err != nil {
+			return err
+		}
+	}
+}
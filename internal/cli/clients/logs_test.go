@@ -0,0 +1,83 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes/tailer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("logSink", func() {
+	var out *bytes.Buffer
+
+	timestamp := time.Date(2021, 7, 1, 10, 20, 30, 123456789, time.UTC)
+	line := tailer.ContainerLogLine{
+		Message:       "listening on :8080",
+		Namespace:     "workspace",
+		PodName:       "sample-abc",
+		ContainerName: "sample",
+		AppName:       "sample",
+		Timestamp:     timestamp,
+	}
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+	})
+
+	It("prints a line as json", func() {
+		sink, err := newLogSink(nil, LogOutput{Format: "json"}, out, false, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sink.print(line)).To(Succeed())
+		Expect(sink.lines).To(Equal(1))
+
+		var printed tailer.ContainerLogLine
+		Expect(json.Unmarshal(out.Bytes(), &printed)).To(Succeed())
+		Expect(printed).To(Equal(line))
+	})
+
+	It("prints a line through the template of the user", func() {
+		sink, err := newLogSink(nil, LogOutput{Format: "template={{.AppName}}: {{.Message}}"}, out, false, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sink.print(line)).To(Succeed())
+		Expect(out.String()).To(Equal("sample: listening on :8080\n"))
+	})
+
+	It("prints a line as plain text to a file", func() {
+		sink, err := newLogSink(nil, LogOutput{}, out, false, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sink.print(line)).To(Succeed())
+		Expect(out.String()).To(Equal("sample [sample-abc] sample listening on :8080\n"))
+	})
+
+	It("prints the timestamp of a line to the nanosecond", func() {
+		sink, err := newLogSink(nil, LogOutput{}, out, true, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sink.print(line)).To(Succeed())
+		Expect(out.String()).To(Equal("[sample-abc] sample " +
+			timestamp.Local().Format(time.RFC3339Nano) + " listening on :8080\n"))
+	})
+
+	It("prints no timestamp for a line without", func() {
+		sink, err := newLogSink(nil, LogOutput{}, out, true, false)
+		Expect(err).ToNot(HaveOccurred())
+
+		line := line
+		line.Timestamp = time.Time{}
+		Expect(sink.print(line)).To(Succeed())
+		Expect(out.String()).To(Equal("[sample-abc] sample listening on :8080\n"))
+	})
+
+	It("rejects an unknown format", func() {
+		_, err := newLogSink(nil, LogOutput{Format: "yaml"}, out, false, false)
+		Expect(err).To(MatchError(ContainSubstring("unknown output format 'yaml'")))
+	})
+
+	It("rejects a bad template", func() {
+		_, err := newLogSink(nil, LogOutput{Format: "template={{.Message"}, out, false, false)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,89 @@
+package clients
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/manifest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("exportManifest", func() {
+	var (
+		app         models.App
+		environment models.EnvVariableList
+		releases    models.ReleaseList
+	)
+
+	BeforeEach(func() {
+		workers := int32(2)
+		app = models.App{
+			Active:        true,
+			Name:          "sample",
+			Instances:     3,
+			Routes:        []string{"sample.example.com"},
+			BoundServices: []string{"mydb"},
+			Port:          9000,
+			Workers:       []string{"worker"},
+		}
+		environment = models.EnvVariableList{{Name: "MODE", Value: "production"}}
+		releases = models.ReleaseList{
+			{
+				ID:           "two",
+				Port:         9000,
+				BuilderImage: "paketobuildpacks/builder:tiny",
+				Buildpacks:   []string{"paketo-buildpacks/go"},
+				ProcessType:  "api",
+				Processes:    []models.Process{{Name: "worker", Command: []string{"./worker"}, Instances: &workers}},
+				Active:       true,
+			},
+			{
+				ID:   "one",
+				Port: 8080,
+			},
+		}
+	})
+
+	It("exports the settings of the active release", func() {
+		m := exportManifest(app, environment, releases)
+
+		Expect(m.Name).To(Equal("sample"))
+		Expect(*m.Instances).To(Equal(int32(3)))
+		Expect(m.Routes).To(Equal([]string{"sample.example.com"}))
+		Expect(m.Services).To(Equal([]string{"mydb"}))
+		Expect(m.Environment).To(Equal(map[string]string{"MODE": "production"}))
+		Expect(m.Port).To(Equal(int32(9000)))
+		Expect(m.Workers).To(Equal(releases[0].Processes))
+		Expect(m.BuilderImage).To(Equal("paketobuildpacks/builder:tiny"))
+		Expect(m.Buildpacks).To(Equal([]string{"paketo-buildpacks/go"}))
+		Expect(m.ProcessType).To(Equal("api"))
+	})
+
+	It("exports no release settings without an active release", func() {
+		app.Active = false
+		releases[0].Active = false
+
+		m := exportManifest(app, environment, releases)
+		Expect(m.Instances).To(BeNil())
+		Expect(m.Port).To(BeZero())
+		Expect(m.Workers).To(BeNil())
+		Expect(m.BuilderImage).To(BeEmpty())
+	})
+
+	It("reads back the manifest it writes", func() {
+		dir, err := ioutil.TempDir("", "epinio-manifest")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		m := exportManifest(app, environment, releases)
+		Expect(m.Write(filepath.Join(dir, manifest.FileName))).To(Succeed())
+
+		loaded, err := manifest.Load(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*loaded).To(Equal(m))
+	})
+})
@@ -0,0 +1,102 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+)
+
+// AppTop shows the cpu and memory usage of the instances of the named
+// app, in the targeted org, and, where linkerd meshes them, their
+// traffic.
+func (c *EpinioClient) AppTop(appName string) error {
+	log := c.Log.WithName("AppTop").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Show application metrics")
+
+	metrics, err := c.appMetrics(appName, true)
+	if err != nil {
+		return err
+	}
+	if len(metrics) == 0 {
+		c.ui.Normal().Msg("The application has no instances")
+		return nil
+	}
+
+	headers := []string{"Instance", "Worker", "CPU", "Memory"}
+	if metrics.HasRequests() {
+		headers = append(headers, "Requests", "Requests/s", "Success", "P50", "P95", "P99")
+	}
+
+	msg := c.ui.Success().WithTable(headers...)
+	for _, instance := range metrics {
+		row := []string{instance.Name, instance.Worker}
+		row = append(row, usageColumns(instance.Usage)...)
+		if metrics.HasRequests() {
+			if r := instance.Requests; r != nil {
+				row = append(row,
+					fmt.Sprintf("%.0f", r.Total),
+					fmt.Sprintf("%.2f", r.Rate),
+					fmt.Sprintf("%.1f%%", 100*r.SuccessRate),
+					formatLatency(r.LatencyP50),
+					formatLatency(r.LatencyP95),
+					formatLatency(r.LatencyP99))
+			} else {
+				row = append(row, "", "", "", "", "", "")
+			}
+		}
+		msg = msg.WithTableRow(row...)
+	}
+	msg.Msg("Metrics:")
+
+	if metrics.HasRequests() {
+		c.ui.Normal().Msg(fmt.Sprintf("The requests are counted since the start of each instance, their rate, success and latencies over %s", application.TrafficInterval))
+	}
+	if !metrics.HasUsage() {
+		c.ui.Normal().Msg("The cluster provides no cpu and memory usage, it has no metrics API")
+	}
+
+	return nil
+}
+
+// appMetrics returns the metrics of the instances of the named app, in
+// the targeted org. Without traffic only the usage is returned.
+func (c *EpinioClient) appMetrics(appName string, traffic bool) (models.AppMetrics, error) {
+	endpoint := api.Routes.Path("AppMetrics", c.Config.Org, appName)
+	if !traffic {
+		endpoint += "?traffic=false"
+	}
+	jsonResponse, err := c.get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	var metrics models.AppMetrics
+	if err := json.Unmarshal(jsonResponse, &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// usageColumns returns the cpu and memory columns of the usage, empty
+// when it is unknown
+func usageColumns(usage *models.ResourceUsage) []string {
+	if usage == nil {
+		return []string{"", ""}
+	}
+	return []string{
+		fmt.Sprintf("%dm", usage.CPU),
+		fmt.Sprintf("%.1fMi", float64(usage.Memory)/(1024*1024)),
+	}
+}
+
+func formatLatency(ms float64) string {
+	return fmt.Sprintf("%.0fms", ms)
+}
@@ -0,0 +1,171 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+// portForward is a local port forwarded to a port of an application
+type portForward struct {
+	local  int
+	remote int
+}
+
+// parsePortForward parses a port specification, LOCAL:REMOTE, or a
+// single PORT used for both
+func parsePortForward(spec string) (portForward, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+
+	var ports [2]int
+	for i, part := range parts {
+		port, err := strconv.Atoi(part)
+		if err != nil || port < 0 || port > 65535 {
+			return portForward{}, errors.New(fmt.Sprintf("bad port '%s' in '%s'", part, spec))
+		}
+		ports[i] = port
+	}
+	if ports[1] == 0 {
+		return portForward{}, errors.New(fmt.Sprintf("remote port of '%s' cannot be zero", spec))
+	}
+
+	return portForward{local: ports[0], remote: ports[1]}, nil
+}
+
+// AppPortForward forwards local ports on the address to ports of an
+// instance of the named app, in the targeted org, until interrupted.
+// Each port is either LOCAL:REMOTE, or a single PORT used for both. A
+// local port of zero picks a free one. An empty instance selects the
+// first ready one.
+func (c *EpinioClient) AppPortForward(ctx context.Context, appName, instance, address string, ports []string) error {
+	log := c.Log.WithName("AppPortForward").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	forwards := []portForward{}
+	for _, spec := range ports {
+		forward, err := parsePortForward(spec)
+		if err != nil {
+			return err
+		}
+		forwards = append(forwards, forward)
+	}
+
+	msg := c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName)
+	if instance != "" {
+		msg = msg.WithStringValue("Instance", instance)
+	}
+	msg.Msg("Forwarding ports of application")
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for _, forward := range forwards {
+		listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(forward.local)))
+		if err != nil {
+			return errors.Wrap(err, "failed to listen")
+		}
+		defer listener.Close()
+
+		c.ui.Normal().Msgf("Forwarding from %s -> %d", listener.Addr().String(), forward.remote)
+
+		wg.Add(1)
+		go func(listener net.Listener, remote int) {
+			defer wg.Done()
+			for {
+				local, err := listener.Accept()
+				if err != nil {
+					// The listener was closed
+					return
+				}
+
+				details.Info("connection", "from", local.RemoteAddr().String(), "port", remote)
+				go func() {
+					err := c.forwardConnection(appName, instance, remote, local)
+					if err != nil {
+						c.ui.Problem().Msgf("Forwarding to port %d failed: %s", remote, err.Error())
+					}
+				}()
+			}
+		}(listener, forward.remote)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+
+	select {
+	case <-interrupted:
+	case <-ctx.Done():
+	}
+
+	return nil
+}
+
+// forwardConnection relays the local connection through a websocket
+// connection of the API server to the port of the app instance
+func (c *EpinioClient) forwardConnection(appName, instance string, port int, local net.Conn) error {
+	defer local.Close()
+
+	query := url.Values{}
+	query.Set("port", strconv.Itoa(port))
+	if instance != "" {
+		query.Set("instance", instance)
+	}
+
+	conn, err := c.wsConnect(api.Routes.Path("AppPortForward", c.Config.Org, appName), query)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Relay the local data until its end, which is sent as an empty
+	// message
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := local.Read(buf)
+			if n > 0 {
+				if conn.WriteMessage(websocket.BinaryMessage, buf[:n]) != nil {
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					_ = conn.WriteMessage(websocket.BinaryMessage, []byte{})
+				}
+				return
+			}
+		}
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil
+			}
+			return err
+		}
+		if _, err := local.Write(message); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package clients
+
+import (
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("parsePortForward", func() {
+	table.DescribeTable("parses a port specification",
+		func(spec string, expected portForward) {
+			forward, err := parsePortForward(spec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(forward).To(Equal(expected))
+		},
+		table.Entry("a single port", "8080", portForward{local: 8080, remote: 8080}),
+		table.Entry("local and remote port", "9000:8080", portForward{local: 9000, remote: 8080}),
+		table.Entry("any local port", "0:8080", portForward{local: 0, remote: 8080}),
+		table.Entry("the largest port", "65535", portForward{local: 65535, remote: 65535}),
+	)
+
+	table.DescribeTable("rejects a bad port specification",
+		func(spec, message string) {
+			_, err := parsePortForward(spec)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		table.Entry("no port", "", "bad port ''"),
+		table.Entry("a name", "http", "bad port 'http'"),
+		table.Entry("a negative port", "-1", "bad port '-1'"),
+		table.Entry("a port out of range", "65536", "bad port '65536'"),
+		table.Entry("a bad remote port", "8080:http", "bad port 'http'"),
+		table.Entry("three ports", "1:2:3", "bad port '2:3'"),
+		table.Entry("a zero remote port", "8080:0", "remote port of '8080:0' cannot be zero"),
+		table.Entry("a single zero port", "0", "remote port of '0' cannot be zero"),
+	)
+})
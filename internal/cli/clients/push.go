@@ -1,22 +1,24 @@
 package clients
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"os/signal"
 	"path"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/epinio/epinio/deployments"
 	api "github.com/epinio/epinio/internal/api/v1"
 	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/epinio/epinio/internal/duration"
 	"github.com/go-logr/logr"
 	"github.com/mholt/archiver/v3"
 	"github.com/pkg/errors"
-	tekton "github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
@@ -92,45 +94,158 @@ func (c *EpinioClient) stageCode(req models.StageRequest) (*models.StageResponse
 	return stage, nil
 }
 
-func (c *EpinioClient) waitForPipelineRun(ctx context.Context, app models.AppRef, id string) error {
-	c.ui.ProgressNote().KeeplineUnder(1).Msg("Running staging")
+func (c *EpinioClient) deployCode(req models.DeployRequest) (*models.DeployResponse, error) {
+	out, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't marshal deploy request")
+	}
 
-	cs, err := tekton.NewForConfig(c.Cluster.RestConfig)
+	b, err := c.post(api.Routes.Path("AppDeploy", req.App.Org, req.App.Name), string(out))
+	if err != nil {
+		return nil, errors.Wrap(err, "can't deploy app")
+	}
+
+	// returns the route of the app
+	deploy := &models.DeployResponse{}
+	if err := json.Unmarshal(b, deploy); err != nil {
+		return nil, err
+	}
+
+	return deploy, nil
+}
+
+// followStaging tails the logs of the identified staging run until it
+// is done
+func (c *EpinioClient) followStaging(ctx context.Context, appRef models.AppRef, stageID string) error {
+	log := c.Log.WithName("followStaging").WithValues("StageID", stageID)
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	details.Info("start tailing logs")
+
+	// Buffered because the go routine may no longer be listening when we try
+	// to stop it. Stopping it should be a fire and forget. We have wg to wait
+	// for the routine to be gone.
+	stopChan := make(chan bool, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	defer wg.Wait()
+	go func() {
+		defer wg.Done()
+		err := c.AppLogs(appRef.Name, stageID, true, models.LogOptions{}, LogOutput{}, stopChan)
+		if err != nil {
+			c.ui.Problem().Msg(fmt.Sprintf("failed to tail logs: %s", err.Error()))
+		}
+	}()
+
+	// An interrupt stops the waiting, to offer the cancellation of
+	// the staging run. Otherwise it would continue unseen.
+	waitCtx, stopWaiting := context.WithCancel(ctx)
+	defer stopWaiting()
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+	go func() {
+		select {
+		case <-interrupted:
+			stopWaiting()
+		case <-waitCtx.Done():
+		}
+	}()
+
+	details.Info("wait for staging")
+	err := c.waitForStaging(waitCtx, appRef, stageID)
+	stopChan <- true // Stop the printing go routine
+	if err != nil {
+		if waitCtx.Err() != nil && ctx.Err() == nil {
+			signal.Stop(interrupted)
+			return c.offerStagingCancel(appRef, stageID)
+		}
+		return errors.Wrap(err, "waiting for staging failed")
+	}
+
+	return nil
+}
+
+// offerStagingCancel asks the user whether the staging run of an
+// interrupted push should be cancelled, and does so.
+func (c *EpinioClient) offerStagingCancel(appRef models.AppRef, stageID string) error {
+	c.ui.Exclamation().KeepLine().Msg("Push interrupted. Cancel the staging of the application? [y/N] ")
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		c.ui.Note().
+			WithStringValue("Staging ID", stageID).
+			Msgf("The staging continues. Cancel it later with `epinio app stage cancel %s`", appRef.Name)
+		return errors.New("push interrupted")
+	}
+
+	err := c.stagingCancel(appRef.Org, stageID)
 	if err != nil {
 		return err
 	}
-	client := cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace)
 
-	return wait.PollImmediate(time.Second, duration.ToAppBuilt(),
-		func() (bool, error) {
-			l, err := client.List(ctx, metav1.ListOptions{LabelSelector: models.EpinioStageIDLabel + "=" + id})
-			if err != nil {
-				return false, err
-			}
-			if len(l.Items) == 0 {
-				return false, nil
-			}
-			for _, pr := range l.Items {
-				// any failed conditions, throw an error so we can exit early
-				for _, c := range pr.Status.Conditions {
-					if c.IsFalse() {
-						return false, errors.New(c.Message)
-					}
-				}
-				// it worked
-				if pr.Status.CompletionTime != nil {
-					return true, nil
+	return errors.New("push interrupted, staging cancelled")
+}
+
+// stagingCancel asks the server to stop the identified staging run
+func (c *EpinioClient) stagingCancel(org, stageID string) error {
+	_, err := c.delete(api.Routes.Path("StagingCancel", org, stageID))
+	return err
+}
+
+// waitForStaging polls the server for the progress of the identified
+// staging run until it is done, reporting each completed task.
+func (c *EpinioClient) waitForStaging(ctx context.Context, app models.AppRef, id string) error {
+	c.ui.ProgressNote().KeeplineUnder(1).Msg("Running staging")
+
+	ctx, cancel := context.WithTimeout(ctx, duration.ToAppBuilt())
+	defer cancel()
+
+	reported := map[string]bool{}
+	return wait.PollImmediateUntil(time.Second, func() (bool, error) {
+		b, err := c.get(api.Routes.Path("StagingShow", app.Org, id))
+		if err != nil {
+			return false, err
+		}
+		var status models.StagingStatus
+		if err := json.Unmarshal(b, &status); err != nil {
+			return false, err
+		}
+
+		for _, task := range status.Tasks {
+			switch task.State {
+			case models.StagingSucceeded:
+				if !reported[task.Name] {
+					reported[task.Name] = true
+					c.ui.Note().WithStringValue("Task", task.Name).Msg("Staging task completed")
 				}
+			case models.StagingFailed:
+				return false, fmt.Errorf("task %s failed: %s", task.Name, task.Message)
 			}
-			// pr exists, but still running
-			return false, nil
-		})
+		}
+
+		switch status.State {
+		case models.StagingSucceeded:
+			return true, nil
+		case models.StagingCancelled:
+			return false, errors.New("staging cancelled")
+		case models.StagingFailed:
+			return false, errors.New(status.Message)
+		}
+
+		// still running
+		return false, nil
+	}, ctx.Done())
 }
 
 func (c *EpinioClient) waitForApp(ctx context.Context, app models.AppRef, id string) error {
 	c.ui.ProgressNote().KeeplineUnder(1).Msg("Creating application resources")
 
-	err := c.Cluster.WaitForDeploymentCompleted(
+	// Wait for the instances of the new release to pass their readiness
+	// checks. The instances of the previous release are still available
+	// during the rollout.
+	err := c.Cluster.WaitForDeploymentRolledOut(
 		ctx,
 		c.ui, app.Org, app.Name, duration.ToAppBuilt())
 	if err != nil {
@@ -87,6 +87,20 @@ var NeededOptions = kubernetes.InstallationOptions{
 		Default:     true,
 		Value:       true,
 	},
+	{
+		Name:        "builder-image",
+		Description: "The buildpacks builder image used to stage applications, unless they ask for another.",
+		Type:        kubernetes.StringType,
+		Default:     deployments.DefaultBuilderImage,
+		Value:       deployments.DefaultBuilderImage,
+	},
+	{
+		Name:        "additional-builder-images",
+		Description: "Comma-separated list of further builder images offered to applications. They are pulled into the cluster ahead of time, like the default.",
+		Type:        kubernetes.StringType,
+		Default:     "",
+		Value:       "",
+	},
 }
 
 var TraefikOptions = kubernetes.InstallationOptions{
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdOrgLimits implements the `epinio org limits` command ensemble
+var CmdOrgLimits = &cobra.Command{
+	Use:           "limits",
+	Short:         "Epinio organization limits",
+	Long:          `Manage the resource defaults and maximums of the applications in epinio organizations`,
+	Args:          cobra.ExactArgs(0),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	flags := CmdOrgLimitsSet.Flags()
+	flags.String("default-memory", "", "memory of instances not setting their own, e.g. 256Mi")
+	flags.String("default-cpu", "", "cpu of instances not setting their own, e.g. 500m")
+	flags.String("max-memory", "", "maximum memory of an instance, e.g. 1Gi")
+	flags.String("max-cpu", "", "maximum cpu of an instance, e.g. 2")
+
+	CmdOrgLimits.AddCommand(CmdOrgLimitsShow)
+	CmdOrgLimits.AddCommand(CmdOrgLimitsSet)
+}
+
+// CmdOrgLimitsShow implements the `epinio org limits show` command
+var CmdOrgLimitsShow = &cobra.Command{
+	Use:   "show NAME",
+	Short: "Show organization limits",
+	Long:  "Show the resource defaults and maximums of the applications in the named organization",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.OrgLimits(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error showing org limits")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.OrgsMatching(toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdOrgLimitsSet implements the `epinio org limits set` command
+var CmdOrgLimitsSet = &cobra.Command{
+	Use:   "set NAME",
+	Short: "Set organization limits",
+	Long: `Set the resource defaults and maximums of the applications in the named organization.
+Limits not given are removed. Without a default the maximum is also the default.
+The limits apply to instances started afterwards.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		var limits models.OrgLimits
+		for _, option := range []struct {
+			name  string
+			value *string
+		}{
+			{"default-memory", &limits.DefaultMemory},
+			{"default-cpu", &limits.DefaultCPU},
+			{"max-memory", &limits.MaxMemory},
+			{"max-cpu", &limits.MaxCPU},
+		} {
+			*option.value, err = cmd.Flags().GetString(option.name)
+			if err != nil {
+				return errors.Wrapf(err, "could not read option --%s", option.name)
+			}
+		}
+
+		err = client.OrgLimitsSet(args[0], limits)
+		if err != nil {
+			return errors.Wrap(err, "error setting org limits")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.OrgsMatching(toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
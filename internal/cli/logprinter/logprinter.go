@@ -5,11 +5,13 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"io"
 	"os"
 	"text/template"
 
 	"github.com/epinio/epinio/helpers/termui"
 	"github.com/fatih/color"
+	"github.com/pkg/errors"
 )
 
 var colorList = [][2]*color.Color{
@@ -40,6 +42,9 @@ type Log struct {
 	// ContainerName of the container
 	ContainerName string `json:"containerName"`
 
+	// AppName of the application of the pod
+	AppName string `json:"appName"`
+
 	PodColor       *color.Color `json:"-"`
 	ContainerColor *color.Color `json:"-"`
 }
@@ -57,6 +62,26 @@ func (printer LogPrinter) Print(log Log, uiMsg *termui.Message) {
 	uiMsg.Msg(result.String() + " ")
 }
 
+// Write writes the data through the template to the writer, followed
+// by a newline. The data is either a Log, or anything a template of the
+// user expects.
+func (printer LogPrinter) Write(w io.Writer, data interface{}) error {
+	if log, ok := data.(Log); ok {
+		log.PodColor, log.ContainerColor = determineColor(log.PodName)
+		data = log
+	}
+
+	var result bytes.Buffer
+	err := printer.Tmpl.Execute(&result, data)
+	if err != nil {
+		return errors.Wrap(err, "expanding template failed")
+	}
+	result.WriteString("\n")
+
+	_, err = w.Write(result.Bytes())
+	return err
+}
+
 func determineColor(podName string) (podColor, containerColor *color.Color) {
 	hash := fnv.New32()
 	hash.Write([]byte(podName))
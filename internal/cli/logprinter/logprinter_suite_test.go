@@ -0,0 +1,13 @@
+package logprinter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLogprinter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Logprinter Suite")
+}
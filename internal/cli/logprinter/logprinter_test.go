@@ -0,0 +1,83 @@
+package logprinter_test
+
+import (
+	"bytes"
+
+	"github.com/epinio/epinio/helpers/kubernetes/tailer"
+	"github.com/epinio/epinio/internal/cli/logprinter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LogPrinter", func() {
+	var out *bytes.Buffer
+
+	log := logprinter.Log{
+		Message:       "listening on :8080",
+		Namespace:     "workspace",
+		PodName:       "sample-abc",
+		ContainerName: "sample",
+		AppName:       "sample",
+	}
+	line := tailer.ContainerLogLine{
+		Message:       "listening on :8080",
+		Namespace:     "workspace",
+		PodName:       "sample-abc",
+		ContainerName: "sample",
+		AppName:       "sample",
+	}
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+	})
+
+	It("writes a log through the default template, without colors", func() {
+		printer := logprinter.LogPrinter{Tmpl: logprinter.LogTemplate(false, false)}
+		Expect(printer.Write(out, log)).To(Succeed())
+		Expect(out.String()).To(Equal("[sample-abc] sample listening on :8080\n"))
+	})
+
+	It("writes the application of a log before its pod", func() {
+		printer := logprinter.LogPrinter{Tmpl: logprinter.LogTemplate(false, true)}
+		Expect(printer.Write(out, log)).To(Succeed())
+		Expect(out.String()).To(Equal("sample [sample-abc] sample listening on :8080\n"))
+	})
+
+	It("writes a line through the template of the user", func() {
+		tmpl, err := logprinter.UserTemplate("{{.Namespace}}/{{.PodName}}: {{.Message}}")
+		Expect(err).ToNot(HaveOccurred())
+
+		printer := logprinter.LogPrinter{Tmpl: tmpl}
+		Expect(printer.Write(out, line)).To(Succeed())
+		Expect(out.String()).To(Equal("workspace/sample-abc: listening on :8080\n"))
+	})
+
+	It("offers the json function to the template of the user", func() {
+		tmpl, err := logprinter.UserTemplate("{{json .Message}}")
+		Expect(err).ToNot(HaveOccurred())
+
+		printer := logprinter.LogPrinter{Tmpl: tmpl}
+		Expect(printer.Write(out, line)).To(Succeed())
+		Expect(out.String()).To(Equal(`"listening on :8080"` + "\n"))
+	})
+
+	It("rejects a bad template of the user", func() {
+		_, err := logprinter.UserTemplate("{{.Message")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects colors in the template of the user", func() {
+		_, err := logprinter.UserTemplate("{{color .PodColor .PodName}}")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails for fields the line does not have", func() {
+		tmpl, err := logprinter.UserTemplate("{{.PodColor}}")
+		Expect(err).ToNot(HaveOccurred())
+
+		printer := logprinter.LogPrinter{Tmpl: tmpl}
+		Expect(printer.Write(out, line)).ToNot(Succeed())
+		Expect(out.String()).To(BeEmpty())
+	})
+})
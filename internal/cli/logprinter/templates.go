@@ -8,12 +8,54 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	singleNamespaceTemplate = "[{{ color .PodColor .PodName}}] {{color .ContainerColor .ContainerName}} {{.Message}}"
+	appTemplate             = "{{ color .PodColor .AppName}} " + singleNamespaceTemplate
+)
+
 // DefaultSingleNamespaceTemplate returns a printing template used when
 // printing with colors and watching resources in a single namespace
 func DefaultSingleNamespaceTemplate() *template.Template {
-	t := "[{{ color .PodColor .PodName}}] {{color .ContainerColor .ContainerName}} {{.Message}}"
+	return LogTemplate(true, false)
+}
+
+// LogTemplate returns the printing template of
+// DefaultSingleNamespaceTemplate, without colors for writing logs to
+// files, and with the application of each line before its pod, for the
+// logs of many applications. The template is executed with a Log.
+func LogTemplate(colors, withApp bool) *template.Template {
+	t := singleNamespaceTemplate
+	if withApp {
+		t = appTemplate
+	}
+
+	template, err := template.New("log").Funcs(functions(colors)).Parse(t)
+	if err != nil {
+		panic(errors.Wrap(err, "unable to parse template"))
+	}
+
+	return template
+}
 
-	funs := map[string]interface{}{
+// UserTemplate returns the printing template given by the user. It has
+// the function `json` in addition to the standard ones, and is executed
+// with a tailer.ContainerLogLine, not a Log. It has no colors.
+func UserTemplate(text string) (*template.Template, error) {
+	funs := functions(false)
+	delete(funs, "color")
+
+	template, err := template.New("log").Funcs(funs).Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse template")
+	}
+
+	return template, nil
+}
+
+// functions returns the functions of the printing templates. Without
+// colors the `color` function returns the text as is.
+func functions(colors bool) template.FuncMap {
+	return template.FuncMap{
 		"json": func(in interface{}) (string, error) {
 			b, err := json.Marshal(in)
 			if err != nil {
@@ -22,13 +64,10 @@ func DefaultSingleNamespaceTemplate() *template.Template {
 			return string(b), nil
 		},
 		"color": func(color color.Color, text string) string {
+			if !colors {
+				return text
+			}
 			return color.SprintFunc()(text)
 		},
 	}
-	template, err := template.New("log").Funcs(funs).Parse(t)
-	if err != nil {
-		panic(errors.Wrap(err, "unable to parse template"))
-	}
-
-	return template
 }
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// logFlags adds the flags selecting and printing log lines, shared by
+// the logs commands
+func logFlags(flags *pflag.FlagSet) {
+	flags.Duration("since", 0, "show only the logs newer than this duration, e.g. 10m. Defaults to the log history of the server")
+	flags.Int64("tail", -1, "the number of past lines to show per container. Defaults to all")
+	flags.Bool("timestamps", false, "show the timestamp of each line")
+	flags.StringArray("include", []string{}, "show only the lines matching this regular expression. Can be repeated, lines matching any are shown")
+	flags.StringArray("exclude", []string{}, "hide the lines matching this regular expression. Can be repeated")
+	flags.StringP("output", "o", "", "print the lines as json, or through a template, as template=TEMPLATE, with the fields .Message, .AppName, .ContainerName, .PodName, .Namespace and .Timestamp. Defaults to colored text")
+	flags.String("file", "", "save the logs to this file, instead of showing them. Cannot be combined with --follow")
+}
+
+// logOptions returns the log options chosen by the flags of the command,
+// except for the instance
+func logOptions(cmd *cobra.Command) (models.LogOptions, error) {
+	options := models.LogOptions{}
+	var err error
+
+	options.Since, err = cmd.Flags().GetDuration("since")
+	if err != nil {
+		return options, errors.Wrap(err, "error reading option --since")
+	}
+	if options.Since < 0 {
+		return options, errors.New("--since has to be a positive duration")
+	}
+
+	tail, err := cmd.Flags().GetInt64("tail")
+	if err != nil {
+		return options, errors.Wrap(err, "error reading option --tail")
+	}
+	if tail >= 0 {
+		options.Tail = &tail
+	}
+
+	options.Timestamps, err = cmd.Flags().GetBool("timestamps")
+	if err != nil {
+		return options, errors.Wrap(err, "error reading option --timestamps")
+	}
+
+	options.Include, err = cmd.Flags().GetStringArray("include")
+	if err != nil {
+		return options, errors.Wrap(err, "error reading option --include")
+	}
+
+	options.Exclude, err = cmd.Flags().GetStringArray("exclude")
+	if err != nil {
+		return options, errors.Wrap(err, "error reading option --exclude")
+	}
+
+	return options, nil
+}
+
+// logOutput returns the log output chosen by the flags of the command
+func logOutput(cmd *cobra.Command, follow bool) (clients.LogOutput, error) {
+	output := clients.LogOutput{}
+	var err error
+
+	output.Format, err = cmd.Flags().GetString("output")
+	if err != nil {
+		return output, errors.Wrap(err, "error reading option --output")
+	}
+
+	output.File, err = cmd.Flags().GetString("file")
+	if err != nil {
+		return output, errors.Wrap(err, "error reading option --file")
+	}
+	if output.File != "" && follow {
+		return output, errors.New("--file cannot be combined with --follow")
+	}
+
+	return output, nil
+}
@@ -30,9 +30,16 @@ func init() {
 	flags := CmdOrgDelete.Flags()
 	flags.BoolVarP(&force, "force", "f", false, "force org deletion")
 
+	logsFlags := CmdOrgLogs.Flags()
+	logsFlags.Bool("follow", false, "follow the logs of the applications")
+	logsFlags.StringArray("app", []string{}, "show only the logs of this application. Can be repeated")
+	logFlags(logsFlags) // See logs.go for implementation
+
 	CmdOrg.AddCommand(CmdOrgCreate)
 	CmdOrg.AddCommand(CmdOrgList)
 	CmdOrg.AddCommand(CmdOrgDelete)
+	CmdOrg.AddCommand(CmdOrgLimits) // See limits.go for implementation
+	CmdOrg.AddCommand(CmdOrgLogs)
 }
 
 // CmdOrgs implements the epinio `orgs list` command
@@ -131,3 +138,47 @@ func askConfirmation(cmd *cobra.Command) bool {
 	}
 	return true
 }
+
+// CmdOrgLogs implements the epinio `orgs logs` command
+var CmdOrgLogs = &cobra.Command{
+	Use:   "logs",
+	Short: "Streams the logs of the applications in the targeted organization",
+	Long: `Streams the logs of the applications in the targeted organization, of their workers, and of their stagings, merged, with the name of the application on each line.
+Use --app to limit them to some of the applications.`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		follow, err := cmd.Flags().GetBool("follow")
+		if err != nil {
+			return errors.Wrap(err, "error reading option --follow")
+		}
+
+		apps, err := cmd.Flags().GetStringArray("app")
+		if err != nil {
+			return errors.Wrap(err, "error reading option --app")
+		}
+
+		options, err := logOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		output, err := logOutput(cmd, follow)
+		if err != nil {
+			return err
+		}
+
+		err = client.OrgLogs(apps, follow, options, output)
+		if err != nil {
+			return errors.Wrap(err, "error streaming organization logs")
+		}
+
+		return nil
+	},
+}
@@ -2,10 +2,13 @@ package cli
 
 import (
 	"os"
+	"strconv"
 	"strings"
 
 	v1 "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/epinio/epinio/internal/manifest"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -17,7 +20,18 @@ func init() {
 	CmdPush.Flags().Int32P("instances", "i", v1.DefaultInstances,
 		"The number of desired instances for the application, default only applies to new deployments")
 	CmdPush.Flags().String("git", "", "git revision of sources. PATH becomes repository location")
+	CmdPush.Flags().String("container-image", "", "prebuilt container image to deploy, skipping the staging of sources")
+	CmdPush.Flags().String("build-strategy", "",
+		"how to build the sources, buildpacks or dockerfile. Default: dockerfile when the sources contain a Dockerfile, else buildpacks")
+	CmdPush.Flags().String("builder-image", "", "buildpacks builder image to stage with, instead of the default builder")
+	CmdPush.Flags().StringSlice("buildpack", []string{}, "buildpack to use, as ID or ID@VERSION, instead of detecting them. Can be repeated")
+	CmdPush.Flags().String("process-type", "", "buildpacks process type the application runs, default web")
+	CmdPush.Flags().Int32("port", 0, "port the application listens on, default 8080, or the port of the deployed application")
+	CmdPush.Flags().StringArray("worker", []string{}, "additional process to run without route, as NAME or NAME=COMMAND. Can be repeated")
+	CmdPush.Flags().String("liveness", "", "check restarting failing instances, as http[:PATH], tcp[:PORT], exec:COMMAND, or none to remove it")
+	CmdPush.Flags().String("readiness", "", "check routing traffic only to passing instances, as http[:PATH], tcp[:PORT], exec:COMMAND, or none to remove it")
 	CmdPush.Flags().StringSliceP("bind", "b", []string{}, "services to bind immediately")
+	CmdPush.Flags().StringArrayP("env", "e", []string{}, "environment variable to set, as NAME=VALUE. Can be repeated")
 	CmdPush.RegisterFlagCompletionFunc("bind",
 		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			// `cmd`, `args` are ignored.
@@ -79,9 +93,19 @@ func instances(cmd *cobra.Command) (*int32, error) {
 
 // CmdPush implements the epinio push command
 var CmdPush = &cobra.Command{
-	Use:   "push NAME [URL|PATH_TO_APPLICATION_SOURCES]",
+	Use:   "push [NAME [URL|PATH_TO_APPLICATION_SOURCES]]",
 	Short: "Push an application from the specified directory, or the current working directory",
-	Args:  cobra.RangeArgs(1, 2),
+	Long: `Push an application from the specified directory, or the current working directory.
+
+Settings for the application can be kept in a manifest file named ` + manifest.FileName + `
+in the application sources. It may declare the name, instances, environment,
+services to bind, routes, build_environment, builder_image, buildpacks,
+process_type, port, workers and health_checks of the application.
+Command line arguments and options override the values from the manifest.
+
+With --container-image the given prebuilt image is deployed as is, without
+staging any sources. A manifest is then read from the current working directory.`,
+	Args: cobra.RangeArgs(0, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 
@@ -95,13 +119,31 @@ var CmdPush = &cobra.Command{
 			return errors.Wrap(err, "could not read option --git")
 		}
 
+		containerImage, err := cmd.Flags().GetString("container-image")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --container-image")
+		}
+
 		// Syntax:
-		// 1. push NAME
-		// 2. push NAME PATH
-		// 3. push NAME URL --git REV
+		// 1. push                   (name taken from the manifest)
+		// 2. push NAME
+		// 3. push NAME PATH
+		// 4. push NAME URL --git REV
+		// 5. push NAME --container-image IMAGE
+
+		if containerImage != "" {
+			if gitRevision != "" {
+				cmd.SilenceUsage = false
+				return errors.New("cannot use both --git and --container-image")
+			}
+			if len(args) > 1 {
+				cmd.SilenceUsage = false
+				return errors.New("no sources to push with --container-image")
+			}
+		}
 
 		var path string
-		if len(args) == 1 {
+		if len(args) < 2 {
 			if gitRevision != "" {
 				// Missing argument is user error. Show usage
 				cmd.SilenceUsage = false
@@ -116,29 +158,42 @@ var CmdPush = &cobra.Command{
 			path = args[1]
 		}
 
+		m := &manifest.Manifest{}
 		if gitRevision == "" {
 			if _, err := os.Stat(path); err != nil {
 				// Path issue is user error. Show usage
 				cmd.SilenceUsage = false
 				return errors.Wrap(err, "path not accessible")
 			}
+
+			m, err = manifest.Load(path)
+			if err != nil {
+				return errors.Wrap(err, "trouble with the application manifest")
+			}
 		}
 
-		i, err := instances(cmd)
-		if err != nil {
-			return errors.Wrap(err, "trouble with instances")
+		name := m.Name
+		if len(args) > 0 {
+			name = args[0]
 		}
-		params := clients.PushParams{
-			Instances: i,
+		if name == "" {
+			// Missing argument is user error. Show usage
+			cmd.SilenceUsage = false
+			return errors.New("app name missing, and not declared by a manifest")
 		}
 
-		services, err := cmd.Flags().GetStringSlice("bind")
+		params, err := pushParams(cmd, m)
 		if err != nil {
-			return errors.Wrap(err, "failed to read option --bind")
+			return err
+		}
+		params.ContainerImage = containerImage
+
+		params.BuildStrategy, err = buildStrategy(cmd)
+		if err != nil {
+			return err
 		}
-		params.Services = services
 
-		err = client.Push(cmd.Context(), args[0], gitRevision, path, params)
+		err = client.Push(cmd.Context(), name, gitRevision, path, params)
 		if err != nil {
 			return errors.Wrap(err, "error pushing app to server")
 		}
@@ -146,3 +201,187 @@ var CmdPush = &cobra.Command{
 		return nil
 	},
 }
+
+// buildStrategy returns the strategy for the staging of the sources.
+// Without an explicit choice the server detects it, building sources
+// containing a Dockerfile from that file.
+func buildStrategy(cmd *cobra.Command) (string, error) {
+	strategy, err := cmd.Flags().GetString("build-strategy")
+	if err != nil {
+		return "", errors.Wrap(err, "could not read option --build-strategy")
+	}
+
+	switch strategy {
+	case "", models.BuildStrategyBuildpacks, models.BuildStrategyDockerfile:
+	default:
+		cmd.SilenceUsage = false
+		return "", errors.Errorf("unknown build strategy '%s', expected %s or %s", strategy,
+			models.BuildStrategyBuildpacks, models.BuildStrategyDockerfile)
+	}
+
+	return strategy, nil
+}
+
+// pushParams merges the settings from the manifest and the command
+// line options into the parameters for the push. Options override the
+// manifest.
+func pushParams(cmd *cobra.Command, m *manifest.Manifest) (clients.PushParams, error) {
+	params := clients.PushParams{
+		Instances:    m.Instances,
+		Services:     m.Services,
+		BuilderImage: m.BuilderImage,
+		Buildpacks:   m.Buildpacks,
+		ProcessType:  m.ProcessType,
+		Port:         m.Port,
+		Workers:      m.Workers,
+	}
+	if m.HealthChecks != nil {
+		params.HealthChecks = *m.HealthChecks
+	}
+
+	i, err := instances(cmd)
+	if err != nil {
+		return params, errors.Wrap(err, "trouble with instances")
+	}
+	if i != nil {
+		params.Instances = i
+	}
+
+	if cmd.Flags().Changed("bind") {
+		services, err := cmd.Flags().GetStringSlice("bind")
+		if err != nil {
+			return params, errors.Wrap(err, "failed to read option --bind")
+		}
+		params.Services = services
+	}
+
+	if cmd.Flags().Changed("builder-image") {
+		params.BuilderImage, err = cmd.Flags().GetString("builder-image")
+		if err != nil {
+			return params, errors.Wrap(err, "failed to read option --builder-image")
+		}
+	}
+
+	if cmd.Flags().Changed("buildpack") {
+		params.Buildpacks, err = cmd.Flags().GetStringSlice("buildpack")
+		if err != nil {
+			return params, errors.Wrap(err, "failed to read option --buildpack")
+		}
+	}
+
+	if cmd.Flags().Changed("process-type") {
+		params.ProcessType, err = cmd.Flags().GetString("process-type")
+		if err != nil {
+			return params, errors.Wrap(err, "failed to read option --process-type")
+		}
+	}
+
+	if cmd.Flags().Changed("port") {
+		params.Port, err = cmd.Flags().GetInt32("port")
+		if err != nil {
+			return params, errors.Wrap(err, "failed to read option --port")
+		}
+		if params.Port < 1 || params.Port > 65535 {
+			cmd.SilenceUsage = false
+			return params, errors.Errorf("bad port %d, expected 1 to 65535", params.Port)
+		}
+	}
+
+	if cmd.Flags().Changed("worker") {
+		workers, err := cmd.Flags().GetStringArray("worker")
+		if err != nil {
+			return params, errors.Wrap(err, "failed to read option --worker")
+		}
+		params.Workers = []models.Process{}
+		for _, worker := range workers {
+			pieces := strings.SplitN(worker, "=", 2)
+			if pieces[0] == "" {
+				cmd.SilenceUsage = false
+				return params, errors.Errorf("bad worker '%s', expected NAME or NAME=COMMAND", worker)
+			}
+			process := models.Process{Name: pieces[0]}
+			if len(pieces) == 2 {
+				process.Command = strings.Fields(pieces[1])
+			}
+			params.Workers = append(params.Workers, process)
+		}
+	}
+
+	if cmd.Flags().Changed("liveness") {
+		params.HealthChecks.Liveness, err = healthCheckOption(cmd, "liveness")
+		if err != nil {
+			return params, err
+		}
+	}
+
+	if cmd.Flags().Changed("readiness") {
+		params.HealthChecks.Readiness, err = healthCheckOption(cmd, "readiness")
+		if err != nil {
+			return params, err
+		}
+	}
+
+	params.Routes = m.Routes
+
+	assignments, err := cmd.Flags().GetStringArray("env")
+	if err != nil {
+		return params, errors.Wrap(err, "failed to read option --env")
+	}
+	if m.Environment == nil {
+		m.Environment = map[string]string{}
+	}
+	for _, assignment := range assignments {
+		pieces := strings.SplitN(assignment, "=", 2)
+		if len(pieces) != 2 || pieces[0] == "" {
+			cmd.SilenceUsage = false
+			return params, errors.Errorf("bad environment assignment '%s', expected NAME=VALUE", assignment)
+		}
+		m.Environment[pieces[0]] = pieces[1]
+	}
+
+	params.Environment = m.EnvironmentList()
+	params.BuildEnvironment = m.BuildEnvironmentList()
+
+	return params, nil
+}
+
+// healthCheckOption returns the health check given by the named
+// option. Its syntax is http[:PATH], tcp[:PORT], exec:COMMAND or none.
+func healthCheckOption(cmd *cobra.Command, name string) (*models.HealthCheck, error) {
+	spec, err := cmd.Flags().GetString(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read option --%s", name)
+	}
+
+	pieces := strings.SplitN(spec, ":", 2)
+	check := &models.HealthCheck{Type: pieces[0]}
+
+	switch {
+	case check.Type == models.HealthCheckNone && len(pieces) == 1:
+		return check, nil
+	case check.Type == models.HealthCheckHTTP:
+		if len(pieces) == 2 {
+			check.Path = pieces[1]
+		}
+		if check.Path == "" || strings.HasPrefix(check.Path, "/") {
+			return check, nil
+		}
+	case check.Type == models.HealthCheckTCP:
+		if len(pieces) == 1 {
+			return check, nil
+		}
+		port, err := strconv.ParseInt(pieces[1], 10, 32)
+		if err == nil && port > 0 && port <= 65535 {
+			check.Port = int32(port)
+			return check, nil
+		}
+	case check.Type == models.HealthCheckExec && len(pieces) == 2:
+		check.Command = strings.Fields(pieces[1])
+		if len(check.Command) > 0 {
+			return check, nil
+		}
+	}
+
+	cmd.SilenceUsage = false
+	return nil, errors.Errorf("bad %s check '%s', expected http[:PATH], tcp[:PORT], exec:COMMAND or none", name, spec)
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdAppRoute implements the `epinio app route` command ensemble
+var CmdAppRoute = &cobra.Command{
+	Use:           "route",
+	Short:         "Epinio application routes",
+	Long:          `Manage the routes of epinio applications`,
+	Args:          cobra.ExactArgs(0),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	CmdAppRoute.AddCommand(CmdAppRouteList)
+	CmdAppRoute.AddCommand(CmdAppRouteAdd)
+	CmdAppRoute.AddCommand(CmdAppRouteRemove)
+}
+
+// CmdAppRouteList implements the `epinio app route list` command
+var CmdAppRouteList = &cobra.Command{
+	Use:   "list NAME",
+	Short: "List application routes",
+	Long:  "List the routes the named application is reachable under",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppRoutes(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error listing app routes")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppRouteAdd implements the `epinio app route add` command
+var CmdAppRouteAdd = &cobra.Command{
+	Use:   "add NAME HOST",
+	Short: "Add application route",
+	Long:  "Make the named application reachable under the host name, with a certificate of its own",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppRouteAdd(args[0], args[1])
+		if err != nil {
+			return errors.Wrap(err, "error adding app route")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppRouteRemove implements the `epinio app route remove` command
+var CmdAppRouteRemove = &cobra.Command{
+	Use:   "remove NAME HOST",
+	Short: "Remove application route",
+	Long:  "Remove the host name from the routes of the named application, together with its certificate",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppRouteRemove(args[0], args[1])
+		if err != nil {
+			return errors.Wrap(err, "error removing app route")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
@@ -14,12 +14,15 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/epinio/epinio/deployments"
 	"github.com/epinio/epinio/helpers/termui"
 	"github.com/epinio/epinio/helpers/tracelog"
 	apiv1 "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/application"
 	"github.com/epinio/epinio/internal/filesystem"
+	"github.com/epinio/epinio/internal/metrics"
 	"github.com/epinio/epinio/internal/web"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
@@ -40,6 +43,14 @@ func init() {
 	flags.Bool("use-internal-registry-node-port", true, "(USE_INTERNAL_REGISTRY_NODE_PORT) Use the internal registry via a node port")
 	viper.BindPFlag("use-internal-registry-node-port", flags.Lookup("use-internal-registry-node-port"))
 	viper.BindEnv("use-internal-registry-node-port", "USE_INTERNAL_REGISTRY_NODE_PORT")
+
+	flags.String("builder-image", deployments.DefaultBuilderImage, "(BUILDER_IMAGE) The default buildpacks builder image for staging")
+	viper.BindPFlag("builder-image", flags.Lookup("builder-image"))
+	viper.BindEnv("builder-image", "BUILDER_IMAGE")
+
+	flags.String("additional-builder-images", "", "(ADDITIONAL_BUILDER_IMAGES) Comma-separated list of further builder images offered for staging")
+	viper.BindPFlag("additional-builder-images", flags.Lookup("additional-builder-images"))
+	viper.BindEnv("additional-builder-images", "ADDITIONAL_BUILDER_IMAGES")
 }
 
 // CmdServer implements the epinio server command
@@ -75,6 +86,7 @@ func startEpinioServer(wg *sync.WaitGroup, port int, ui *termui.UI, logger logr.
 
 	http.Handle("/api/v1/", logRequestHandler(apiv1.Router(), logger))
 	http.Handle("/", logRequestHandler(web.Router(), logger))
+	http.Handle("/metrics", metrics.Handler())
 	// Static files
 	var assetsDir http.FileSystem
 	if os.Getenv("LOCAL_FILESYSTEM") == "true" {
@@ -84,6 +96,16 @@ func startEpinioServer(wg *sync.WaitGroup, port int, ui *termui.UI, logger logr.
 	}
 	http.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(assetsDir)))
 	srv := &http.Server{Handler: nil}
+
+	// Deploy the stagings whose client went away before deploying them
+	go apiv1.DeployStaged(context.Background(), logger.WithName("DeployStaged"), 10*time.Second)
+
+	// Keep the logs of the stagings beyond their runs
+	go application.SaveStagingLogs(context.Background(), logger.WithName("SaveStagingLogs"), 10*time.Second)
+
+	// Count the resources for the metrics, apart from the scrapes
+	go metrics.CountResources(context.Background(), logger.WithName("CountResources"), 30*time.Second)
+
 	go func() {
 		defer wg.Done() // let caller know we are done cleaning up
 
@@ -3,6 +3,7 @@ package cli
 import (
 	"encoding/json"
 
+	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/epinio/epinio/internal/cli/clients"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -12,6 +13,8 @@ func init() {
 	CmdServiceCreate.Flags().String("data", "", "json data to be passed to the underlying service as parameters")
 	CmdServiceCreate.Flags().Bool("dont-wait", false, "Return immediately, without waiting for the service to be provisioned")
 	CmdServiceDelete.Flags().Bool("unbind", false, "Unbind from applications before deleting")
+	CmdServiceBind.Flags().Bool("env", false, "Also pass the keys of the binding as environment variables, prefixed with the service name")
+	CmdServiceBind.Flags().Bool("json", false, "Also pass the binding as part of the JSON of all bindings, in EPINIO_SERVICES")
 	CmdService.AddCommand(CmdServiceShow)
 	CmdService.AddCommand(CmdServiceCreate)
 	CmdService.AddCommand(CmdServiceCreateCustom)
@@ -140,9 +143,10 @@ var CmdServiceDelete = &cobra.Command{
 var CmdServiceBind = &cobra.Command{
 	Use:   "bind NAME APP",
 	Short: "Bind a service to an application",
-	Long:  `Bind service by name, to named application.`,
-	Args:  cobra.ExactArgs(2),
-	RunE:  ServiceBind,
+	Long: `Bind service by name, to named application.
+The binding is mounted as files at /services/NAME. --env and --json pass it as environment variables too.`,
+	Args: cobra.ExactArgs(2),
+	RunE: ServiceBind,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) > 1 {
 			return nil, cobra.ShellCompDirectiveNoFileComp
@@ -350,12 +354,21 @@ func ServiceDelete(cmd *cobra.Command, args []string) error {
 func ServiceBind(cmd *cobra.Command, args []string) error {
 	cmd.SilenceUsage = true
 
+	env, err := cmd.Flags().GetBool("env")
+	if err != nil {
+		return errors.Wrap(err, "error reading option --env")
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return errors.Wrap(err, "error reading option --json")
+	}
+
 	client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
 	if err != nil {
 		return errors.Wrap(err, "error initializing cli")
 	}
 
-	err = client.BindService(args[0], args[1])
+	err = client.BindService(args[0], args[1], models.BindOptions{Env: env, JSON: asJSON})
 	if err != nil {
 		return errors.Wrap(err, "error binding service")
 	}
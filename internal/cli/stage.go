@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdAppStage implements the `epinio app stage` command ensemble
+var CmdAppStage = &cobra.Command{
+	Use:           "stage",
+	Short:         "Epinio application staging",
+	Long:          `Manage the staging of epinio applications`,
+	Args:          cobra.ExactArgs(0),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	CmdAppStage.AddCommand(CmdAppStageCancel)
+}
+
+// CmdAppStageCancel implements the `epinio app stage cancel` command
+var CmdAppStageCancel = &cobra.Command{
+	Use:   "cancel NAME",
+	Short: "Cancel application staging",
+	Long:  "Stop the staging of the named application which is in progress",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppStageCancel(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error cancelling the staging")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := clients.NewEpinioClient(cmd.Context(), cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(cmd.Context(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
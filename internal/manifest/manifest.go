@@ -0,0 +1,165 @@
+// Package manifest handles the application manifest, a file named
+// epinio.yml kept next to the application sources. It declares the
+// settings `epinio push` uses to deploy the application.
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// FileName is the name of the manifest file in the application sources
+const FileName = "epinio.yml"
+
+// Manifest describes an application as declared in its epinio.yml.
+// All fields are optional.
+type Manifest struct {
+	Name             string               `json:"name,omitempty"`
+	Instances        *int32               `json:"instances,omitempty"`
+	Environment      map[string]string    `json:"environment,omitempty"`
+	Services         []string             `json:"services,omitempty"`
+	Routes           []string             `json:"routes,omitempty"`
+	BuildEnvironment map[string]string    `json:"build_environment,omitempty"`
+	BuilderImage     string               `json:"builder_image,omitempty"`
+	Buildpacks       []string             `json:"buildpacks,omitempty"`
+	ProcessType      string               `json:"process_type,omitempty"`
+	Port             int32                `json:"port,omitempty"`
+	Workers          []models.Process     `json:"workers,omitempty"`
+	HealthChecks     *models.HealthChecks `json:"health_checks,omitempty"`
+}
+
+// Load reads the manifest found in the given directory. A missing
+// manifest is not an error, an empty manifest is returned instead.
+func Load(dir string) (*Manifest, error) {
+	path := filepath.Join(dir, FileName)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Manifest{}, nil
+		}
+		return nil, errors.Wrapf(err, "cannot read manifest %s", path)
+	}
+
+	m, err := Parse(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "bad manifest %s", path)
+	}
+
+	return m, nil
+}
+
+// Parse converts the YAML text of a manifest into a validated structure
+func Parse(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	if err := yaml.UnmarshalStrict(data, m); err != nil {
+		return nil, errors.Wrap(err, "cannot parse manifest")
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Validate checks the manifest for values the server would reject
+func (m *Manifest) Validate() error {
+	if m.Instances != nil && *m.Instances < 0 {
+		return errors.New("instances must be equal or greater than zero")
+	}
+	for name := range m.Environment {
+		if name == "" {
+			return errors.New("environment variable without name")
+		}
+	}
+	for name := range m.BuildEnvironment {
+		if name == "" {
+			return errors.New("build environment variable without name")
+		}
+	}
+	if m.Port < 0 || m.Port > 65535 {
+		return errors.New("port must be between 1 and 65535")
+	}
+	seen := map[string]bool{}
+	for _, worker := range m.Workers {
+		if worker.Name == "" {
+			return errors.New("worker without name")
+		}
+		if seen[worker.Name] {
+			return errors.Errorf("worker %s is declared more than once", worker.Name)
+		}
+		seen[worker.Name] = true
+		if worker.Instances != nil && *worker.Instances < 0 {
+			return errors.Errorf("instances of worker %s must be equal or greater than zero", worker.Name)
+		}
+	}
+	if m.HealthChecks != nil {
+		if err := validateHealthCheck("liveness", m.HealthChecks.Liveness); err != nil {
+			return err
+		}
+		if err := validateHealthCheck("readiness", m.HealthChecks.Readiness); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateHealthCheck(name string, check *models.HealthCheck) error {
+	if check == nil {
+		return nil
+	}
+	switch check.Type {
+	case models.HealthCheckHTTP, models.HealthCheckTCP, models.HealthCheckNone:
+	case models.HealthCheckExec:
+		if len(check.Command) == 0 {
+			return errors.Errorf("%s check without command", name)
+		}
+	default:
+		return errors.Errorf("unknown type '%s' of %s check, expected http, tcp, exec or none", check.Type, name)
+	}
+	if check.Port < 0 || check.Port > 65535 {
+		return errors.Errorf("port of %s check must be between 1 and 65535", name)
+	}
+	return nil
+}
+
+// Write saves the manifest as YAML into the file at path
+func (m *Manifest) Write(path string) error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "cannot serialize manifest")
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// EnvironmentList returns the runtime environment of the manifest in
+// the form used by the env API, sorted by name.
+func (m *Manifest) EnvironmentList() models.EnvVariableList {
+	return toList(m.Environment)
+}
+
+// BuildEnvironmentList returns the staging environment of the manifest
+// in the form used by the stage API, sorted by name.
+func (m *Manifest) BuildEnvironmentList() models.EnvVariableList {
+	return toList(m.BuildEnvironment)
+}
+
+func toList(assignments map[string]string) models.EnvVariableList {
+	result := models.EnvVariableList{}
+	for name, value := range assignments {
+		result = append(result, models.EnvVariable{
+			Name:  name,
+			Value: value,
+		})
+	}
+	sort.Sort(result)
+	return result
+}
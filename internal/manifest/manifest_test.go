@@ -0,0 +1,148 @@
+package manifest_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/manifest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manifest", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "epinio-manifest")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Describe("Load", func() {
+		It("returns an empty manifest when the file is missing", func() {
+			m, err := manifest.Load(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*m).To(Equal(manifest.Manifest{}))
+		})
+
+		It("reads all the fields", func() {
+			err := ioutil.WriteFile(filepath.Join(dir, manifest.FileName), []byte(`
+name: sample
+instances: 3
+environment:
+  MODE: production
+  COLOR: blue
+services:
+- mydb
+routes:
+- sample.example.com
+build_environment:
+  BP_NODE_VERSION: "14"
+builder_image: paketobuildpacks/builder:base
+buildpacks:
+- paketo-buildpacks/nodejs
+process_type: web
+port: 3000
+workers:
+- name: worker
+  instances: 2
+- name: mailer
+  command: ["bin/mailer", "--queue=mail"]
+health_checks:
+  liveness:
+    type: http
+    path: /healthz
+    initial_delay: 5
+  readiness:
+    type: tcp
+`), 0644)
+			Expect(err).ToNot(HaveOccurred())
+
+			m, err := manifest.Load(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Name).To(Equal("sample"))
+			Expect(*m.Instances).To(Equal(int32(3)))
+			Expect(m.Services).To(Equal([]string{"mydb"}))
+			Expect(m.Routes).To(Equal([]string{"sample.example.com"}))
+			Expect(m.EnvironmentList()).To(Equal(models.EnvVariableList{
+				{Name: "COLOR", Value: "blue"},
+				{Name: "MODE", Value: "production"},
+			}))
+			Expect(m.BuildEnvironmentList()).To(Equal(models.EnvVariableList{
+				{Name: "BP_NODE_VERSION", Value: "14"},
+			}))
+			Expect(m.BuilderImage).To(Equal("paketobuildpacks/builder:base"))
+			Expect(m.Buildpacks).To(Equal([]string{"paketo-buildpacks/nodejs"}))
+			Expect(m.ProcessType).To(Equal("web"))
+			Expect(m.Port).To(Equal(int32(3000)))
+			Expect(m.Workers).To(HaveLen(2))
+			Expect(m.Workers[0].Name).To(Equal("worker"))
+			Expect(*m.Workers[0].Instances).To(Equal(int32(2)))
+			Expect(m.Workers[1].Command).To(Equal([]string{"bin/mailer", "--queue=mail"}))
+			Expect(m.HealthChecks).ToNot(BeNil())
+			Expect(*m.HealthChecks.Liveness).To(Equal(models.HealthCheck{
+				Type:         models.HealthCheckHTTP,
+				Path:         "/healthz",
+				InitialDelay: 5,
+			}))
+			Expect(m.HealthChecks.Readiness.Type).To(Equal(models.HealthCheckTCP))
+		})
+
+		It("rejects unknown fields", func() {
+			err := ioutil.WriteFile(filepath.Join(dir, manifest.FileName), []byte("instance: 3\n"), 0644)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = manifest.Load(dir)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects negative instances", func() {
+			err := ioutil.WriteFile(filepath.Join(dir, manifest.FileName), []byte("instances: -1\n"), 0644)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = manifest.Load(dir)
+			Expect(err).To(MatchError(ContainSubstring("instances must be equal or greater than zero")))
+		})
+
+		It("rejects duplicate workers", func() {
+			err := ioutil.WriteFile(filepath.Join(dir, manifest.FileName), []byte("workers:\n- name: worker\n- name: worker\n"), 0644)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = manifest.Load(dir)
+			Expect(err).To(MatchError(ContainSubstring("worker worker is declared more than once")))
+		})
+
+		It("rejects exec checks without command", func() {
+			err := ioutil.WriteFile(filepath.Join(dir, manifest.FileName), []byte("health_checks:\n  liveness:\n    type: exec\n"), 0644)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = manifest.Load(dir)
+			Expect(err).To(MatchError(ContainSubstring("liveness check without command")))
+		})
+	})
+
+	Describe("Write", func() {
+		It("round-trips through Load", func() {
+			instances := int32(2)
+			m := manifest.Manifest{
+				Name:        "sample",
+				Instances:   &instances,
+				Environment: map[string]string{"MODE": "production"},
+				Services:    []string{"mydb", "cache"},
+			}
+
+			Expect(m.Write(filepath.Join(dir, manifest.FileName))).To(Succeed())
+
+			loaded, err := manifest.Load(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*loaded).To(Equal(m))
+		})
+	})
+})
@@ -0,0 +1,225 @@
+// Package metrics provides the prometheus metrics of the Epinio API
+// server: the requests served per route, and the number of the
+// resources it manages.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/epinio/epinio/internal/services"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "epinio"
+
+	// countTimeout bounds the time taken to count the resources
+	countTimeout = 10 * time.Second
+)
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "requests_total",
+		Help:      "Count of the requests served by the API, by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to serve the requests to the API, by route, method and status code. Streaming routes, like logs, take as long as the client listens.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+
+	inFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "requests_in_flight",
+		Help:      "Number of the requests currently served by the API.",
+	})
+
+	resources = newResourceCollector()
+)
+
+func init() {
+	prometheus.MustRegister(requests, duration, inFlight, resources)
+}
+
+// Handler returns the handler serving the metrics to prometheus
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Instrument returns the handler of the named API route, counting and
+// timing the requests it serves.
+func Instrument(route string, h http.HandlerFunc) http.HandlerFunc {
+	labels := prometheus.Labels{"route": route}
+
+	return promhttp.InstrumentHandlerInFlight(inFlight,
+		promhttp.InstrumentHandlerDuration(duration.MustCurryWith(labels),
+			promhttp.InstrumentHandlerCounter(requests.MustCurryWith(labels), h))).ServeHTTP
+}
+
+// CountResources counts the resources managed by Epinio, at once and
+// then every interval until the context is done. The scrapes are
+// served the last counts, so they do not list the resources of the
+// cluster each time.
+func CountResources(ctx context.Context, logger logr.Logger, interval time.Duration) {
+	ctx = context.WithValue(ctx, tracelog.CtxLoggerKey{}, logger)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		resources.setCounts(countResources(ctx))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resourceCounts holds the numbers of the orgs, and of the
+// applications, services and staging runs in progress of each org. A
+// count which failed is missing.
+type resourceCounts struct {
+	orgs     *int
+	apps     map[string]int
+	services map[string]int
+	stagings map[string]int
+}
+
+// countResources counts the resources in the cluster. A failure to
+// count some of the resources is logged, and drops their counts only.
+func countResources(ctx context.Context) resourceCounts {
+	ctx, cancel := context.WithTimeout(ctx, countTimeout)
+	defer cancel()
+	log := tracelog.Logger(ctx)
+
+	counts := resourceCounts{
+		apps:     map[string]int{},
+		services: map[string]int{},
+		stagings: map[string]int{},
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		log.Error(err, "connecting to the cluster")
+		return counts
+	}
+
+	orgs, err := organizations.List(ctx, cluster)
+	if err != nil {
+		log.Error(err, "counting organizations")
+		return counts
+	}
+	count := len(orgs)
+	counts.orgs = &count
+
+	stagings, err := application.StagingsInProgress(ctx, cluster)
+	if err != nil {
+		log.Error(err, "counting stagings")
+	}
+
+	for _, org := range orgs {
+		apps, err := application.ListAppRefs(ctx, cluster, org.Name)
+		if err != nil {
+			log.Error(err, "counting applications", "org", org.Name)
+		} else {
+			counts.apps[org.Name] = len(apps)
+		}
+
+		list, err := services.List(ctx, cluster, org.Name)
+		if err != nil {
+			log.Error(err, "counting services", "org", org.Name)
+		} else {
+			counts.services[org.Name] = len(list)
+		}
+
+		if stagings != nil {
+			counts.stagings[org.Name] = stagings[org.Name]
+		}
+	}
+
+	return counts
+}
+
+// resourceCollector serves the last counts of the resources, as made
+// by CountResources. Before the first count it serves none.
+type resourceCollector struct {
+	orgs     *prometheus.Desc
+	apps     *prometheus.Desc
+	services *prometheus.Desc
+	stagings *prometheus.Desc
+
+	lock   sync.Mutex
+	counts resourceCounts
+}
+
+func newResourceCollector() *resourceCollector {
+	return &resourceCollector{
+		orgs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "organizations"),
+			"Number of the organizations.",
+			nil, nil),
+		apps: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "applications"),
+			"Number of the applications, by organization.",
+			[]string{"org"}, nil),
+		services: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "services"),
+			"Number of the services, by organization.",
+			[]string{"org"}, nil),
+		stagings: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "stagings_in_progress"),
+			"Number of the staging runs in progress, by organization.",
+			[]string{"org"}, nil),
+	}
+}
+
+func (c *resourceCollector) setCounts(counts resourceCounts) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counts = counts
+}
+
+// Describe implements prometheus.Collector
+func (c *resourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.orgs
+	ch <- c.apps
+	ch <- c.services
+	ch <- c.stagings
+}
+
+// Collect implements prometheus.Collector
+func (c *resourceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	counts := c.counts
+	c.lock.Unlock()
+
+	if counts.orgs != nil {
+		ch <- prometheus.MustNewConstMetric(c.orgs, prometheus.GaugeValue, float64(*counts.orgs))
+	}
+	for org, count := range counts.apps {
+		ch <- prometheus.MustNewConstMetric(c.apps, prometheus.GaugeValue, float64(count), org)
+	}
+	for org, count := range counts.services {
+		ch <- prometheus.MustNewConstMetric(c.services, prometheus.GaugeValue, float64(count), org)
+	}
+	for org, count := range counts.stagings {
+		ch <- prometheus.MustNewConstMetric(c.stagings, prometheus.GaugeValue, float64(count), org)
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("resourceCollector", func() {
+	It("serves no counts before the first count", func() {
+		c := newResourceCollector()
+		Expect(testutil.CollectAndCount(c)).To(Equal(0))
+	})
+
+	It("serves the last counts", func() {
+		c := newResourceCollector()
+		orgs := 2
+		c.setCounts(resourceCounts{
+			orgs:     &orgs,
+			apps:     map[string]int{"workspace": 3, "other": 0},
+			services: map[string]int{"workspace": 1},
+			stagings: map[string]int{"workspace": 1, "other": 0},
+		})
+
+		Expect(testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP epinio_organizations Number of the organizations.
+# TYPE epinio_organizations gauge
+epinio_organizations 2
+# HELP epinio_applications Number of the applications, by organization.
+# TYPE epinio_applications gauge
+epinio_applications{org="other"} 0
+epinio_applications{org="workspace"} 3
+# HELP epinio_services Number of the services, by organization.
+# TYPE epinio_services gauge
+epinio_services{org="workspace"} 1
+# HELP epinio_stagings_in_progress Number of the staging runs in progress, by organization.
+# TYPE epinio_stagings_in_progress gauge
+epinio_stagings_in_progress{org="other"} 0
+epinio_stagings_in_progress{org="workspace"} 1
+`))).To(Succeed())
+	})
+
+	It("drops the counts which failed", func() {
+		c := newResourceCollector()
+		c.setCounts(resourceCounts{
+			apps: map[string]int{"workspace": 3},
+		})
+
+		Expect(testutil.CollectAndCount(c)).To(Equal(1))
+	})
+})
@@ -0,0 +1,113 @@
+package organizations
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// LimitRangeName is the name of the LimitRange holding the limits of
+// an organization, in the namespace of the organization
+const LimitRangeName = "epinio-limits"
+
+// Limits returns the limits of the organization. They are empty if the
+// organization has none.
+func Limits(ctx context.Context, client k8s.Interface, org string) (models.OrgLimits, error) {
+	limitRange, err := client.CoreV1().LimitRanges(org).Get(ctx, LimitRangeName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return models.OrgLimits{}, nil
+		}
+		return models.OrgLimits{}, err
+	}
+
+	limits := models.OrgLimits{}
+	for _, item := range limitRange.Spec.Limits {
+		if item.Type != corev1.LimitTypeContainer {
+			continue
+		}
+		limits.DefaultMemory = quantity(item.Default, corev1.ResourceMemory)
+		limits.DefaultCPU = quantity(item.Default, corev1.ResourceCPU)
+		limits.MaxMemory = quantity(item.Max, corev1.ResourceMemory)
+		limits.MaxCPU = quantity(item.Max, corev1.ResourceCPU)
+	}
+
+	return limits, nil
+}
+
+// SetLimits replaces the limits of the organization. The values have to
+// be valid quantities. The defaults apply to the containers of the
+// organization which do not declare their resources, the maximums to
+// all containers.
+func SetLimits(ctx context.Context, client k8s.Interface, org string, limits models.OrgLimits) error {
+	item := corev1.LimitRangeItem{
+		Type:    corev1.LimitTypeContainer,
+		Default: corev1.ResourceList{},
+		Max:     corev1.ResourceList{},
+	}
+	for _, value := range []struct {
+		list     corev1.ResourceList
+		name     corev1.ResourceName
+		quantity string
+	}{
+		{item.Default, corev1.ResourceMemory, limits.DefaultMemory},
+		{item.Default, corev1.ResourceCPU, limits.DefaultCPU},
+		{item.Max, corev1.ResourceMemory, limits.MaxMemory},
+		{item.Max, corev1.ResourceCPU, limits.MaxCPU},
+	} {
+		if value.quantity == "" {
+			continue
+		}
+		q, err := resource.ParseQuantity(value.quantity)
+		if err != nil {
+			return err
+		}
+		value.list[value.name] = q
+	}
+
+	limitRanges := client.CoreV1().LimitRanges(org)
+
+	if len(item.Default) == 0 && len(item.Max) == 0 {
+		err := limitRanges.Delete(ctx, LimitRangeName, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	limitRange := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      LimitRangeName,
+			Namespace: org,
+		},
+		Spec: corev1.LimitRangeSpec{
+			Limits: []corev1.LimitRangeItem{item},
+		},
+	}
+
+	current, err := limitRanges.Get(ctx, LimitRangeName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = limitRanges.Create(ctx, limitRange, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	current.Spec = limitRange.Spec
+	_, err = limitRanges.Update(ctx, current, metav1.UpdateOptions{})
+	return err
+}
+
+func quantity(list corev1.ResourceList, name corev1.ResourceName) string {
+	q, ok := list[name]
+	if !ok {
+		return ""
+	}
+	return q.String()
+}
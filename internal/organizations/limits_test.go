@@ -0,0 +1,77 @@
+package organizations_test
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/organizations"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Limits", func() {
+	var (
+		ctx    context.Context
+		client *fake.Clientset
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = fake.NewSimpleClientset()
+	})
+
+	It("returns no limits for an organization without limits", func() {
+		limits, err := organizations.Limits(ctx, client, "workspace")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(limits).To(Equal(models.OrgLimits{}))
+	})
+
+	table.DescribeTable("returns the limits set",
+		func(limits models.OrgLimits) {
+			Expect(organizations.SetLimits(ctx, client, "workspace", limits)).To(Succeed())
+
+			current, err := organizations.Limits(ctx, client, "workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(current).To(Equal(limits))
+		},
+		table.Entry("all of them", models.OrgLimits{
+			DefaultMemory: "128Mi", DefaultCPU: "250m", MaxMemory: "1Gi", MaxCPU: "2",
+		}),
+		table.Entry("defaults only", models.OrgLimits{DefaultMemory: "128Mi", DefaultCPU: "250m"}),
+		table.Entry("maximums only", models.OrgLimits{MaxMemory: "1Gi", MaxCPU: "2"}),
+		table.Entry("some of them", models.OrgLimits{MaxMemory: "1Gi"}),
+	)
+
+	It("replaces the limits", func() {
+		Expect(organizations.SetLimits(ctx, client, "workspace", models.OrgLimits{
+			DefaultMemory: "128Mi", MaxMemory: "1Gi",
+		})).To(Succeed())
+		Expect(organizations.SetLimits(ctx, client, "workspace", models.OrgLimits{MaxCPU: "2"})).To(Succeed())
+
+		limits, err := organizations.Limits(ctx, client, "workspace")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(limits).To(Equal(models.OrgLimits{MaxCPU: "2"}))
+	})
+
+	It("removes the limit range without limits", func() {
+		Expect(organizations.SetLimits(ctx, client, "workspace", models.OrgLimits{MaxCPU: "2"})).To(Succeed())
+		Expect(organizations.SetLimits(ctx, client, "workspace", models.OrgLimits{})).To(Succeed())
+
+		_, err := client.CoreV1().LimitRanges("workspace").Get(ctx, organizations.LimitRangeName, metav1.GetOptions{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("removing nothing when there are no limits")
+		Expect(organizations.SetLimits(ctx, client, "workspace", models.OrgLimits{})).To(Succeed())
+	})
+
+	It("fails for a bad quantity", func() {
+		err := organizations.SetLimits(ctx, client, "workspace", models.OrgLimits{MaxCPU: "fast"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,13 @@
+package organizations_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOrganizations(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Organizations Suite")
+}